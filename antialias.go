@@ -0,0 +1,250 @@
+//
+// Copyright (c) 2020 Jason S. McMullan <jason.mcmullan@gmail.com>
+//
+
+package uv3dp
+
+import (
+	"image"
+	"math"
+)
+
+// AntialiasMethod is the method of creating gray edges from binary layers
+type AntialiasMethod int
+
+const (
+	// AntialiasSmooth smooths the signed distance to the edges of the
+	// islands, which keeps horizontal and vertical edges sharp, and
+	// recovers the sloped edges from their pixel steps
+	AntialiasSmooth = AntialiasMethod(iota)
+
+	// AntialiasBlur blurs the layer, and thresholds the blur again between
+	// the Low and High levels
+	AntialiasBlur
+)
+
+// Antialias creates anti-aliased gray edges from binary layers.
+//
+// Pixels stay on the same side of the threshold, so that formats without
+// gray levels are unchanged.
+type Antialias struct {
+	Method    AntialiasMethod
+	Radius    float32 // Smoothing radius, or blur sigma, in pixels
+	Low       uint8   // Blurred levels at or below this are black
+	High      uint8   // Blurred levels at or above this are white
+	Levels    int     // Gray levels of the result (0 for all 256)
+	Threshold uint8   // Pixels brighter than this are on
+}
+
+// boxBlur returns the mean of the (2*radius+1) pixels square around every
+// value, ignoring values outside of the image
+func boxBlur(in []float32, width, height, radius int) (out []float32) {
+	return separableBlur(in, width, height, radius, func(n int) float32 { return 1.0 })
+}
+
+// gaussianBlur returns a gaussian blur of the values, ignoring values
+// outside of the image
+func gaussianBlur(in []float32, width, height int, sigma float64) (out []float32) {
+	radius := int(math.Ceil(sigma * 3))
+	return separableBlur(in, width, height, radius, func(n int) float32 {
+		return float32(math.Exp(-float64(n*n) / (2 * sigma * sigma)))
+	})
+}
+
+// separableBlur blurs values by a symmetric kernel, first by rows, then by
+// columns, normalising the weights inside the image
+func separableBlur(in []float32, width, height, radius int, weight func(n int) float32) (out []float32) {
+	kernel := make([]float32, radius+1)
+	for n := range kernel {
+		kernel[n] = weight(n)
+	}
+
+	pass := func(in []float32, lines, length, stride, step int) (out []float32) {
+		out = make([]float32, len(in))
+		for line := 0; line < lines; line++ {
+			base := line * stride
+			for n := 0; n < length; n++ {
+				var sum, total float32
+				for k := -radius; k <= radius; k++ {
+					m := n + k
+					if m < 0 || m >= length {
+						continue
+					}
+					w := kernel[abs(k)]
+					sum += in[base+m*step] * w
+					total += w
+				}
+				out[base+n*step] = sum / total
+			}
+		}
+		return
+	}
+
+	out = pass(in, height, width, width, 1)
+	out = pass(out, width, height, 1, width)
+
+	return
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// coverage returns the quantized coverage of a pixel, on the same side of
+// the threshold as the pixel it replaces
+func (aa *Antialias) coverage(on bool, value float32) (pix uint8) {
+	switch {
+	case value <= 0:
+		pix = 0x00
+	case value >= 1:
+		pix = 0xff
+	default:
+		pix = uint8(value*0xff + 0.5)
+	}
+
+	pix = QuantizeLevel(pix, aa.Levels)
+
+	// Stay on the same side of the threshold, at the nearest level
+	if on && pix <= aa.Threshold {
+		pix = 0xff
+		for level := int(aa.Threshold) + 1; level < 0x100; level++ {
+			if QuantizeLevel(uint8(level), aa.Levels) == uint8(level) {
+				pix = uint8(level)
+				break
+			}
+		}
+	} else if !on && pix > aa.Threshold {
+		pix = 0x00
+		for level := int(aa.Threshold); level >= 0; level-- {
+			if QuantizeLevel(uint8(level), aa.Levels) == uint8(level) {
+				pix = uint8(level)
+				break
+			}
+		}
+	}
+
+	return
+}
+
+// Apply returns a new image, with anti-aliased edges
+func (aa *Antialias) Apply(in *image.Gray) (out *image.Gray) {
+	bounds := in.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+
+	out = image.NewGray(bounds)
+	if width == 0 || height == 0 {
+		return
+	}
+
+	isOn := func(pix uint8) bool { return pix > aa.Threshold }
+	on := make([]bool, width*height)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			on[y*width+x] = isOn(in.Pix[in.PixOffset(bounds.Min.X+x, bounds.Min.Y+y)])
+		}
+	}
+
+	var values []float32
+
+	switch aa.Method {
+	case AntialiasBlur:
+		values = make([]float32, width*height)
+		for n, isOn := range on {
+			if isOn {
+				values[n] = 1.0
+			}
+		}
+
+		if aa.Radius > 0 {
+			values = gaussianBlur(values, width, height, float64(aa.Radius))
+		}
+
+		// Threshold again, between the low and high levels
+		low, high := float32(aa.Low)/0xff, float32(aa.High)/0xff
+		for n, value := range values {
+			switch {
+			case high <= low:
+				if value > low {
+					values[n] = 1.0
+				} else {
+					values[n] = 0.0
+				}
+			default:
+				values[n] = (value - low) / (high - low)
+			}
+		}
+	default:
+		// Signed distance from each pixel centre to the edges, which are
+		// half a pixel from the centres of the nearest other pixels
+		distOff, _, _ := distanceTransform(in, func(pix uint8) bool { return !isOn(pix) }, 1, 1)
+		distOn, _, _ := distanceTransform(in, isOn, 1, 1)
+
+		values = make([]float32, width*height)
+		for n, isOn := range on {
+			var sd float32
+			if isOn {
+				sd = float32(math.Sqrt(float64(distOff[n]))) - 0.5
+			} else {
+				sd = 0.5 - float32(math.Sqrt(float64(distOn[n])))
+			}
+
+			// Only the nearby edges matter
+			if sd > 2 {
+				sd = 2
+			} else if sd < -2 {
+				sd = -2
+			}
+
+			values[n] = sd
+		}
+
+		radius := int(aa.Radius + 0.5)
+		if radius > 0 {
+			values = boxBlur(values, width, height, radius)
+		}
+
+		for n := range values {
+			values[n] += 0.5
+		}
+	}
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			n := y*width + x
+			out.Pix[out.PixOffset(bounds.Min.X+x, bounds.Min.Y+y)] = aa.coverage(on[n], values[n])
+		}
+	}
+
+	return
+}
+
+// AntialiasPrintable anti-aliases the edges of a range of layers
+type AntialiasPrintable struct {
+	Printable
+	Antialias Antialias
+	Layers    LayerRange // Layers to anti-alias
+}
+
+// NewAntialiasPrintable anti-aliases the edges of a range of layers
+func NewAntialiasPrintable(printable Printable, aa Antialias, layers LayerRange) (ap *AntialiasPrintable) {
+	ap = &AntialiasPrintable{
+		Printable: printable,
+		Antialias: aa,
+		Layers:    layers,
+	}
+
+	return
+}
+
+func (ap *AntialiasPrintable) LayerImage(index int) (ig *image.Gray) {
+	ig = ap.Printable.LayerImage(index)
+
+	if ap.Layers.Contains(ap.Printable, index) {
+		ig = ap.Antialias.Apply(ig)
+	}
+
+	return
+}
@@ -0,0 +1,71 @@
+//
+// Copyright (c) 2020 Jason S. McMullan <jason.mcmullan@gmail.com>
+//
+
+package uv3dp
+
+import (
+	"testing"
+
+	"image"
+)
+
+// antialiasTriangle has a vertical edge at x = 4, and a sloped edge
+func antialiasTriangle() (gray *image.Gray) {
+	gray = image.NewGray(image.Rect(0, 0, 16, 16))
+	for y := 0; y < 16; y++ {
+		for x := 4; x < 16; x++ {
+			if x-4 <= y {
+				gray.Pix[gray.PixOffset(x, y)] = 0xff
+			}
+		}
+	}
+
+	return
+}
+
+func TestAntialias(t *testing.T) {
+	in := antialiasTriangle()
+
+	for _, aa := range []Antialias{
+		{Method: AntialiasSmooth, Radius: 1, Threshold: 127},
+		{Method: AntialiasSmooth, Radius: 1, Levels: 8, Threshold: 127},
+		{Method: AntialiasBlur, Radius: 1, Low: 0x40, High: 0xc0, Threshold: 127},
+		{Method: AntialiasBlur, Radius: 1, Low: 0x40, High: 0xc0, Levels: 4, Threshold: 127},
+	} {
+		out := aa.Apply(in)
+
+		gray := 0
+		for n, pix := range out.Pix {
+			// Thresholding gives back the input
+			if (pix > 127) != (in.Pix[n] > 127) {
+				t.Fatalf("%+v: pixel %v changed sides of the threshold (%#x)", aa, n, pix)
+			}
+
+			if pix != 0x00 && pix != 0xff {
+				gray++
+				if aa.Levels != 0 && QuantizeLevel(pix, aa.Levels) != pix {
+					t.Errorf("%+v: pixel %v is not one of the levels (%#x)", aa, n, pix)
+				}
+			}
+		}
+
+		if gray == 0 {
+			t.Errorf("%+v: no gray edges", aa)
+		}
+
+		// The sloped edge is gray
+		if out.GrayAt(10, 6).Y == 0x00 && out.GrayAt(9, 6).Y == 0xff {
+			t.Errorf("%+v: sloped edge is not anti-aliased", aa)
+		}
+	}
+
+	// Smoothing keeps the straight edges sharp, away from the corner
+	aa := &Antialias{Method: AntialiasSmooth, Radius: 1, Threshold: 127}
+	out := aa.Apply(in)
+	for y := 4; y < 15; y++ {
+		if out.GrayAt(3, y).Y != 0x00 || out.GrayAt(4, y).Y != 0xff {
+			t.Errorf("vertical edge at y %v: %#x %#x", y, out.GrayAt(3, y).Y, out.GrayAt(4, y).Y)
+		}
+	}
+}
@@ -0,0 +1,216 @@
+//
+// Copyright (c) 2020 Jason S. McMullan <jason.mcmullan@gmail.com>
+//
+
+package uv3dp
+
+import (
+	"errors"
+	"fmt"
+	"image"
+)
+
+// LayerFunc produces the image of a layer, when it is needed
+type LayerFunc func() *image.Gray
+
+type builderLayer struct {
+	z        float32
+	exposure Exposure
+	image    *image.Gray
+	render   LayerFunc
+}
+
+// Builder assembles a Printable from layers, for generated prints
+type Builder struct {
+	prop   Properties
+	layers []builderLayer
+
+	exposureSet    bool
+	bottomSet      bool
+	layerHeightSet bool
+}
+
+// NewBuilder starts a printable for a bed
+func NewBuilder(bed MachineSize) (b *Builder) {
+	b = &Builder{
+		prop: Properties{
+			Size: Size{
+				X:          bed.X,
+				Y:          bed.Y,
+				Millimeter: SizeMillimeter{X: bed.Xmm, Y: bed.Ymm},
+			},
+			Preview:  map[PreviewType]image.Image{},
+			Metadata: map[string]interface{}{},
+		},
+	}
+
+	return
+}
+
+// Bounds returns the bounds that all layer images must have
+func (b *Builder) Bounds() image.Rectangle {
+	return b.prop.Bounds()
+}
+
+// Layers returns the number of layers added so far
+func (b *Builder) Layers() int {
+	return len(b.layers)
+}
+
+func (b *Builder) addLayer(layer builderLayer) (err error) {
+	if layer.z <= 0 {
+		err = fmt.Errorf("layer %v: Z %v is not above the bed", len(b.layers), layer.z)
+		return
+	}
+
+	if len(b.layers) > 0 {
+		prior := b.layers[len(b.layers)-1].z
+		if layer.z <= prior {
+			err = fmt.Errorf("layer %v: Z %v is not above the prior layer (%v)", len(b.layers), layer.z, prior)
+			return
+		}
+	}
+
+	b.layers = append(b.layers, layer)
+
+	return
+}
+
+// AddLayer appends a layer image, at a Z height in mm. The image is not
+// copied, and should not be changed afterwards.
+func (b *Builder) AddLayer(z float32, exposure Exposure, gray *image.Gray) (err error) {
+	if gray == nil || gray.Bounds() != b.Bounds() {
+		err = fmt.Errorf("layer %v: image bounds must be %v", len(b.layers), b.Bounds())
+		return
+	}
+
+	err = b.addLayer(builderLayer{z: z, exposure: exposure, image: gray})
+
+	return
+}
+
+// AddLayerFunc appends a layer whose image is produced when needed, at
+// a Z height in mm. The image must have the bounds of the bed.
+func (b *Builder) AddLayerFunc(z float32, exposure Exposure, render LayerFunc) (err error) {
+	if render == nil {
+		err = fmt.Errorf("layer %v: no image function", len(b.layers))
+		return
+	}
+
+	err = b.addLayer(builderLayer{z: z, exposure: exposure, render: render})
+
+	return
+}
+
+// SetExposure sets the normal exposure. If not set, it is the exposure
+// of the last layer.
+func (b *Builder) SetExposure(exposure Exposure) {
+	b.prop.Exposure = exposure
+	b.exposureSet = true
+}
+
+// SetBottom sets the bottom exposure. If not set, it is the exposure of
+// the first layer, for as many layers as share it.
+func (b *Builder) SetBottom(bottom Bottom) {
+	b.prop.Bottom = bottom
+	b.bottomSet = true
+}
+
+// SetLayerHeight sets the nominal layer height, in mm. If not set, it is
+// the average layer height.
+func (b *Builder) SetLayerHeight(height float32) {
+	b.prop.Size.LayerHeight = height
+	b.layerHeightSet = true
+}
+
+// SetPreview sets a preview image
+func (b *Builder) SetPreview(ptype PreviewType, preview image.Image) {
+	b.prop.Preview[ptype] = preview
+}
+
+// SetMetadata sets a metadata value
+func (b *Builder) SetMetadata(key string, data interface{}) {
+	b.prop.Metadata[key] = data
+}
+
+// Build returns the printable, from the layers added so far
+func (b *Builder) Build() (printable Printable, err error) {
+	layers := len(b.layers)
+	if layers == 0 {
+		err = errors.New("no layers to build")
+		return
+	}
+
+	prop := b.prop
+	prop.Preview = map[PreviewType]image.Image{}
+	for key, preview := range b.prop.Preview {
+		prop.Preview[key] = preview
+	}
+	prop.Metadata = map[string]interface{}{}
+	for key, data := range b.prop.Metadata {
+		prop.Metadata[key] = data
+	}
+
+	prop.Size.Layers = layers
+	if !b.layerHeightSet {
+		prop.Size.LayerHeight = b.layers[layers-1].z / float32(layers)
+	}
+
+	if !b.exposureSet {
+		prop.Exposure = b.layers[layers-1].exposure
+	}
+
+	if !b.bottomSet {
+		prop.Bottom = Bottom{Exposure: b.layers[0].exposure}
+		if prop.Bottom.Exposure != prop.Exposure {
+			for _, layer := range b.layers {
+				if layer.exposure != prop.Bottom.Exposure {
+					break
+				}
+				prop.Bottom.Count++
+			}
+		}
+	}
+
+	printable = &builtPrint{
+		Print:  Print{Properties: prop},
+		layers: append([]builderLayer{}, b.layers...),
+	}
+
+	return
+}
+
+// builtPrint is a printable from a Builder
+type builtPrint struct {
+	Print
+	layers []builderLayer
+}
+
+func (bp *builtPrint) LayerZ(index int) float32 {
+	return bp.layers[index].z
+}
+
+func (bp *builtPrint) LayerExposure(index int) Exposure {
+	return bp.layers[index].exposure
+}
+
+func (bp *builtPrint) LayerImage(index int) (gray *image.Gray) {
+	layer := &bp.layers[index]
+
+	if layer.render == nil {
+		// A copy, so that the layer can not be changed
+		gray = &image.Gray{
+			Pix:    append([]uint8{}, layer.image.Pix...),
+			Stride: layer.image.Stride,
+			Rect:   layer.image.Rect,
+		}
+		return
+	}
+
+	gray = layer.render()
+	if gray == nil || gray.Bounds() != bp.Bounds() {
+		panic(fmt.Sprintf("layer %v: image bounds must be %v", index, bp.Bounds()))
+	}
+
+	return
+}
@@ -0,0 +1,151 @@
+//
+// Copyright (c) 2020 Jason S. McMullan <jason.mcmullan@gmail.com>
+//
+
+package uv3dp
+
+import (
+	"image"
+
+	"testing"
+)
+
+var testBuilderBed = MachineSize{X: 8, Y: 4, Xmm: 0.8, Ymm: 0.4}
+
+func TestBuilder(t *testing.T) {
+	bottom := Exposure{LightOnTime: 30, LightPWM: 255}
+	normal := Exposure{LightOnTime: 5, LightPWM: 255}
+
+	b := NewBuilder(testBuilderBed)
+
+	// Two bottom layers from images, then two generated layers
+	for n := 0; n < 2; n++ {
+		gray := image.NewGray(b.Bounds())
+		gray.Pix[n] = 0xff
+		err := b.AddLayer(float32(n+1)*0.05, bottom, gray)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	calls := 0
+	for n := 2; n < 4; n++ {
+		index := n
+		err := b.AddLayerFunc(float32(n+1)*0.05, normal, func() *image.Gray {
+			calls++
+			gray := image.NewGray(image.Rect(0, 0, 8, 4))
+			gray.Pix[index] = 0xff
+			return gray
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tiny := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	b.SetPreview(PreviewTypeTiny, tiny)
+	b.SetMetadata("name", "test")
+
+	printable, err := b.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if calls != 0 {
+		t.Errorf("expected layer functions to be called only when needed")
+	}
+
+	size := printable.Size()
+	if size.Layers != 4 || size.X != 8 || size.Y != 4 || size.Millimeter.X != 0.8 {
+		t.Errorf("unexpected size %+v", size)
+	}
+
+	if size.LayerHeight != 0.05 {
+		t.Errorf("expected layer height 0.05, got %v", size.LayerHeight)
+	}
+
+	if printable.Exposure() != normal {
+		t.Errorf("expected normal exposure %+v, got %+v", normal, printable.Exposure())
+	}
+
+	if bot := printable.Bottom(); bot.Count != 2 || bot.Exposure != bottom {
+		t.Errorf("expected 2 bottom layers of %+v, got %+v", bottom, bot)
+	}
+
+	for n := 0; n < 4; n++ {
+		if z := printable.LayerZ(n); z != float32(n+1)*0.05 {
+			t.Errorf("layer %v: unexpected Z %v", n, z)
+		}
+
+		gray := printable.LayerImage(n)
+		if gray.Bounds() != b.Bounds() || gray.Pix[n] != 0xff {
+			t.Errorf("layer %v: unexpected image", n)
+		}
+	}
+
+	if calls != 2 {
+		t.Errorf("expected 2 calls of the layer functions, got %v", calls)
+	}
+
+	if ig, ok := printable.Preview(PreviewTypeTiny); !ok || ig != image.Image(tiny) {
+		t.Errorf("expected the tiny preview")
+	}
+
+	if data, ok := printable.Metadata("name"); !ok || data != "test" {
+		t.Errorf("expected the metadata")
+	}
+
+	// Layer images can not be changed by their users
+	printable.LayerImage(0).Pix[0] = 0
+	if printable.LayerImage(0).Pix[0] != 0xff {
+		t.Errorf("expected layer images to be copies")
+	}
+}
+
+func TestBuilderErrors(t *testing.T) {
+	exposure := Exposure{LightOnTime: 5}
+
+	b := NewBuilder(testBuilderBed)
+	_, err := b.Build()
+	if err == nil {
+		t.Errorf("expected an error with no layers")
+	}
+
+	table := map[string]func(b *Builder) error{
+		"bounds": func(b *Builder) error {
+			return b.AddLayer(0.1, exposure, image.NewGray(image.Rect(0, 0, 4, 8)))
+		},
+		"nil image": func(b *Builder) error {
+			return b.AddLayer(0.1, exposure, nil)
+		},
+		"nil func": func(b *Builder) error {
+			return b.AddLayerFunc(0.1, exposure, nil)
+		},
+		"bed": func(b *Builder) error {
+			return b.AddLayer(0, exposure, image.NewGray(b.Bounds()))
+		},
+		"same z": func(b *Builder) error {
+			return b.AddLayer(0.05, exposure, image.NewGray(b.Bounds()))
+		},
+		"lower z": func(b *Builder) error {
+			return b.AddLayer(0.01, exposure, image.NewGray(b.Bounds()))
+		},
+	}
+
+	for name, add := range table {
+		b := NewBuilder(testBuilderBed)
+		err := b.AddLayer(0.05, exposure, image.NewGray(b.Bounds()))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = add(b)
+		if err == nil {
+			t.Errorf("%v: expected an error", name)
+		}
+
+		if b.Layers() != 1 {
+			t.Errorf("%v: expected the layer to not be added", name)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+//
+// Copyright (c) 2020 Jason S. McMullan <jason.mcmullan@gmail.com>
+//
+
+package cbddlp
+
+import (
+	"fmt"
+
+	"testing"
+
+	"github.com/ezrec/uv3dp"
+	"github.com/ezrec/uv3dp/uv3dptest"
+)
+
+func TestConformance(t *testing.T) {
+	newFormatter := func(suffix string) uv3dp.Formatter { return NewFormatter(suffix) }
+
+	fixup := func(kind uv3dptest.ExposureKind, exposure *uv3dp.Exposure) {
+		// Retract height is not saved
+		exposure.RetractHeight = defaultRetractHeight
+
+		// Layers do not have their own lift or retract
+		if kind == uv3dptest.ExposureLayer {
+			exposure.LiftHeight = 0
+			exposure.LiftSpeed = 0
+			exposure.RetractSpeed = 0
+		}
+	}
+
+	for _, item := range []struct {
+		Suffix     string
+		Args       []string
+		GrayLevels int
+	}{
+		{".cbddlp", nil, 2},
+		{".cbddlp", []string{"--anti-alias", "8"}, 8},
+	} {
+		t.Run(fmt.Sprintf("%v%v", item.Suffix, item.Args), func(t *testing.T) {
+			uv3dptest.RunFormatterConformance(t, newFormatter, uv3dptest.Capabilities{
+				Suffix:        item.Suffix,
+				Args:          item.Args,
+				GrayLevels:    item.GrayLevels,
+				LayerExposure: true,
+				LayerZ:        true,
+				Previews:      []uv3dp.PreviewType{uv3dp.PreviewTypeTiny, uv3dp.PreviewTypeHuge},
+				Fixup:         fixup,
+			})
+		})
+	}
+}
@@ -147,6 +147,14 @@ func NewFormatter(suffix string) (cf *Formatter) {
 	return
 }
 
+// PreviewSizes returns the sizes of the previews shown by the printer
+func (cf *Formatter) PreviewSizes() map[uv3dp.PreviewType]image.Point {
+	return map[uv3dp.PreviewType]image.Point{
+		uv3dp.PreviewTypeTiny: {X: 200, Y: 125},
+		uv3dp.PreviewTypeHuge: {X: 400, Y: 300},
+	}
+}
+
 // Save a uv3dp.Printable in CBD DLP format
 func (cf *Formatter) Encode(writer uv3dp.Writer, p uv3dp.Printable) (err error) {
 	switch cf.Version {
@@ -256,8 +264,10 @@ func (cf *Formatter) Encode(writer uv3dp.Writer, p uv3dp.Printable) (err error)
 	}
 
 	uv3dp.WithAllLayers(p, func(p uv3dp.Printable, n int) {
+		// Each anti-alias bit plane adds a gray level
+		layer := uv3dp.QuantizeLayer(p.LayerImage(n), cf.AntiAlias+1)
 		for bit := 0; bit < cf.AntiAlias; bit++ {
-			rle, hash, bitsOn := rleEncodeBitmap(p.LayerImage(n), bit, cf.AntiAlias)
+			rle, hash, bitsOn := rleEncodeBitmap(layer, bit, cf.AntiAlias)
 			doneMap[n] <- layerInfo{
 				Z:        p.LayerZ(n),
 				Exposure: p.LayerExposure(n),
@@ -0,0 +1,87 @@
+//
+// Copyright (c) 2020 Jason S. McMullan <jason.mcmullan@gmail.com>
+//
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/pflag"
+
+	"github.com/ezrec/uv3dp"
+)
+
+type AntialiasCommand struct {
+	*pflag.FlagSet
+
+	Method    string
+	Radius    float32
+	Low       uint8
+	High      uint8
+	Levels    int
+	Threshold uint8
+	Layers    string
+}
+
+func NewAntialiasCommand() (cmd *AntialiasCommand) {
+	flagSet := pflag.NewFlagSet("antialias", pflag.ContinueOnError)
+	flagSet.SetInterspersed(false)
+
+	cmd = &AntialiasCommand{
+		FlagSet: flagSet,
+	}
+
+	cmd.StringVarP(&cmd.Method, "method", "m", "smooth", "Method - 'smooth' (edge smoothing) or 'blur' (blur, and threshold again)")
+	cmd.Float32VarP(&cmd.Radius, "radius", "r", 1.0, "Smoothing radius, or blur sigma, in pixels")
+	cmd.Uint8VarP(&cmd.Low, "low", "L", 0x40, "Blurred levels at or below this are black (for 'blur')")
+	cmd.Uint8VarP(&cmd.High, "high", "H", 0xc0, "Blurred levels at or above this are white (for 'blur')")
+	cmd.IntVarP(&cmd.Levels, "levels", "n", 0, "Gray levels to quantize to, ie 9 for '.cbddlp --anti-alias 8' (default 0, for none: the output format quantizes to its own gray levels, by the --quantize mode)")
+	cmd.Uint8VarP(&cmd.Threshold, "threshold", "t", 127, "Pixels brighter than this are on")
+	cmd.StringVarP(&cmd.Layers, "layers", "l", "", "Layers to change, by index ('0,5-9,12-') or Z ('1.5mm-3mm'), or 'bottom' or 'normal' (default all layers)")
+
+	return
+}
+
+func (cmd *AntialiasCommand) Filter(input uv3dp.Printable) (output uv3dp.Printable, err error) {
+	aa := uv3dp.Antialias{
+		Radius:    cmd.Radius,
+		Low:       cmd.Low,
+		High:      cmd.High,
+		Levels:    cmd.Levels,
+		Threshold: cmd.Threshold,
+	}
+
+	switch cmd.Method {
+	case "smooth":
+		aa.Method = uv3dp.AntialiasSmooth
+	case "blur":
+		aa.Method = uv3dp.AntialiasBlur
+	default:
+		err = fmt.Errorf("illegal --method setting: %v", cmd.Method)
+		return
+	}
+
+	if cmd.Radius < 0 {
+		err = fmt.Errorf("illegal --radius setting: %v", cmd.Radius)
+		return
+	}
+
+	if cmd.Levels != 0 && (cmd.Levels < 2 || cmd.Levels > 256) {
+		err = fmt.Errorf("illegal --levels setting: %v (2..256)", cmd.Levels)
+		return
+	}
+
+	layers, err := uv3dp.ParseLayerRange(cmd.Layers)
+	if err != nil {
+		err = fmt.Errorf("illegal --layers setting: %w", err)
+		return
+	}
+
+	TraceVerbosef(VerbosityNotice, "  Anti-aliasing edges by %v, radius %v", cmd.Method, cmd.Radius)
+
+	// Previews no longer match the edges
+	output = uv3dp.StalePreviews(uv3dp.NewAntialiasPrintable(input, aa, layers))
+
+	return
+}
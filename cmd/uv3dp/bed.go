@@ -12,7 +12,6 @@ import (
 	"image/color"
 
 	"github.com/spf13/pflag"
-	"golang.org/x/image/draw"
 
 	"github.com/ezrec/uv3dp"
 )
@@ -24,6 +23,7 @@ type BedCommand struct {
 	Millimeters []float32
 	Machine     string
 	Reflect     bool
+	ResampleFlags
 }
 
 func NewBedCommand() (bc *BedCommand) {
@@ -36,12 +36,18 @@ func NewBedCommand() (bc *BedCommand) {
 
 	bc.StringVarP(&bc.Machine, "machine", "M", "EPAX-X1", "Size preset by machine type")
 	bc.BoolVarP(&bc.Reflect, "reflect", "r", false, "Mirror image along the X axis")
+	bc.ResampleFlags.AddFlags(bc.FlagSet)
 	bc.SetInterspersed(false)
 
 	return
 }
 
 func (bc *BedCommand) Filter(input uv3dp.Printable) (output uv3dp.Printable, err error) {
+	resample, err := bc.ResampleFlags.Resample()
+	if err != nil {
+		return
+	}
+
 	srcSize := input.Size()
 	dstSize := srcSize
 	rotate := false
@@ -114,9 +120,11 @@ func (bc *BedCommand) Filter(input uv3dp.Printable) (output uv3dp.Printable, err
 		rotate:    rotate,
 		dstRect:   dstRect,
 		reflect:   bc.Reflect,
+		resample:  resample,
 	}
 
-	output = bm
+	// Previews no longer match the bed
+	output = uv3dp.StalePreviews(bm)
 
 	return
 }
@@ -149,10 +157,11 @@ func (ri *reflectImage) At(x, y int) color.Color {
 type bedModifier struct {
 	uv3dp.Printable
 
-	size    uv3dp.Size
-	dstRect image.Rectangle
-	rotate  bool
-	reflect bool
+	size     uv3dp.Size
+	dstRect  image.Rectangle
+	rotate   bool
+	reflect  bool
+	resample uv3dp.Resample
 }
 
 func (bm *bedModifier) Size() (size uv3dp.Size) {
@@ -181,7 +190,7 @@ func (bm *bedModifier) LayerImage(index int) (newImage *image.Gray) {
 		srcImage = &reflectImage{Image: srcImage, dX: dX}
 	}
 
-	draw.NearestNeighbor.Scale(newImage, bm.dstRect, srcImage, srcImage.Bounds(), draw.Src, nil)
+	bm.resample.Scale(newImage, bm.dstRect, srcImage, srcImage.Bounds())
 
 	return
 }
@@ -0,0 +1,75 @@
+//
+// Copyright (c) 2020 Jason S. McMullan <jason.mcmullan@gmail.com>
+//
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/pflag"
+
+	"github.com/ezrec/uv3dp"
+)
+
+type CompensateCommand struct {
+	*pflag.FlagSet
+
+	Distance  float32
+	X         float32
+	Y         float32
+	Threshold uint8
+	Layers    string
+}
+
+func NewCompensateCommand() (cmd *CompensateCommand) {
+	flagSet := pflag.NewFlagSet("compensate", pflag.ContinueOnError)
+	flagSet.SetInterspersed(false)
+
+	cmd = &CompensateCommand{
+		FlagSet: flagSet,
+	}
+
+	cmd.Float32VarP(&cmd.Distance, "distance", "d", 0.0, "Distance to grow (or shrink, if negative) the outline of islands, in mm")
+	cmd.Float32VarP(&cmd.X, "x", "x", 0.0, "Distance in X, in mm (default --distance)")
+	cmd.Float32VarP(&cmd.Y, "y", "y", 0.0, "Distance in Y, in mm (default --distance)")
+	cmd.Uint8VarP(&cmd.Threshold, "threshold", "t", 127, "Pixels brighter than this are on")
+	cmd.StringVarP(&cmd.Layers, "layers", "l", "", "Layers to change, by index ('0,5-9,12-') or Z ('1.5mm-3mm'), or 'bottom' or 'normal' (default all layers)")
+
+	return
+}
+
+func (cmd *CompensateCommand) Filter(input uv3dp.Printable) (output uv3dp.Printable, err error) {
+	compensate := uv3dp.Compensate{
+		X:         cmd.Distance,
+		Y:         cmd.Distance,
+		Threshold: cmd.Threshold,
+	}
+
+	if cmd.Changed("x") {
+		compensate.X = cmd.X
+	}
+
+	if cmd.Changed("y") {
+		compensate.Y = cmd.Y
+	}
+
+	size := input.Size()
+	if size.X == 0 || size.Y == 0 || size.Millimeter.X <= 0 || size.Millimeter.Y <= 0 {
+		err = fmt.Errorf("compensate: printable has no size in millimeters")
+		return
+	}
+
+	layers, err := uv3dp.ParseLayerRange(cmd.Layers)
+	if err != nil {
+		err = fmt.Errorf("illegal --layers setting: %w", err)
+		return
+	}
+
+	TraceVerbosef(VerbosityNotice, "  Compensating outlines by %vmm in X, %vmm in Y", compensate.X, compensate.Y)
+
+	// Previews no longer match the outlines
+	output = uv3dp.StalePreviews(uv3dp.NewCompensatePrintable(input, compensate, layers))
+
+	return
+}
@@ -32,28 +32,28 @@ func NewDecimateCommand() (cmd *DecimateCommand) {
 	return
 }
 
+// Filter is a preset of the 'morph' command: an erosion with a square
+// kernel, of a radius of the number of passes
 func (cmd *DecimateCommand) Filter(input uv3dp.Printable) (output uv3dp.Printable, err error) {
-	layers := input.Size().Layers
-	botCount := input.Bottom().Count
-
-	if cmd.Bottom > 0 {
-		dec := uv3dp.NewDecimatedPrintable(input)
-
-		dec.Passes = cmd.Bottom
-		dec.FirstLayer = 0
-		dec.Layers = botCount
-
-		input = dec
-	}
-
-	if cmd.Normal > 0 {
-		dec := uv3dp.NewDecimatedPrintable(input)
-
-		dec.Passes = cmd.Normal
-		dec.FirstLayer = botCount
-		dec.Layers = layers - botCount
-
-		input = dec
+	for _, item := range []struct {
+		Passes int
+		Layers string
+	}{
+		{cmd.Bottom, "bottom"},
+		{cmd.Normal, "normal"},
+	} {
+		if item.Passes <= 0 {
+			continue
+		}
+
+		var layers uv3dp.LayerRange
+		layers, err = uv3dp.ParseLayerRange(item.Layers)
+		if err != nil {
+			return
+		}
+
+		// Previews no longer match the decimated layers
+		input = uv3dp.StalePreviews(uv3dp.NewMorphPrintable(input, uv3dp.DecimateMorph(item.Passes), layers))
 	}
 
 	output = input
@@ -0,0 +1,111 @@
+//
+// Copyright (c) 2020 Jason S. McMullan <jason.mcmullan@gmail.com>
+//
+
+package main
+
+import (
+	"fmt"
+	"image"
+
+	"github.com/spf13/pflag"
+
+	"github.com/ezrec/uv3dp"
+)
+
+type DimCommand struct {
+	*pflag.FlagSet
+
+	Pattern   string
+	Level     uint8
+	Cell      int
+	Tile      string
+	Wall      float32
+	Threshold uint8
+	Layers    string
+}
+
+func NewDimCommand() (cmd *DimCommand) {
+	flagSet := pflag.NewFlagSet("dim", pflag.ContinueOnError)
+	flagSet.SetInterspersed(false)
+
+	cmd = &DimCommand{
+		FlagSet: flagSet,
+	}
+
+	cmd.StringVarP(&cmd.Pattern, "pattern", "p", "checkerboard", "Dimming pattern - 'solid', 'checkerboard', 'chessboard', or 'tile'")
+	cmd.Uint8VarP(&cmd.Level, "level", "L", 128, "Light level of the dimmed pixels of the pattern (0..255)")
+	cmd.IntVarP(&cmd.Cell, "cell", "c", 4, "Size of the cells of the 'chessboard' pattern, in pixels")
+	cmd.StringVarP(&cmd.Tile, "tile", "T", "", "PNG or JPEG image of the 'tile' pattern (black is --level, white is full light)")
+	cmd.Float32VarP(&cmd.Wall, "wall", "w", 0.5, "Thickness of the island walls that stay at full light, in mm")
+	cmd.Uint8VarP(&cmd.Threshold, "threshold", "t", 127, "Pixels brighter than this are on")
+	cmd.StringVarP(&cmd.Layers, "layers", "l", "", "Layers to change, by index ('0,5-9,12-') or Z ('1.5mm-3mm'), or 'bottom' or 'normal' (default all layers)")
+
+	return
+}
+
+func (cmd *DimCommand) Filter(input uv3dp.Printable) (output uv3dp.Printable, err error) {
+	dim := uv3dp.Dim{
+		Wall:      cmd.Wall,
+		Threshold: cmd.Threshold,
+	}
+
+	if cmd.Tile != "" && cmd.Pattern != "tile" {
+		err = fmt.Errorf("illegal --tile setting: needs --pattern tile")
+		return
+	}
+
+	switch cmd.Pattern {
+	case "solid":
+		dim.Pattern = uv3dp.NewSolidPattern(cmd.Level)
+	case "checkerboard":
+		dim.Pattern = uv3dp.NewCheckerboardPattern(cmd.Level)
+	case "chessboard":
+		if cmd.Cell < 1 {
+			err = fmt.Errorf("illegal --cell setting: %v", cmd.Cell)
+			return
+		}
+		dim.Pattern = uv3dp.NewChessboardPattern(cmd.Level, cmd.Cell)
+	case "tile":
+		if cmd.Tile == "" {
+			err = fmt.Errorf("illegal --pattern setting: 'tile' needs --tile")
+			return
+		}
+		var ig image.Image
+		ig, err = loadImage(cmd.Tile)
+		if err != nil {
+			return
+		}
+		if ig.Bounds().Empty() {
+			err = fmt.Errorf("illegal --tile setting: %v is empty", cmd.Tile)
+			return
+		}
+		dim.Pattern = uv3dp.NewTilePattern(ig, cmd.Level)
+	default:
+		err = fmt.Errorf("illegal --pattern setting: %v", cmd.Pattern)
+		return
+	}
+
+	if cmd.Wall < 0 {
+		err = fmt.Errorf("illegal --wall setting: %v", cmd.Wall)
+		return
+	}
+
+	size := input.Size()
+	if size.X == 0 || size.Y == 0 || size.Millimeter.X <= 0 || size.Millimeter.Y <= 0 {
+		err = fmt.Errorf("dim: printable has no size in millimeters")
+		return
+	}
+
+	layers, err := uv3dp.ParseLayerRange(cmd.Layers)
+	if err != nil {
+		err = fmt.Errorf("illegal --layers setting: %w", err)
+		return
+	}
+
+	TraceVerbosef(VerbosityNotice, "  Dimming island interiors to %v with a %v pattern, keeping %vmm walls", cmd.Level, cmd.Pattern, cmd.Wall)
+
+	output = uv3dp.NewDimPrintable(input, dim, layers)
+
+	return
+}
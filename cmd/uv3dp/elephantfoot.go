@@ -0,0 +1,80 @@
+//
+// Copyright (c) 2020 Jason S. McMullan <jason.mcmullan@gmail.com>
+//
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/pflag"
+
+	"github.com/ezrec/uv3dp"
+)
+
+type ElephantFootCommand struct {
+	*pflag.FlagSet
+
+	Distance float32
+	Taper    int
+	Pattern  string
+	Level    uint8
+}
+
+func NewElephantFootCommand() (cmd *ElephantFootCommand) {
+	flagSet := pflag.NewFlagSet("elephant-foot", pflag.ContinueOnError)
+	flagSet.SetInterspersed(false)
+
+	cmd = &ElephantFootCommand{
+		FlagSet: flagSet,
+	}
+
+	cmd.Float32VarP(&cmd.Distance, "distance", "d", 0.5, "Inset of the bottom layers' outline, in mm")
+	cmd.IntVarP(&cmd.Taper, "taper", "t", 0, "Number of layers above the bottom layers, over which the inset tapers away")
+	cmd.StringVarP(&cmd.Pattern, "pattern", "p", "none", "Dim the inset with a pattern, instead of removing it - 'none', 'solid', or 'checkerboard'")
+	cmd.Uint8VarP(&cmd.Level, "level", "L", 128, "Light level of the dimmed pixels of the pattern (0..255)")
+
+	return
+}
+
+func (cmd *ElephantFootCommand) Filter(input uv3dp.Printable) (output uv3dp.Printable, err error) {
+	if cmd.Distance < 0 {
+		err = fmt.Errorf("illegal --distance setting: %v", cmd.Distance)
+		return
+	}
+
+	if cmd.Taper < 0 {
+		err = fmt.Errorf("illegal --taper setting: %v", cmd.Taper)
+		return
+	}
+
+	ef := uv3dp.ElephantFoot{
+		Distance:  cmd.Distance,
+		Taper:     cmd.Taper,
+		Threshold: 127,
+	}
+
+	switch cmd.Pattern {
+	case "none":
+	case "solid":
+		ef.Pattern = uv3dp.NewSolidPattern(cmd.Level)
+	case "checkerboard":
+		ef.Pattern = uv3dp.NewCheckerboardPattern(cmd.Level)
+	default:
+		err = fmt.Errorf("illegal --pattern setting: %v", cmd.Pattern)
+		return
+	}
+
+	size := input.Size()
+	if size.X == 0 || size.Y == 0 || size.Millimeter.X <= 0 || size.Millimeter.Y <= 0 {
+		err = fmt.Errorf("elephant-foot: printable has no size in millimeters")
+		return
+	}
+
+	TraceVerbosef(VerbosityNotice, "  Insetting %v bottom layers by %vmm, tapering over %v layers", input.Bottom().Count, cmd.Distance, cmd.Taper)
+
+	// Previews no longer match the bottom layers
+	output = uv3dp.StalePreviews(uv3dp.NewElephantFootPrintable(input, ef))
+
+	return
+}
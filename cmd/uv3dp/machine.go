@@ -7,6 +7,7 @@ package main
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 	"sort"
 	"strings"
 
@@ -33,3 +34,79 @@ func PrintMachines() {
 		fmt.Fprintf(os.Stderr, "Format: %s %v\n", item.Extension, strings.Join(item.Args, " "))
 	}
 }
+
+// machineProfileFile returns the path of a file of a machine's profile, in
+// the 'uv3dp/machines' directory of the user's configuration directory
+func machineProfileFile(name string, file string) (path string, err error) {
+	config, err := os.UserConfigDir()
+	if err != nil {
+		return
+	}
+
+	path = filepath.Join(config, "uv3dp", "machines", name, file)
+
+	return
+}
+
+// LoadMachineProfiles attaches the saved profiles of the known machines
+func LoadMachineProfiles() (errs []error) {
+	for name := range uv3dp.MachineFormats {
+		path, err := machineProfileFile(name, "uniformity.csv")
+		if err != nil {
+			return
+		}
+
+		reader, err := os.Open(path)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				errs = append(errs, err)
+			}
+			continue
+		}
+
+		uniformity, err := uv3dp.ReadUniformityCSV(reader)
+		reader.Close()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%v: %w", path, err))
+			continue
+		}
+
+		uv3dp.AttachUniformity(name, uniformity)
+	}
+
+	return
+}
+
+// SaveMachineUniformity saves, and attaches, the uniformity of a machine
+func SaveMachineUniformity(name string, uniformity *uv3dp.Uniformity) (err error) {
+	_, found := uv3dp.MachineFormats[name]
+	if !found {
+		err = fmt.Errorf("machine '%s' is not a known machine type", name)
+		return
+	}
+
+	path, err := machineProfileFile(name, "uniformity.csv")
+	if err != nil {
+		return
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return
+	}
+
+	writer, err := os.Create(path)
+	if err != nil {
+		return
+	}
+	defer writer.Close()
+
+	err = uniformity.WriteCSV(writer)
+	if err != nil {
+		return
+	}
+
+	err = uv3dp.AttachUniformity(name, uniformity)
+
+	return
+}
@@ -14,11 +14,18 @@ import (
 	_ "github.com/ezrec/uv3dp/ctb"
 	_ "github.com/ezrec/uv3dp/cws"
 	_ "github.com/ezrec/uv3dp/czip"
+	_ "github.com/ezrec/uv3dp/dxf"
 	_ "github.com/ezrec/uv3dp/fdg"
 	_ "github.com/ezrec/uv3dp/lgs"
+	_ "github.com/ezrec/uv3dp/nanodlp"
 	_ "github.com/ezrec/uv3dp/phz"
+	"github.com/ezrec/uv3dp/plugin"
 	_ "github.com/ezrec/uv3dp/pws"
+	_ "github.com/ezrec/uv3dp/review"
 	_ "github.com/ezrec/uv3dp/sl1"
+	_ "github.com/ezrec/uv3dp/stack"
+	_ "github.com/ezrec/uv3dp/stl"
+	_ "github.com/ezrec/uv3dp/svg"
 	_ "github.com/ezrec/uv3dp/uvj"
 	_ "github.com/ezrec/uv3dp/zcodex"
 
@@ -37,9 +44,17 @@ const (
 )
 
 var param struct {
-	Verbose  int  // Verbose counts the number of '-v' flags
-	Version  bool // Show version
-	Progress bool // Show progress bar
+	Verbose  int    // Verbose counts the number of '-v' flags
+	Version  bool   // Show version
+	Progress bool   // Show progress bar
+	Quantize string // Gray level quantization mode
+}
+
+var quantizeModes = map[string]uv3dp.QuantizeMode{
+	"threshold":       uv3dp.QuantizeThreshold,
+	"nearest":         uv3dp.QuantizeNearest,
+	"ordered":         uv3dp.QuantizeOrdered,
+	"floyd-steinberg": uv3dp.QuantizeFloydSteinberg,
 }
 
 func TraceVerbosef(level Verbosity, format string, args ...interface{}) {
@@ -61,6 +76,10 @@ var commandMap = map[string]struct {
 	NewCommander func() (cmd Commander)
 	Description  string
 }{
+	"antialias": {
+		NewCommander: func() Commander { return NewAntialiasCommand() },
+		Description:  "Create anti-aliased gray edges from binary layers",
+	},
 	"info": {
 		NewCommander: func() Commander { return NewInfoCommand() },
 		Description:  "Dumps information about the printable",
@@ -69,10 +88,22 @@ var commandMap = map[string]struct {
 		NewCommander: func() Commander { return NewBedCommand() },
 		Description:  "Adjust image for a different bed size/resolution",
 	},
+	"compensate": {
+		NewCommander: func() Commander { return NewCompensateCommand() },
+		Description:  "Grow or shrink the outline of all islands by a distance in mm (for resin shrinkage or bloom)",
+	},
 	"decimate": {
 		NewCommander: func() Commander { return NewDecimateCommand() },
 		Description:  "Remove outmost pixels of all islands in each layer (reduces over-curing on edges)",
 	},
+	"dim": {
+		NewCommander: func() Commander { return NewDimCommand() },
+		Description:  "Dim the interior of islands with a pattern, keeping their walls at full light",
+	},
+	"elephant-foot": {
+		NewCommander: func() Commander { return NewElephantFootCommand() },
+		Description:  "Inset the outline of the bottom layers by a distance in mm (reduces elephant's foot)",
+	},
 	"exposure": {
 		NewCommander: func() Commander { return NewExposureCommand() },
 		Description:  "Alters exposure times",
@@ -93,10 +124,30 @@ var commandMap = map[string]struct {
 		NewCommander: func() Commander { return NewResinCommand() },
 		Description:  "Changes all properties to match a selected resin",
 	},
+	"morph": {
+		NewCommander: func() Commander { return NewMorphCommand() },
+		Description:  "Erode, dilate, open or close the islands of a range of layers",
+	},
+	"pixel": {
+		NewCommander: func() Commander { return NewPixelCommand() },
+		Description:  "Combine layers with a constant or a mask, or change their gray levels",
+	},
+	"transform": {
+		NewCommander: func() Commander { return NewTransformCommand() },
+		Description:  "Move, rotate, scale, fit or crop the layers on the bed",
+	},
+	"uniformity": {
+		NewCommander: func() Commander { return NewUniformityCommand() },
+		Description:  "Scale the lit pixels of layers by a measured LCD light intensity grid, to equalize their dose (needs an output format with gray levels)",
+	},
 	"select": {
 		NewCommander: func() Commander { return NewSelectCommand() },
 		Description:  "Select to print only a range of layers",
 	},
+	"preview": {
+		NewCommander: func() Commander { return NewPreviewCommand() },
+		Description:  "Replace, render, resize or save the previews",
+	},
 }
 
 func Usage() {
@@ -157,6 +208,7 @@ func init() {
 	pflag.BoolVarP(&param.Progress, "progress", "p", false, "Show progress during operations")
 	pflag.CountVarP(&param.Verbose, "verbose", "v", "Verbosity")
 	pflag.BoolVarP(&param.Version, "version", "V", false, "Show version")
+	pflag.StringVarP(&param.Quantize, "quantize", "q", "threshold", "Gray level quantization for formats with fewer gray levels (threshold (by each format's own thresholds), nearest, ordered, floyd-steinberg)")
 	pflag.SetInterspersed(false)
 }
 
@@ -171,6 +223,13 @@ func evaluate(args []string) (err error) {
 		return
 	}
 
+	mode, ok := quantizeModes[param.Quantize]
+	if !ok {
+		err = fmt.Errorf("illegal --quantize setting: %v", param.Quantize)
+		return
+	}
+	uv3dp.SetQuantizeMode(mode)
+
 	var input uv3dp.Printable
 	var format *uv3dp.Format
 
@@ -271,6 +330,15 @@ func main() {
 		panic(err)
 	}
 
+	// Plugins do not replace the compiled in formats
+	for _, err := range plugin.Register(plugin.Dirs(), plugin.CacheFile()) {
+		fmt.Fprintf(os.Stderr, "uv3dp: plugin %v\n", err)
+	}
+
+	for _, err := range LoadMachineProfiles() {
+		fmt.Fprintf(os.Stderr, "uv3dp: machine profile %v\n", err)
+	}
+
 	pflag.Parse()
 
 	err = evaluate(pflag.Args())
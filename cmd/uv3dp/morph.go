@@ -0,0 +1,97 @@
+//
+// Copyright (c) 2020 Jason S. McMullan <jason.mcmullan@gmail.com>
+//
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/pflag"
+
+	"github.com/ezrec/uv3dp"
+)
+
+var (
+	morphOperations = map[string]uv3dp.MorphOperation{
+		"erode":  uv3dp.MorphErode,
+		"dilate": uv3dp.MorphDilate,
+		"open":   uv3dp.MorphOpen,
+		"close":  uv3dp.MorphClose,
+	}
+
+	morphKernels = map[string]uv3dp.MorphKernel{
+		"square": uv3dp.MorphKernelSquare,
+		"cross":  uv3dp.MorphKernelCross,
+		"disc":   uv3dp.MorphKernelDisc,
+	}
+)
+
+type MorphCommand struct {
+	*pflag.FlagSet
+
+	Operation string
+	Kernel    string
+	Radius    int
+	Gray      bool
+	Threshold uint8
+	Layers    string
+}
+
+func NewMorphCommand() (cmd *MorphCommand) {
+	flagSet := pflag.NewFlagSet("morph", pflag.ContinueOnError)
+	flagSet.SetInterspersed(false)
+
+	cmd = &MorphCommand{
+		FlagSet: flagSet,
+	}
+
+	cmd.StringVarP(&cmd.Operation, "operation", "o", "erode", "Operation - 'erode', 'dilate', 'open', or 'close'")
+	cmd.StringVarP(&cmd.Kernel, "kernel", "k", "square", "Kernel shape - 'square', 'cross', or 'disc'")
+	cmd.IntVarP(&cmd.Radius, "radius", "r", 1, "Kernel radius, in pixels")
+	cmd.BoolVarP(&cmd.Gray, "gray", "g", false, "Keep gray levels (min/max of the kernel), instead of thresholding")
+	cmd.Uint8VarP(&cmd.Threshold, "threshold", "t", 127, "Pixels brighter than this are on, unless --gray")
+	cmd.StringVarP(&cmd.Layers, "layers", "l", "", "Layers to change, by index ('0,5-9,12-') or Z ('1.5mm-3mm'), or 'bottom' or 'normal' (default all layers)")
+
+	return
+}
+
+func (cmd *MorphCommand) Filter(input uv3dp.Printable) (output uv3dp.Printable, err error) {
+	operation, ok := morphOperations[cmd.Operation]
+	if !ok {
+		err = fmt.Errorf("illegal --operation setting: %v", cmd.Operation)
+		return
+	}
+
+	kernel, ok := morphKernels[cmd.Kernel]
+	if !ok {
+		err = fmt.Errorf("illegal --kernel setting: %v", cmd.Kernel)
+		return
+	}
+
+	if cmd.Radius < 0 {
+		err = fmt.Errorf("illegal --radius setting: %v", cmd.Radius)
+		return
+	}
+
+	layers, err := uv3dp.ParseLayerRange(cmd.Layers)
+	if err != nil {
+		err = fmt.Errorf("illegal --layers setting: %w", err)
+		return
+	}
+
+	morph := uv3dp.Morph{
+		Operation: operation,
+		Kernel:    kernel,
+		Radius:    cmd.Radius,
+		Gray:      cmd.Gray,
+		Threshold: cmd.Threshold,
+	}
+
+	TraceVerbosef(VerbosityNotice, "  %v with a %v kernel of radius %v", cmd.Operation, cmd.Kernel, cmd.Radius)
+
+	// Previews no longer match the morphed layers
+	output = uv3dp.StalePreviews(uv3dp.NewMorphPrintable(input, morph, layers))
+
+	return
+}
@@ -0,0 +1,155 @@
+//
+// Copyright (c) 2020 Jason S. McMullan <jason.mcmullan@gmail.com>
+//
+
+package main
+
+import (
+	"fmt"
+	"image"
+	"math"
+
+	"github.com/spf13/pflag"
+
+	"github.com/ezrec/uv3dp"
+)
+
+var pixelOperations = map[string]uv3dp.PixelOperation{
+	"set":       uv3dp.PixelSet,
+	"and":       uv3dp.PixelAnd,
+	"or":        uv3dp.PixelOr,
+	"xor":       uv3dp.PixelXor,
+	"subtract":  uv3dp.PixelSubtract,
+	"invert":    uv3dp.PixelInvert,
+	"lut":       uv3dp.PixelLUT,
+	"gamma":     uv3dp.PixelGamma,
+	"threshold": uv3dp.PixelThreshold,
+}
+
+type PixelCommand struct {
+	*pflag.FlagSet
+
+	Operation string
+	Value     uint8
+	Mask      string
+	Position  []float32
+	Table     string
+	Gamma     float32
+	Threshold uint8
+	Layers    string
+}
+
+func NewPixelCommand() (cmd *PixelCommand) {
+	flagSet := pflag.NewFlagSet("pixel", pflag.ContinueOnError)
+	flagSet.SetInterspersed(false)
+
+	cmd = &PixelCommand{
+		FlagSet: flagSet,
+	}
+
+	cmd.StringVarP(&cmd.Operation, "operation", "o", "set", "Operation - 'set', 'and', 'or', 'xor', 'subtract', 'invert', 'lut', 'gamma', or 'threshold'")
+	cmd.Uint8VarP(&cmd.Value, "value", "c", 255, "Constant operand of 'set', 'and', 'or', 'xor', and 'subtract' (0..255)")
+	cmd.StringVarP(&cmd.Mask, "mask", "m", "", "PNG or JPEG image operand, instead of --value (pixels outside of it are unchanged)")
+	cmd.Float32SliceVarP(&cmd.Position, "position", "P", []float32{0, 0}, "Position of the centre of the --mask from the centre of the bed, in mm")
+	cmd.StringVarP(&cmd.Table, "lut", "L", "", "Lookup table of 'lut', as 'in:out' gray level points ('0:0,128:64,255:255')")
+	cmd.Float32VarP(&cmd.Gamma, "gamma", "g", 1.0, "Gamma of 'gamma', as 255*(pixel/255)^gamma")
+	cmd.Uint8VarP(&cmd.Threshold, "threshold", "t", 127, "Pixels brighter than this are on, for 'threshold'")
+	cmd.StringVarP(&cmd.Layers, "layers", "l", "", "Layers to change, by index ('0,5-9,12-') or Z ('1.5mm-3mm'), or 'bottom' or 'normal' (default all layers)")
+
+	return
+}
+
+func (cmd *PixelCommand) Filter(input uv3dp.Printable) (output uv3dp.Printable, err error) {
+	operation, ok := pixelOperations[cmd.Operation]
+	if !ok {
+		err = fmt.Errorf("illegal --operation setting: %v", cmd.Operation)
+		return
+	}
+
+	pixel := uv3dp.Pixel{
+		Operation: operation,
+		Value:     cmd.Value,
+		Gamma:     float64(cmd.Gamma),
+		Threshold: cmd.Threshold,
+	}
+
+	// 'set' through 'subtract' are the operations with an operand
+	hasOperand := operation <= uv3dp.PixelSubtract
+	if !hasOperand && (cmd.Changed("value") || cmd.Mask != "") {
+		err = fmt.Errorf("illegal --operation setting: '%v' has no --value or --mask operand", cmd.Operation)
+		return
+	}
+
+	if cmd.Changed("value") && cmd.Mask != "" {
+		err = fmt.Errorf("illegal --mask setting: cannot be used with --value")
+		return
+	}
+
+	if (cmd.Table != "") != (operation == uv3dp.PixelLUT) {
+		err = fmt.Errorf("illegal --lut setting: needs (and is only for) --operation lut")
+		return
+	}
+
+	if operation == uv3dp.PixelLUT {
+		pixel.Table, err = uv3dp.ParsePixelTable(cmd.Table)
+		if err != nil {
+			err = fmt.Errorf("illegal --lut setting: %w", err)
+			return
+		}
+	}
+
+	if cmd.Gamma <= 0 {
+		err = fmt.Errorf("illegal --gamma setting: %v", cmd.Gamma)
+		return
+	}
+
+	if len(cmd.Position) != 2 {
+		err = fmt.Errorf("illegal --position setting: expected X,Y")
+		return
+	}
+
+	size := input.Size()
+
+	if cmd.Mask != "" {
+		if size.X == 0 || size.Y == 0 || size.Millimeter.X <= 0 || size.Millimeter.Y <= 0 {
+			err = fmt.Errorf("pixel: printable has no size in millimeters")
+			return
+		}
+
+		var ig image.Image
+		ig, err = loadImage(cmd.Mask)
+		if err != nil {
+			return
+		}
+		pixel.Mask = uv3dp.NewPixelMask(ig)
+
+		// Centre of the mask, from the centre of the bed
+		bounds := pixel.Mask.Bounds()
+		dx := math.Round(float64(cmd.Position[0]) * float64(size.X) / float64(size.Millimeter.X))
+		dy := math.Round(float64(cmd.Position[1]) * float64(size.Y) / float64(size.Millimeter.Y))
+		pixel.Position = image.Point{
+			X: (size.X-bounds.Dx())/2 + int(dx),
+			Y: (size.Y-bounds.Dy())/2 + int(dy),
+		}
+
+		if !pixel.Mask.Bounds().Add(pixel.Position).Overlaps(image.Rect(0, 0, size.X, size.Y)) {
+			TraceVerbosef(VerbosityWarning, "  Warning: %v is entirely off the bed", cmd.Mask)
+		}
+	} else if cmd.Changed("position") {
+		err = fmt.Errorf("illegal --position setting: needs --mask")
+		return
+	}
+
+	layers, err := uv3dp.ParseLayerRange(cmd.Layers)
+	if err != nil {
+		err = fmt.Errorf("illegal --layers setting: %w", err)
+		return
+	}
+
+	TraceVerbosef(VerbosityNotice, "  Pixel operation '%v'", cmd.Operation)
+
+	// Previews no longer match the changed layers
+	output = uv3dp.StalePreviews(uv3dp.NewPixelPrintable(input, pixel, layers))
+
+	return
+}
@@ -0,0 +1,213 @@
+//
+// Copyright (c) 2020 Jason S. McMullan <jason.mcmullan@gmail.com>
+//
+
+package main
+
+import (
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	"image/png"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/pflag"
+
+	"github.com/ezrec/uv3dp"
+)
+
+type PreviewCommand struct {
+	*pflag.FlagSet
+
+	Types  []string
+	Import string
+	Export string
+	Size   []int
+	Render bool
+	View   string
+}
+
+func NewPreviewCommand() (cmd *PreviewCommand) {
+	flagSet := pflag.NewFlagSet("preview", pflag.ContinueOnError)
+	flagSet.SetInterspersed(false)
+
+	cmd = &PreviewCommand{
+		FlagSet: flagSet,
+	}
+
+	cmd.StringSliceVarP(&cmd.Types, "type", "t", nil, "Previews to change (default all existing previews, or 'tiny' and 'huge' if none)")
+	cmd.StringVarP(&cmd.Import, "import", "i", "", "Replace the previews with a PNG or JPEG image")
+	cmd.StringVarP(&cmd.Export, "export", "e", "", "Save the previews as PNG files ('%s' in the name is replaced by the preview type)")
+	cmd.IntSliceVarP(&cmd.Size, "size", "s", nil, "Fit the previews to a size (width,height), letterboxing as needed")
+	cmd.BoolVarP(&cmd.Render, "render", "r", false, "Render the previews from the layers")
+	cmd.StringVarP(&cmd.View, "view", "w", "isometric", "View of rendered previews - 'isometric' or 'top'")
+
+	return
+}
+
+// previewModifier replaces some of the previews of a printable
+type previewModifier struct {
+	uv3dp.Printable
+
+	previews map[uv3dp.PreviewType]image.Image
+}
+
+func (pm *previewModifier) Preview(index uv3dp.PreviewType) (ig image.Image, ok bool) {
+	ig, ok = pm.previews[index]
+	if !ok {
+		ig, ok = pm.Printable.Preview(index)
+	}
+
+	return
+}
+
+func (pm *previewModifier) PreviewKeys() (keys []uv3dp.PreviewType) {
+	keys = pm.Printable.PreviewKeys()
+	for key := range pm.previews {
+		_, ok := pm.Printable.Preview(key)
+		if !ok {
+			keys = append(keys, key)
+		}
+	}
+
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+
+	return
+}
+
+func loadImage(filename string) (ig image.Image, err error) {
+	reader, err := os.Open(filename)
+	if err != nil {
+		return
+	}
+	defer reader.Close()
+
+	ig, _, err = image.Decode(reader)
+	if err != nil {
+		err = fmt.Errorf("%v: %w", filename, err)
+		return
+	}
+
+	return
+}
+
+func saveImage(filename string, ig image.Image) (err error) {
+	writer, err := os.Create(filename)
+	if err != nil {
+		return
+	}
+	defer writer.Close()
+
+	err = png.Encode(writer, ig)
+
+	return
+}
+
+func (cmd *PreviewCommand) Filter(input uv3dp.Printable) (output uv3dp.Printable, err error) {
+	if cmd.Render && len(cmd.Import) > 0 {
+		err = fmt.Errorf("illegal --render setting: cannot be used with --import")
+		return
+	}
+
+	var size image.Point
+	if cmd.Changed("size") {
+		if len(cmd.Size) != 2 || cmd.Size[0] < 1 || cmd.Size[1] < 1 {
+			err = fmt.Errorf("illegal --size setting: expected width,height")
+			return
+		}
+		size = image.Pt(cmd.Size[0], cmd.Size[1])
+	}
+
+	var view uv3dp.PreviewView
+	switch cmd.View {
+	case "isometric":
+		view = uv3dp.PreviewViewIsometric
+	case "top":
+		view = uv3dp.PreviewViewTop
+	default:
+		err = fmt.Errorf("illegal --view setting: %v", cmd.View)
+		return
+	}
+
+	var types []uv3dp.PreviewType
+	for _, name := range cmd.Types {
+		types = append(types, uv3dp.PreviewType(name))
+	}
+
+	if len(types) == 0 {
+		types = input.PreviewKeys()
+	}
+
+	if len(types) == 0 && (cmd.Render || len(cmd.Import) > 0) {
+		types = []uv3dp.PreviewType{uv3dp.PreviewTypeTiny, uv3dp.PreviewTypeHuge}
+	}
+
+	if len(types) > 1 && len(cmd.Export) > 0 && !strings.Contains(cmd.Export, "%s") {
+		err = fmt.Errorf("illegal --export setting: '%s' is needed in the name to save %v previews", "%s", len(types))
+		return
+	}
+
+	var imported image.Image
+	if len(cmd.Import) > 0 {
+		imported, err = loadImage(cmd.Import)
+		if err != nil {
+			return
+		}
+	}
+
+	pm := &previewModifier{
+		Printable: input,
+		previews:  map[uv3dp.PreviewType]image.Image{},
+	}
+
+	for _, ptype := range types {
+		ig, ok := input.Preview(ptype)
+
+		switch {
+		case cmd.Render:
+			// Render at the requested, existing, or default size
+			target := size
+			if target == image.Pt(0, 0) && ok {
+				target = ig.Bounds().Size()
+			}
+			if target == image.Pt(0, 0) {
+				target, ok = uv3dp.DefaultPreviewSize[ptype]
+				if !ok {
+					err = fmt.Errorf("preview '%v' has no default size, and needs --size", ptype)
+					return
+				}
+			}
+			ig = uv3dp.RenderPreview(input, target, view)
+		case imported != nil:
+			ig = imported
+		case !ok:
+			err = fmt.Errorf("preview '%v' not found", ptype)
+			return
+		}
+
+		if size != image.Pt(0, 0) && ig.Bounds().Size() != size {
+			ig = uv3dp.LetterboxPreview(ig, size)
+		}
+
+		pm.previews[ptype] = ig
+
+		if len(cmd.Export) > 0 {
+			filename := cmd.Export
+			if strings.Contains(filename, "%s") {
+				filename = fmt.Sprintf(filename, ptype)
+			}
+
+			err = saveImage(filename, ig)
+			if err != nil {
+				return
+			}
+			TraceVerbosef(VerbosityNotice, "preview %v: saved to %v", ptype, filename)
+		}
+	}
+
+	output = pm
+
+	return
+}
@@ -0,0 +1,55 @@
+//
+// Copyright (c) 2020 Jason S. McMullan <jason.mcmullan@gmail.com>
+//
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/pflag"
+
+	"github.com/ezrec/uv3dp"
+)
+
+var resampleKernels = map[string]uv3dp.ResampleKernel{
+	"nearest":      uv3dp.ResampleNearest,
+	"bilinear":     uv3dp.ResampleBilinear,
+	"catmull-rom":  uv3dp.ResampleCatmullRom,
+	"area-average": uv3dp.ResampleAreaAverage,
+}
+
+// ResampleFlags are the resampling options of commands that scale layers
+type ResampleFlags struct {
+	Kernel      string
+	Rethreshold int
+}
+
+func (rf *ResampleFlags) AddFlags(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&rf.Kernel, "resample", "nearest", "Resampling kernel - 'nearest', 'bilinear', 'catmull-rom', or 'area-average'")
+	flagSet.IntVar(&rf.Rethreshold, "rethreshold", -1, "Threshold resampled layers again (for binary formats): pixels brighter than this are on (-1 to keep gray levels)")
+}
+
+func (rf *ResampleFlags) Resample() (resample uv3dp.Resample, err error) {
+	kernel, ok := resampleKernels[rf.Kernel]
+	if !ok {
+		err = fmt.Errorf("illegal --resample setting: %v", rf.Kernel)
+		return
+	}
+
+	if rf.Rethreshold < -1 || rf.Rethreshold > 255 {
+		err = fmt.Errorf("illegal --rethreshold setting: %v", rf.Rethreshold)
+		return
+	}
+
+	resample = uv3dp.Resample{
+		Kernel:      kernel,
+		Rethreshold: rf.Rethreshold >= 0,
+	}
+
+	if resample.Rethreshold {
+		resample.Threshold = uint8(rf.Rethreshold)
+	}
+
+	return
+}
@@ -90,7 +90,8 @@ func (cmd *SelectCommand) Filter(input uv3dp.Printable) (output uv3dp.Printable,
 		count:     count,
 	}
 
-	output = sp
+	// Previews no longer match the selected layers
+	output = uv3dp.StalePreviews(sp)
 
 	return
 }
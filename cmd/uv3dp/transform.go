@@ -0,0 +1,126 @@
+//
+// Copyright (c) 2020 Jason S. McMullan <jason.mcmullan@gmail.com>
+//
+
+package main
+
+import (
+	"fmt"
+	"image"
+	"strings"
+
+	"github.com/spf13/pflag"
+
+	"github.com/ezrec/uv3dp"
+)
+
+var transformModes = map[string]uv3dp.TransformMode{
+	"none": uv3dp.TransformNone,
+	"fit":  uv3dp.TransformFit,
+	"crop": uv3dp.TransformCrop,
+}
+
+type TransformCommand struct {
+	*pflag.FlagSet
+
+	Translate []float32
+	Rotate    float32
+	Scale     []float32
+	Mode      string
+	ResampleFlags
+}
+
+func NewTransformCommand() (cmd *TransformCommand) {
+	flagSet := pflag.NewFlagSet("transform", pflag.ContinueOnError)
+	flagSet.SetInterspersed(false)
+
+	cmd = &TransformCommand{
+		FlagSet: flagSet,
+	}
+
+	cmd.Float32SliceVarP(&cmd.Translate, "translate", "t", []float32{0, 0}, "Move by X,Y, in mm")
+	cmd.Float32VarP(&cmd.Rotate, "rotate", "r", 0, "Rotate about the centre of the bed, in degrees counter-clockwise")
+	cmd.Float32SliceVarP(&cmd.Scale, "scale", "s", []float32{100, 100}, "Scale about the centre of the bed by X,Y (or by X for both), in percent")
+	cmd.ResampleFlags.AddFlags(cmd.FlagSet)
+	cmd.StringVarP(&cmd.Mode, "mode", "m", "none", "Mode - 'none', 'fit' (scale and centre the layers to fill the bed), or 'crop' (crop the bed to the layers)")
+
+	return
+}
+
+func (cmd *TransformCommand) Filter(input uv3dp.Printable) (output uv3dp.Printable, err error) {
+	mode, ok := transformModes[cmd.Mode]
+	if !ok {
+		err = fmt.Errorf("illegal --mode setting: %v", cmd.Mode)
+		return
+	}
+
+	if len(cmd.Translate) != 2 {
+		err = fmt.Errorf("illegal --translate setting: expected X,Y")
+		return
+	}
+
+	if mode == uv3dp.TransformCrop && cmd.Changed("translate") {
+		err = fmt.Errorf("illegal --translate setting: cannot be used with --mode crop")
+		return
+	}
+
+	scale := cmd.Scale
+	if len(scale) == 1 {
+		scale = []float32{scale[0], scale[0]}
+	}
+
+	if len(scale) != 2 || scale[0] <= 0 || scale[1] <= 0 {
+		err = fmt.Errorf("illegal --scale setting: %v", cmd.Scale)
+		return
+	}
+
+	resample, err := cmd.ResampleFlags.Resample()
+	if err != nil {
+		return
+	}
+
+	transform := uv3dp.Transform{
+		X:        cmd.Translate[0],
+		Y:        cmd.Translate[1],
+		Rotate:   cmd.Rotate,
+		ScaleX:   scale[0] / 100,
+		ScaleY:   scale[1] / 100,
+		Mode:     mode,
+		Resample: resample,
+	}
+
+	content := uv3dp.ContentBounds(input)
+
+	tp, err := uv3dp.NewTransformPrintable(input, transform, content)
+	if err != nil {
+		return
+	}
+
+	size := tp.Size()
+	bed := image.Rect(0, 0, size.X, size.Y)
+	moved := uv3dp.TransformBounds(tp.Matrix(), content)
+
+	TraceVerbosef(VerbosityNotice, "  Transform: %v => %v on a %dx%d bed", content, moved, size.X, size.Y)
+
+	if !moved.In(bed) {
+		lost := []string{}
+		if moved.Min.X < 0 {
+			lost = append(lost, fmt.Sprintf("%d pixels left", -moved.Min.X))
+		}
+		if moved.Max.X > bed.Max.X {
+			lost = append(lost, fmt.Sprintf("%d pixels right", moved.Max.X-bed.Max.X))
+		}
+		if moved.Min.Y < 0 {
+			lost = append(lost, fmt.Sprintf("%d pixels above", -moved.Min.Y))
+		}
+		if moved.Max.Y > bed.Max.Y {
+			lost = append(lost, fmt.Sprintf("%d pixels below", moved.Max.Y-bed.Max.Y))
+		}
+		TraceVerbosef(VerbosityWarning, "  Warning: the layers fall off the bed, by %v", strings.Join(lost, ", "))
+	}
+
+	// Previews no longer match the bed
+	output = uv3dp.StalePreviews(tp)
+
+	return
+}
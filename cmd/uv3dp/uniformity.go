@@ -0,0 +1,156 @@
+//
+// Copyright (c) 2020 Jason S. McMullan <jason.mcmullan@gmail.com>
+//
+
+package main
+
+import (
+	"fmt"
+	"image"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/pflag"
+
+	"github.com/ezrec/uv3dp"
+)
+
+type UniformityCommand struct {
+	*pflag.FlagSet
+
+	Grid    string
+	Machine string
+	Save    bool
+	Layers  string
+}
+
+func NewUniformityCommand() (cmd *UniformityCommand) {
+	flagSet := pflag.NewFlagSet("uniformity", pflag.ContinueOnError)
+	flagSet.SetInterspersed(false)
+
+	cmd = &UniformityCommand{
+		FlagSet: flagSet,
+	}
+
+	cmd.StringVarP(&cmd.Grid, "grid", "g", "", "Measured light intensity, as a CSV of UV meter readings (a row of the grid per line), or a grayscale PNG or JPEG of the bed (lit pixels are dimmed with gray levels, which binary output formats lose)")
+	cmd.StringVarP(&cmd.Machine, "machine", "M", "", "Machine profile of the uniformity (default the only profile with a uniformity for the size of the bed)")
+	cmd.BoolVarP(&cmd.Save, "save", "s", false, "Save the --grid to the --machine profile, for later use")
+	cmd.StringVarP(&cmd.Layers, "layers", "l", "", "Layers to change, by index ('0,5-9,12-') or Z ('1.5mm-3mm'), or 'bottom' or 'normal' (default all layers)")
+
+	return
+}
+
+// loadUniformity loads a uniformity grid from a CSV, or from an image
+func loadUniformity(filename string) (uniformity *uv3dp.Uniformity, err error) {
+	if strings.ToLower(filepath.Ext(filename)) == ".csv" {
+		var reader *os.File
+		reader, err = os.Open(filename)
+		if err != nil {
+			return
+		}
+		defer reader.Close()
+
+		uniformity, err = uv3dp.ReadUniformityCSV(reader)
+	} else {
+		var ig image.Image
+		ig, err = loadImage(filename)
+		if err != nil {
+			return
+		}
+
+		uniformity, err = uv3dp.NewUniformityImage(ig)
+	}
+
+	if err != nil {
+		err = fmt.Errorf("%v: %w", filename, err)
+		return
+	}
+
+	return
+}
+
+func (cmd *UniformityCommand) Filter(input uv3dp.Printable) (output uv3dp.Printable, err error) {
+	size := input.Size()
+	if size.X == 0 || size.Y == 0 {
+		err = fmt.Errorf("uniformity: printable has no size")
+		return
+	}
+
+	if cmd.Save && (cmd.Grid == "" || cmd.Machine == "") {
+		err = fmt.Errorf("illegal --save setting: needs --grid and --machine")
+		return
+	}
+
+	if cmd.Grid != "" && cmd.Machine != "" && !cmd.Save {
+		err = fmt.Errorf("illegal --machine setting: needs --save when used with --grid")
+		return
+	}
+
+	layers, err := uv3dp.ParseLayerRange(cmd.Layers)
+	if err != nil {
+		err = fmt.Errorf("illegal --layers setting: %w", err)
+		return
+	}
+
+	var uniformity *uv3dp.Uniformity
+
+	switch {
+	case cmd.Grid != "":
+		uniformity, err = loadUniformity(cmd.Grid)
+		if err != nil {
+			return
+		}
+
+		if cmd.Save {
+			err = SaveMachineUniformity(cmd.Machine, uniformity)
+			if err != nil {
+				return
+			}
+			TraceVerbosef(VerbosityNotice, "  Saved uniformity to the '%v' machine profile", cmd.Machine)
+		}
+	case cmd.Machine != "":
+		machine, found := uv3dp.MachineFormats[cmd.Machine]
+		if !found {
+			err = fmt.Errorf("illegal --machine setting: '%s' is not a known machine type", cmd.Machine)
+			return
+		}
+
+		uniformity = machine.Machine.Uniformity
+		if uniformity == nil {
+			err = fmt.Errorf("illegal --machine setting: '%s' has no saved uniformity", cmd.Machine)
+			return
+		}
+	default:
+		// The machine profiles with a uniformity, and the size of the bed
+		names := []string{}
+		for name, machine := range uv3dp.MachineFormats {
+			msize := machine.Machine.Size
+			if machine.Machine.Uniformity != nil && msize.X == size.X && msize.Y == size.Y {
+				names = append(names, name)
+			}
+		}
+
+		sort.Strings(names)
+
+		switch len(names) {
+		case 0:
+			err = fmt.Errorf("uniformity: no --grid, and no machine profile with a uniformity for a %dx%d bed", size.X, size.Y)
+			return
+		case 1:
+			uniformity = uv3dp.MachineFormats[names[0]].Machine.Uniformity
+			TraceVerbosef(VerbosityNotice, "  Using the uniformity of the '%v' machine profile", names[0])
+		default:
+			err = fmt.Errorf("uniformity: select one of the '%v' machine profiles with --machine", strings.Join(names, "', '"))
+			return
+		}
+	}
+
+	TraceVerbosef(VerbosityNotice, "  Equalizing dose with a %dx%d uniformity grid", uniformity.Width, uniformity.Height)
+	TraceVerbosef(VerbosityWarning, "  Warning: uniformity dims lit pixels with gray levels, which are lost by binary output formats (ie '.cbddlp' without --anti-alias)")
+
+	output = uv3dp.NewUniformityPrintable(input, uniformity, layers)
+
+	return
+}
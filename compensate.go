@@ -0,0 +1,283 @@
+//
+// Copyright (c) 2020 Jason S. McMullan <jason.mcmullan@gmail.com>
+//
+
+package uv3dp
+
+import (
+	"image"
+	"math"
+)
+
+const (
+	// compensateFar is the squared distance of pixels with no nearest pixel
+	compensateFar = 1e20
+
+	// compensateFixed is the weight of an axis with no offset, which keeps
+	// edges from moving along it
+	compensateFixed = 1000.0
+)
+
+// Compensate grows, or shrinks, the outline of every island of a layer
+// by a distance in millimeters, separately in X and Y.
+//
+// Layer images are thresholded, and the new edges are anti-aliased to a
+// gray level of the pixel's coverage. Formats without gray levels will
+// threshold them again as they are encoded.
+type Compensate struct {
+	X         float32 // Distance to grow (or shrink, if negative) in X, in mm
+	Y         float32 // Distance to grow (or shrink, if negative) in Y, in mm
+	Threshold uint8   // Pixels brighter than this are on
+}
+
+// distance1D is the lower envelope of the parabolas w²(p-q)² + f(q), of
+// Felzenszwalb and Huttenlocher's distance transform, with the nearest q
+func distance1D(f []float64, w float64, d []float64, nearest []int32, v []int32, z []float64) {
+	n := len(f)
+	w2 := w * w
+
+	// Skip to the first sample with a distance
+	first := 0
+	for first < n && f[first] >= compensateFar {
+		first++
+	}
+
+	if first == n {
+		for q := range d {
+			d[q] = compensateFar
+			nearest[q] = -1
+		}
+		return
+	}
+
+	k := 0
+	v[0] = int32(first)
+	z[0] = math.Inf(-1)
+	z[1] = math.Inf(1)
+
+	for q := first + 1; q < n; q++ {
+		if f[q] >= compensateFar {
+			continue
+		}
+
+		var s float64
+		for {
+			p := int(v[k])
+			s = ((f[q] + w2*float64(q*q)) - (f[p] + w2*float64(p*p))) / (2 * w2 * float64(q-p))
+			if s > z[k] {
+				break
+			}
+			k--
+		}
+
+		k++
+		v[k] = int32(q)
+		z[k] = s
+		z[k+1] = math.Inf(1)
+	}
+
+	k = 0
+	for q := 0; q < n; q++ {
+		for z[k+1] < float64(q) {
+			k++
+		}
+		p := int(v[k])
+		d[q] = w2*float64((q-p)*(q-p)) + f[p]
+		nearest[q] = v[k]
+	}
+}
+
+// distanceTransform returns the squared distance from every pixel of an
+// image to the nearest target pixel, with pixels wx wide and wy high, and
+// the nearest X of every pixel, and the nearest Y of every column's pixel
+func distanceTransform(in *image.Gray, isTarget func(pix uint8) bool, wx, wy float64) (dist []float32, nearestX []int32, nearestY []int32) {
+	bounds := in.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+
+	size := width
+	if height > size {
+		size = height
+	}
+
+	f := make([]float64, size)
+	d := make([]float64, size)
+	nearest := make([]int32, size)
+	v := make([]int32, size)
+	z := make([]float64, size+1)
+
+	// Columns, then rows
+	dist = make([]float32, width*height)
+	nearestY = make([]int32, width*height)
+	nearestX = make([]int32, width*height)
+
+	for x := 0; x < width; x++ {
+		for y := 0; y < height; y++ {
+			if isTarget(in.Pix[in.PixOffset(bounds.Min.X+x, bounds.Min.Y+y)]) {
+				f[y] = 0
+			} else {
+				f[y] = compensateFar
+			}
+		}
+
+		distance1D(f[:height], wy, d[:height], nearest[:height], v, z)
+
+		for y := 0; y < height; y++ {
+			dist[y*width+x] = float32(d[y])
+			nearestY[y*width+x] = nearest[y]
+		}
+	}
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			f[x] = float64(dist[y*width+x])
+		}
+
+		distance1D(f[:width], wx, d[:width], nearest[:width], v, z)
+
+		for x := 0; x < width; x++ {
+			dist[y*width+x] = float32(d[x])
+			nearestX[y*width+x] = nearest[x]
+		}
+	}
+
+	return
+}
+
+// offset grows (or shrinks) the thresholded islands of an image by an
+// ellipse with semi-axes of ax and ay pixels
+func (c *Compensate) offset(in *image.Gray, ax, ay float64, grow bool) (out *image.Gray) {
+	bounds := in.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+
+	out = image.NewGray(bounds)
+
+	if width == 0 || height == 0 {
+		return
+	}
+
+	// Distances are in units of the offset
+	wx, wy := compensateFixed, compensateFixed
+	if ax > 0 {
+		wx = 1.0 / ax
+	}
+	if ay > 0 {
+		wy = 1.0 / ay
+	}
+
+	// Distances are to the pixels that are on (when growing), or off
+	isTarget := func(pix uint8) bool {
+		return (pix > c.Threshold) == grow
+	}
+
+	dist, nearestX, nearestY := distanceTransform(in, isTarget, wx, wy)
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			n := y*width + x
+			pix := in.Pix[in.PixOffset(bounds.Min.X+x, bounds.Min.Y+y)]
+
+			if isTarget(pix) {
+				if grow {
+					out.Pix[n] = 0xff
+				}
+				continue
+			}
+
+			coverage := float64(0.0)
+			if dist[n] < compensateFar && nearestX[n] >= 0 {
+				qx := int(nearestX[n])
+				qy := int(nearestY[qx+y*width])
+				dx, dy := float64(x-qx), float64(y-qy)
+
+				// Offset units per pixel, towards the nearest pixel
+				du := math.Sqrt(float64(dist[n]))
+				su := du / math.Hypot(dx, dy)
+
+				if grow {
+					coverage = (1.0-du)/su + 1.0
+				} else {
+					coverage = (du - 1.0) / su
+				}
+			} else if !grow {
+				coverage = 1.0
+			}
+
+			if coverage > 1.0 {
+				coverage = 1.0
+			} else if coverage < 0.0 {
+				coverage = 0.0
+			}
+
+			out.Pix[n] = uint8(coverage*0xff + 0.5)
+		}
+	}
+
+	return
+}
+
+// Apply returns a new image, with the islands grown (or shrunk). The size
+// is that of the printable, for the pixel pitch.
+func (c *Compensate) Apply(in *image.Gray, size Size) (out *image.Gray) {
+	pitchX := float64(size.Millimeter.X) / float64(size.X)
+	pitchY := float64(size.Millimeter.Y) / float64(size.Y)
+
+	ax := float64(c.X) / pitchX
+	ay := float64(c.Y) / pitchY
+
+	switch {
+	case ax >= 0 && ay >= 0:
+		out = c.offset(in, ax, ay, true)
+	case ax <= 0 && ay <= 0:
+		out = c.offset(in, -ax, -ay, false)
+	default:
+		// Grow along one axis, then shrink along the other
+		growX, growY := math.Max(ax, 0), math.Max(ay, 0)
+		shrinkX, shrinkY := -math.Min(ax, 0), -math.Min(ay, 0)
+
+		grown := c.offset(in, growX, growY, true)
+		shrunk := c.offset(grown, shrinkX, shrinkY, false)
+
+		// Keep the anti-aliased edges of the growth, where the shrink
+		// did not change them
+		out = grown
+		for n, pix := range shrunk.Pix {
+			thresholded := uint8(0x00)
+			if grown.Pix[n] > c.Threshold {
+				thresholded = 0xff
+			}
+			if pix != thresholded && pix < out.Pix[n] {
+				out.Pix[n] = pix
+			}
+		}
+	}
+
+	return
+}
+
+// CompensatePrintable grows, or shrinks, the islands of a range of layers
+type CompensatePrintable struct {
+	Printable
+	Compensate Compensate
+	Layers     LayerRange // Layers to compensate
+}
+
+// NewCompensatePrintable grows, or shrinks, the islands of a range of layers
+func NewCompensatePrintable(printable Printable, compensate Compensate, layers LayerRange) (cp *CompensatePrintable) {
+	cp = &CompensatePrintable{
+		Printable:  printable,
+		Compensate: compensate,
+		Layers:     layers,
+	}
+
+	return
+}
+
+func (cp *CompensatePrintable) LayerImage(index int) (ig *image.Gray) {
+	ig = cp.Printable.LayerImage(index)
+
+	if cp.Layers.Contains(cp.Printable, index) {
+		ig = cp.Compensate.Apply(ig, cp.Printable.Size())
+	}
+
+	return
+}
@@ -0,0 +1,93 @@
+//
+// Copyright (c) 2020 Jason S. McMullan <jason.mcmullan@gmail.com>
+//
+
+package uv3dp
+
+import (
+	"testing"
+
+	"image"
+)
+
+// compensateBlock is a 20x20 bed, of 0.05mm pixels, with a block from
+// (5,6) to (15,14)
+func compensateBlock() (gray *image.Gray, size Size) {
+	size = Size{X: 20, Y: 20, Millimeter: SizeMillimeter{X: 1.0, Y: 1.0}}
+
+	gray = image.NewGray(image.Rect(0, 0, 20, 20))
+	for y := 6; y < 14; y++ {
+		for x := 5; x < 15; x++ {
+			gray.Pix[gray.PixOffset(x, y)] = 0xff
+		}
+	}
+
+	return
+}
+
+func TestCompensate(t *testing.T) {
+	table := []struct {
+		name string
+		x, y float32
+		row  []uint8 // Row 10, from x = 0
+		col  []uint8 // Column 10, from y = 0
+	}{
+		{"none", 0, 0,
+			[]uint8{0, 0, 0, 0, 0, 0xff},
+			[]uint8{0, 0, 0, 0, 0, 0, 0xff},
+		},
+		{"grow", 0.1, 0.1,
+			[]uint8{0, 0, 0, 0xff, 0xff, 0xff},
+			[]uint8{0, 0, 0, 0, 0xff, 0xff, 0xff},
+		},
+		{"grow-x", 0.0625, 0,
+			[]uint8{0, 0, 0, 0x40, 0xff, 0xff},
+			[]uint8{0, 0, 0, 0, 0, 0, 0xff},
+		},
+		{"shrink-y", 0, -0.0625,
+			[]uint8{0, 0, 0, 0, 0, 0xff},
+			[]uint8{0, 0, 0, 0, 0, 0, 0, 0xbf, 0xff},
+		},
+		{"grow-x-shrink-y", 0.05, -0.05,
+			[]uint8{0, 0, 0, 0, 0xff, 0xff},
+			[]uint8{0, 0, 0, 0, 0, 0, 0, 0xff},
+		},
+	}
+
+	in, size := compensateBlock()
+
+	for _, item := range table {
+		c := &Compensate{X: item.x, Y: item.y, Threshold: 127}
+		out := c.Apply(in, size)
+
+		for x, expected := range item.row {
+			got := out.GrayAt(x, 10).Y
+			if got != expected {
+				t.Errorf("%v: (%v,10) expected %#x, got %#x", item.name, x, expected, got)
+			}
+		}
+
+		for y, expected := range item.col {
+			got := out.GrayAt(10, y).Y
+			if got != expected {
+				t.Errorf("%v: (10,%v) expected %#x, got %#x", item.name, y, expected, got)
+			}
+		}
+	}
+
+	// Growing rounds the corners
+	c := &Compensate{X: 0.1, Y: 0.1, Threshold: 127}
+	out := c.Apply(in, size)
+	if out.GrayAt(3, 4).Y == 0xff || out.GrayAt(4, 5).Y != 0xff {
+		t.Errorf("grow: unexpected corner %#x, %#x", out.GrayAt(3, 4).Y, out.GrayAt(4, 5).Y)
+	}
+
+	// Shrinking an island away
+	c = &Compensate{X: -0.3, Y: -0.3, Threshold: 127}
+	out = c.Apply(in, size)
+	for n, pix := range out.Pix {
+		if pix != 0 {
+			t.Fatalf("shrink: pixel %v not removed", n)
+		}
+	}
+}
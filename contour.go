@@ -0,0 +1,276 @@
+//
+// Copyright (c) 2020 Jason S. McMullan <jason.mcmullan@gmail.com>
+//
+
+package uv3dp
+
+import (
+	"image"
+	"math"
+	"sort"
+)
+
+// ContourPoint is a point on a contour
+type ContourPoint struct {
+	X, Y float32
+}
+
+// Contour is a closed loop of points. The last point connects to the first.
+type Contour []ContourPoint
+
+// Polygon is an outline, and the holes within it
+type Polygon struct {
+	Outline Contour
+	Holes   []Contour
+}
+
+// Area returns the signed area of a contour. Outlines have a positive
+// area, holes have a negative area.
+func (contour Contour) Area() (area float32) {
+	var sum float64
+	for n, p := range contour {
+		q := contour[(n+1)%len(contour)]
+		sum += float64(p.X)*float64(q.Y) - float64(q.X)*float64(p.Y)
+	}
+
+	area = float32(sum / 2)
+
+	return
+}
+
+// Contains returns true if a point is inside the contour
+func (contour Contour) Contains(pt ContourPoint) (inside bool) {
+	for n, p := range contour {
+		q := contour[(n+1)%len(contour)]
+		if (p.Y > pt.Y) != (q.Y > pt.Y) {
+			x := p.X + (pt.Y-p.Y)*(q.X-p.X)/(q.Y-p.Y)
+			if pt.X < x {
+				inside = !inside
+			}
+		}
+	}
+
+	return
+}
+
+// contourEdge identifies a crossing on a grid edge, between pixel centers
+type contourEdge struct {
+	x, y     int
+	vertical bool
+}
+
+// Cell edges, clockwise from the top, by the corners they join.
+// Corners are clockwise from the top left.
+var contourCellEdges = [4][2]int{{0, 1}, {1, 2}, {2, 3}, {3, 0}}
+
+// Corner offsets of a cell, clockwise from the top left
+var contourCellCorners = [4]image.Point{{0, 0}, {1, 0}, {1, 1}, {0, 1}}
+
+// ContourImage traces the edges of an image at the gray level, using
+// marching squares. Edges are placed at sub-pixel positions, interpolated
+// from the gray levels of adjacent pixels. Points are in pixels, with the
+// origin at the top left of the image, and X right and Y down.
+func ContourImage(gray *image.Gray, level float32) (polygons []Polygon) {
+	bounds := gray.Bounds()
+	width := bounds.Dx()
+	height := bounds.Dy()
+
+	value := func(x, y int) float32 {
+		if x < 0 || y < 0 || x >= width || y >= height {
+			return 0
+		}
+		return float32(gray.Pix[gray.PixOffset(bounds.Min.X+x, bounds.Min.Y+y)])
+	}
+
+	next := map[contourEdge]contourEdge{}
+	point := map[contourEdge]ContourPoint{}
+
+	// Crossing on the edge of a cell
+	crossing := func(x, y int, edge int, values *[4]float32) (key contourEdge) {
+		a, b := contourCellEdges[edge][0], contourCellEdges[edge][1]
+		pa := contourCellCorners[a].Add(image.Point{x, y})
+		pb := contourCellCorners[b].Add(image.Point{x, y})
+
+		// Keys are by the top/left end of the edge
+		if pb.X < pa.X || pb.Y < pa.Y {
+			key = contourEdge{x: pb.X, y: pb.Y, vertical: pa.X == pb.X}
+		} else {
+			key = contourEdge{x: pa.X, y: pa.Y, vertical: pa.X == pb.X}
+		}
+
+		_, found := point[key]
+		if !found {
+			t := (level - values[a]) / (values[b] - values[a])
+			point[key] = ContourPoint{
+				X: float32(pa.X) + 0.5 + t*float32(pb.X-pa.X),
+				Y: float32(pa.Y) + 0.5 + t*float32(pb.Y-pa.Y),
+			}
+		}
+
+		return
+	}
+
+	for y := -1; y < height; y++ {
+		for x := -1; x < width; x++ {
+			var values [4]float32
+			var inside [4]bool
+			count := 0
+			for n, corner := range contourCellCorners {
+				values[n] = value(x+corner.X, y+corner.Y)
+				inside[n] = values[n] >= level
+				if inside[n] {
+					count++
+				}
+			}
+
+			if count == 0 || count == 4 {
+				continue
+			}
+
+			// Clockwise, an edge from outside to inside is an entry,
+			// and from inside to outside is an exit.
+			var entries, exits []int
+			for edge, corners := range contourCellEdges {
+				a, b := inside[corners[0]], inside[corners[1]]
+				if !a && b {
+					entries = append(entries, edge)
+				} else if a && !b {
+					exits = append(exits, edge)
+				}
+			}
+
+			// Segments run from exit to entry, so that outlines are
+			// clockwise (with Y down), and holes counter-clockwise.
+			if len(entries) == 1 {
+				next[crossing(x, y, exits[0], &values)] = crossing(x, y, entries[0], &values)
+				continue
+			}
+
+			// Saddle: each entry joins the next exit clockwise, which
+			// separates the inside corners, unless the cell center is
+			// inside, where each entry joins the previous exit.
+			turn := 1
+			center := (values[0] + values[1] + values[2] + values[3]) / 4
+			if center >= level {
+				turn = 3
+			}
+
+			for _, entry := range entries {
+				next[crossing(x, y, (entry+turn)%4, &values)] = crossing(x, y, entry, &values)
+			}
+		}
+	}
+
+	// Link the segments into loops
+	var outlines, holes []Contour
+	keys := make([]contourEdge, 0, len(next))
+	for key := range next {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		if a.y != b.y {
+			return a.y < b.y
+		}
+		if a.x != b.x {
+			return a.x < b.x
+		}
+		return !a.vertical && b.vertical
+	})
+
+	for _, start := range keys {
+		_, unused := next[start]
+		if !unused {
+			continue
+		}
+
+		var contour Contour
+		for key := start; ; {
+			contour = append(contour, point[key])
+			to, ok := next[key]
+			if !ok {
+				break
+			}
+			delete(next, key)
+			key = to
+			if key == start {
+				break
+			}
+		}
+
+		contour = contour.simplify()
+		if len(contour) < 3 {
+			continue
+		}
+
+		if contour.Area() > 0 {
+			outlines = append(outlines, contour)
+		} else {
+			holes = append(holes, contour)
+		}
+	}
+
+	polygons = make([]Polygon, len(outlines))
+	for n, outline := range outlines {
+		polygons[n].Outline = outline
+	}
+
+	// Each hole belongs to the smallest outline that contains it
+	for _, hole := range holes {
+		best := -1
+		for n, outline := range outlines {
+			if !outline.Contains(hole[0]) {
+				continue
+			}
+			if best < 0 || outline.Area() < outlines[best].Area() {
+				best = n
+			}
+		}
+
+		if best >= 0 {
+			polygons[best].Holes = append(polygons[best].Holes, hole)
+		}
+	}
+
+	return
+}
+
+// Remove points that are on a straight line between their neighbors
+func (contour Contour) simplify() (out Contour) {
+	for n, p := range contour {
+		prev := contour[(n+len(contour)-1)%len(contour)]
+		next := contour[(n+1)%len(contour)]
+		cross := (p.X-prev.X)*(next.Y-p.Y) - (p.Y-prev.Y)*(next.X-p.X)
+		if math.Abs(float64(cross)) > 1e-6 {
+			out = append(out, p)
+		}
+	}
+
+	return
+}
+
+// LayerContours traces the edges of a layer at the gray level. Points are
+// in millimeters, with the origin at the top left of the layer image, and
+// X right and Y down.
+func LayerContours(p Printable, index int, level float32) (polygons []Polygon) {
+	size := p.Size()
+	scaleX := size.Millimeter.X / float32(size.X)
+	scaleY := size.Millimeter.Y / float32(size.Y)
+
+	polygons = ContourImage(p.LayerImage(index), level)
+	scale := func(contour Contour) {
+		for n := range contour {
+			contour[n].X *= scaleX
+			contour[n].Y *= scaleY
+		}
+	}
+
+	for _, polygon := range polygons {
+		scale(polygon.Outline)
+		for _, hole := range polygon.Holes {
+			scale(hole)
+		}
+	}
+
+	return
+}
@@ -0,0 +1,188 @@
+//
+// Copyright (c) 2020 Jason S. McMullan <jason.mcmullan@gmail.com>
+//
+
+package uv3dp
+
+import (
+	"image"
+	"math"
+
+	"testing"
+)
+
+func grayFromPattern(pattern string) (gray *image.Gray) {
+	var rows []string
+	row := ""
+	for _, c := range pattern {
+		if c == '\n' {
+			if len(row) > 0 {
+				rows = append(rows, row)
+			}
+			row = ""
+			continue
+		}
+		row += string(c)
+	}
+
+	gray = image.NewGray(image.Rect(0, 0, len(rows[0]), len(rows)))
+	for y, row := range rows {
+		for x, c := range row {
+			switch c {
+			case 'X':
+				gray.Pix[y*gray.Stride+x] = 0xff
+			case 'x':
+				gray.Pix[y*gray.Stride+x] = 0x80
+			}
+		}
+	}
+
+	return
+}
+
+func TestContourImage(t *testing.T) {
+	table := []struct {
+		Name    string
+		Pattern string
+		Level   float32
+		Areas   []float32 // Outline area, followed by the hole areas, for each polygon
+	}{
+		{"empty", `
+......
+......
+`, 127.5, nil},
+		{"square", `
+......
+.XXXX.
+.XXXX.
+.XXXX.
+.XXXX.
+......
+`, 127.5, []float32{15.5}},
+		{"edge", `
+XXXX
+XXXX
+`, 127.5, []float32{7.5}},
+		{"ring", `
+........
+.XXXXXX.
+.XXXXXX.
+.XX..XX.
+.XX..XX.
+.XXXXXX.
+.XXXXXX.
+........
+`, 127.5, []float32{35.5, -3.5}},
+		{"islands", `
+.......
+.XX.XX.
+.XX.XX.
+.......
+`, 127.5, []float32{3.5, 3.5}},
+		{"saddle connected", `
+....
+.X..
+..X.
+....
+`, 127.5, []float32{1.5}},
+		{"saddle separate", `
+....
+.X..
+..X.
+....
+`, 200, nil},
+		{"anti-aliased", `
+.....
+.XXx.
+.....
+`, 127.5, nil},
+	}
+
+	for _, item := range table {
+		polygons := ContourImage(grayFromPattern(item.Pattern), item.Level)
+
+		var areas []float32
+		for _, polygon := range polygons {
+			areas = append(areas, polygon.Outline.Area())
+			for _, hole := range polygon.Holes {
+				areas = append(areas, hole.Area())
+			}
+		}
+
+		switch item.Name {
+		case "saddle separate":
+			// Two small diamonds
+			if len(polygons) != 2 || len(areas) != 2 || areas[0] <= 0 || math.Abs(float64(areas[0]-areas[1])) > 1e-6 {
+				t.Errorf("%v: expected two equal islands, got %v", item.Name, areas)
+			}
+			continue
+		case "anti-aliased":
+			// Right edge is just past the center of the half-lit pixel
+			if len(polygons) != 1 {
+				t.Fatalf("%v: expected one polygon, got %v", item.Name, len(polygons))
+			}
+			var right float32
+			for _, p := range polygons[0].Outline {
+				if p.X > right {
+					right = p.X
+				}
+			}
+			expected := float32(3.5 + (128-127.5)/128.0)
+			if math.Abs(float64(right-expected)) > 1e-5 {
+				t.Errorf("%v: expected right edge at %v, got %v", item.Name, expected, right)
+			}
+			continue
+		}
+
+		if len(areas) != len(item.Areas) {
+			t.Errorf("%v: expected areas %v, got %v", item.Name, item.Areas, areas)
+			continue
+		}
+
+		for n := range areas {
+			if math.Abs(float64(areas[n]-item.Areas[n])) > 1e-5 {
+				t.Errorf("%v: expected areas %v, got %v", item.Name, item.Areas, areas)
+				break
+			}
+		}
+	}
+}
+
+func TestLayerContours(t *testing.T) {
+	prop := Properties{
+		Size: Size{
+			X:          4,
+			Y:          2,
+			Millimeter: SizeMillimeter{X: 2.0, Y: 4.0},
+			Layers:     1,
+		},
+	}
+
+	printable := &patternPrint{
+		Print: Print{Properties: prop},
+		Gray: grayFromPattern(`
+XXXX
+XXXX
+`),
+	}
+
+	polygons := LayerContours(printable, 0, 127.5)
+	if len(polygons) != 1 {
+		t.Fatalf("expected one polygon, got %v", len(polygons))
+	}
+
+	// 0.5mm x 2mm pixels, with the corners clipped
+	area := polygons[0].Outline.Area()
+	if math.Abs(float64(area)-(8.0-0.5)) > 1e-5 {
+		t.Errorf("expected an area of 7.5mm^2, got %v", area)
+	}
+}
+
+type patternPrint struct {
+	Print
+	Gray *image.Gray
+}
+
+func (pp *patternPrint) LayerImage(index int) *image.Gray {
+	return pp.Gray
+}
@@ -0,0 +1,35 @@
+//
+// Copyright (c) 2020 Jason S. McMullan <jason.mcmullan@gmail.com>
+//
+
+package ctb
+
+import (
+	"testing"
+
+	"github.com/ezrec/uv3dp"
+	"github.com/ezrec/uv3dp/uv3dptest"
+)
+
+func TestConformance(t *testing.T) {
+	newFormatter := func(suffix string) uv3dp.Formatter { return NewFormatter(suffix) }
+
+	uv3dptest.RunFormatterConformance(t, newFormatter, uv3dptest.Capabilities{
+		Suffix:        ".ctb",
+		GrayLevels:    128, // 7-bit gray
+		LayerExposure: true,
+		LayerZ:        true,
+		Previews:      []uv3dp.PreviewType{uv3dp.PreviewTypeTiny, uv3dp.PreviewTypeHuge},
+		Fixup: func(kind uv3dptest.ExposureKind, exposure *uv3dp.Exposure) {
+			// Retract height is not saved
+			exposure.RetractHeight = defaultRetractHeight
+
+			// Layers do not have their own lift or retract
+			if kind == uv3dptest.ExposureLayer {
+				exposure.LiftHeight = 0
+				exposure.LiftSpeed = 0
+				exposure.RetractSpeed = 0
+			}
+		},
+	})
+}
@@ -0,0 +1,42 @@
+//
+// Copyright (c) 2020 Jason S. McMullan <jason.mcmullan@gmail.com>
+//
+
+package cws
+
+import (
+	"testing"
+
+	"github.com/ezrec/uv3dp"
+	"github.com/ezrec/uv3dp/uv3dptest"
+)
+
+func TestConformance(t *testing.T) {
+	newFormatter := func(suffix string) uv3dp.Formatter { return NewFormatter(suffix) }
+
+	// Retract height is not saved, and there is one lift height for all
+	// of the layers
+	fixup := func(kind uv3dptest.ExposureKind, exposure *uv3dp.Exposure) {
+		exposure.RetractHeight = 0
+		exposure.LiftHeight = 0
+	}
+
+	t.Run("rgb", func(t *testing.T) {
+		uv3dptest.RunFormatterConformance(t, newFormatter, uv3dptest.Capabilities{
+			Suffix:   ".cws",
+			Previews: []uv3dp.PreviewType{uv3dp.PreviewTypeTiny, uv3dp.PreviewTypeHuge},
+			Fixup:    fixup,
+		})
+	})
+
+	// Mono slices pack three pixels into each RGB pixel
+	t.Run("mono", func(t *testing.T) {
+		uv3dptest.RunFormatterConformance(t, newFormatter, uv3dptest.Capabilities{
+			Suffix:   ".cws",
+			Args:     []string{"--mono"},
+			Bed:      uv3dp.MachineSize{X: 66, Y: 40, Xmm: 3.3, Ymm: 2.0},
+			Previews: []uv3dp.PreviewType{uv3dp.PreviewTypeTiny, uv3dp.PreviewTypeHuge},
+			Fixup:    fixup,
+		})
+	})
+}
@@ -11,6 +11,7 @@ import (
 	"errors"
 	"fmt"
 	"image"
+	"image/draw"
 	"image/png"
 	"io"
 	"reflect"
@@ -255,6 +256,8 @@ type Print struct {
 
 type Format struct {
 	*pflag.FlagSet
+
+	Mono bool // RGB sub-pixel packed slices, and NovaMaker G-code
 }
 
 func NewFormatter(suffix string) (sf *Format) {
@@ -264,11 +267,26 @@ func NewFormatter(suffix string) (sf *Format) {
 		FlagSet: flagSet,
 	}
 
+	sf.BoolVarP(&sf.Mono, "mono", "m", false, "Mono LCD (ie Bene4 Mono): pack 3 pixels per RGB slice pixel")
 	sf.SetInterspersed(false)
 
 	return
 }
 
+// Archive files of the thumbnails
+var thumbnailFiles = map[uv3dp.PreviewType]string{
+	uv3dp.PreviewTypeTiny: "thumbnail/thumbnail400x400.png",
+	uv3dp.PreviewTypeHuge: "thumbnail/thumbnail800x480.png",
+}
+
+// PreviewSizes returns the sizes of the thumbnails in the archive
+func (sf *Format) PreviewSizes() map[uv3dp.PreviewType]image.Point {
+	return map[uv3dp.PreviewType]image.Point{
+		uv3dp.PreviewTypeTiny: {X: 400, Y: 400},
+		uv3dp.PreviewTypeHuge: {X: 800, Y: 480},
+	}
+}
+
 func (sf *Format) Encode(writer uv3dp.Writer, printable uv3dp.Printable) (err error) {
 	jobName := defaultName
 
@@ -296,7 +314,12 @@ func (sf *Format) Encode(writer uv3dp.Writer, printable uv3dp.Printable) (err er
 			return
 		}
 
-		err = png.Encode(writer, p.LayerImage(n))
+		var layerImage image.Image = p.LayerImage(n)
+		if sf.Mono {
+			layerImage = PackRGB(layerImage.(*image.Gray))
+		}
+
+		err = png.Encode(writer, layerImage)
 		if err != nil {
 			return
 		}
@@ -344,6 +367,38 @@ func (sf *Format) Encode(writer uv3dp.Writer, printable uv3dp.Printable) (err er
 		return
 	}
 
+	if sf.Mono {
+		emitMonoGcode(gcode, printable)
+	} else {
+		emitGcode(gcode, printable)
+	}
+
+	// Save the thumbnails
+	for code, filename := range thumbnailFiles {
+		image, ok := printable.Preview(code)
+		if !ok {
+			continue
+		}
+
+		var writer io.Writer
+		writer, err = archive.Create(filename)
+		if err != nil {
+			return
+		}
+
+		err = png.Encode(writer, image)
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+// Emit the Elfin G-code (relative Z motion)
+func emitGcode(gcode io.Writer, printable uv3dp.Printable) {
+	size := printable.Size()
+
 	// Emit the GCode header
 	fmt.Fprintf(gcode, `
 G28
@@ -380,8 +435,52 @@ M106 S0
 	fmt.Fprintf(gcode, "M106 S0\n")
 	fmt.Fprintf(gcode, "G1 Z80\n")
 	fmt.Fprintf(gcode, ";<Completed>\n")
+}
 
-	return
+// Emit the NovaMaker G-code used by the mono LCD machines (absolute Z motion)
+func emitMonoGcode(gcode io.Writer, printable uv3dp.Printable) {
+	size := printable.Size()
+
+	// Emit the GCode header
+	fmt.Fprintf(gcode, `
+G21;
+G90;
+M106 S0;
+G28 Z0;
+`)
+
+	if size.Layers > 0 {
+		fmt.Fprintf(gcode, "G1 Z%1.3f F%v;\n", printable.LayerZ(0), int(printable.LayerExposure(0).LiftSpeed))
+	}
+
+	// Create all the layer movement gcode
+	lastZ := float32(0.0)
+	for n := 0; n < size.Layers; n++ {
+		layerZ := printable.LayerZ(n)
+		layerExposure := printable.LayerExposure(n)
+
+		retractSpeed := layerExposure.RetractSpeed
+		if retractSpeed == 0 {
+			retractSpeed = layerExposure.LiftSpeed
+		}
+
+		fmt.Fprintf(gcode, "\n;<Slice> %v\n", n)
+		fmt.Fprintf(gcode, "M106 S%v;\n;<Delay> %v\n", layerExposure.LightPWM, int(layerExposure.LightOnTime*1000.0))
+		fmt.Fprintf(gcode, "M106 S0;\n;<Slice> Blank\n")
+		fmt.Fprintf(gcode, "G1 Z%1.3f F%v;\n", layerZ+layerExposure.LiftHeight, int(layerExposure.LiftSpeed))
+		if n+1 < size.Layers {
+			fmt.Fprintf(gcode, "G1 Z%1.3f F%v;\n", printable.LayerZ(n+1), int(retractSpeed))
+		}
+		fmt.Fprintf(gcode, ";<Delay> %v\n", int(layerExposure.LightOffTime*1000.0))
+		lastZ = layerZ + layerExposure.LiftHeight
+	}
+
+	// Emit the GCode trailer
+	fmt.Fprintf(gcode, "\n")
+	fmt.Fprintf(gcode, "M106 S0;\n")
+	fmt.Fprintf(gcode, "G1 Z%1.3f F25;\n", lastZ+20.0)
+	fmt.Fprintf(gcode, "M18;\n")
+	fmt.Fprintf(gcode, ";<Completed>\n")
 }
 
 func (sf *Format) Decode(reader uv3dp.Reader, filesize int64) (printable uv3dp.Printable, err error) {
@@ -444,13 +543,8 @@ func (sf *Format) Decode(reader uv3dp.Reader, filesize int64) (printable uv3dp.P
 	}
 
 	// Collect the thumbnails
-	thumbs := map[uv3dp.PreviewType]string{
-		uv3dp.PreviewTypeTiny: "thumbnail/thumbnail400x400.png",
-		uv3dp.PreviewTypeHuge: "thumbnail/thumbnail800x480.png",
-	}
-
 	thumbImage := make(map[uv3dp.PreviewType]image.Image)
-	for pt, pn := range thumbs {
+	for pt, pn := range thumbnailFiles {
 		file, ok := fileMap[pn]
 		if !ok {
 			continue
@@ -497,9 +591,15 @@ func (sf *Format) Decode(reader uv3dp.Reader, filesize int64) (printable uv3dp.P
 	exp.RetractSpeed = config.ZLiftRetractRate
 
 	bot.LightOffTime = exp.LightOffTime
-	bot.LiftSpeed = exp.LiftSpeed
+	bot.LiftHeight = exp.LiftHeight
+	bot.LiftSpeed = config.ZBottomLiftFeedRate
+	if bot.LiftSpeed == 0 {
+		bot.LiftSpeed = exp.LiftSpeed
+	}
 	bot.RetractSpeed = exp.RetractSpeed
 
+	prop.Preview = thumbImage
+
 	cws := &Print{
 		Print:    uv3dp.Print{Properties: prop},
 		layerPng: layerPng,
@@ -518,7 +618,19 @@ func (cws *Print) LayerImage(index int) (imageGray *image.Gray) {
 	if err != nil {
 		panic(err)
 	}
-	imageGray = pngImage.(*image.Gray)
+
+	bounds := cws.Bounds()
+
+	imageGray, ok := pngImage.(*image.Gray)
+	if !ok {
+		if pngImage.Bounds().Dx() < bounds.Dx() {
+			// RGB sub-pixel packed slice
+			imageGray = UnpackRGB(pngImage, bounds)
+		} else {
+			imageGray = image.NewGray(pngImage.Bounds())
+			draw.Draw(imageGray, imageGray.Bounds(), pngImage, pngImage.Bounds().Min, draw.Src)
+		}
+	}
 
 	return
 }
@@ -30,6 +30,10 @@ func (br *bufferReader) ReadAt(p []byte, off int64) (n int, err error) {
 	return
 }
 
+func (br *bufferReader) Read(p []byte) (n int, err error) {
+	return 0, io.EOF
+}
+
 func (br *bufferReader) Len() int64 {
 	return int64(len(br.data))
 }
@@ -211,3 +215,125 @@ func TestEncodeEmptyCWS(t *testing.T) {
 		}
 	}
 }
+
+const (
+	testMonoGcode = `
+G21;
+G90;
+M106 S0;
+G28 Z0;
+G1 Z0.050 F120;
+
+;<Slice> 0
+M106 S255;
+;<Delay> 16500
+M106 S0;
+;<Slice> Blank
+G1 Z5.550 F120;
+G1 Z0.100 F200;
+;<Delay> 2250
+
+;<Slice> 1
+M106 S255;
+;<Delay> 16500
+M106 S0;
+;<Slice> Blank
+G1 Z5.600 F120;
+G1 Z0.150 F200;
+;<Delay> 2250
+
+;<Slice> 2
+M106 S100;
+;<Delay> 16500
+M106 S0;
+;<Slice> Blank
+G1 Z5.650 F120;
+G1 Z0.200 F200;
+;<Delay> 2250
+
+;<Slice> 3
+M106 S100;
+;<Delay> 16500
+M106 S0;
+;<Slice> Blank
+G1 Z5.700 F120;
+;<Delay> 2250
+
+M106 S0;
+G1 Z25.700 F25;
+M18;
+;<Completed>
+`
+)
+
+type stripePrint struct {
+	uv3dp.Print
+}
+
+func (sp *stripePrint) LayerImage(index int) (gi *image.Gray) {
+	gi = image.NewGray(sp.Bounds())
+	for n := range gi.Pix {
+		if (n+index)%3 == 0 {
+			gi.Pix[n] = 0xff
+		}
+	}
+
+	return
+}
+
+func TestEncodeMonoCWS(t *testing.T) {
+	time_Now = func() (now time.Time) { return }
+
+	stripes := &stripePrint{Print: uv3dp.Print{Properties: testProperties}}
+
+	formatter := NewFormatter(".cws")
+	err := formatter.Parse([]string{"--mono"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buffWriter := &bytes.Buffer{}
+	err = formatter.Encode(buffWriter, stripes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buffReader := &bufferReader{buffWriter.Bytes()}
+
+	archive, _ := zip.NewReader(buffReader, buffReader.Len())
+
+	for _, file := range archive.File {
+		rc, _ := file.Open()
+		defer rc.Close()
+
+		switch {
+		case file.Name == "uv3dp.gcode":
+			got, _ := io.ReadAll(rc)
+			if !strings.HasSuffix(string(got), testMonoGcode) {
+				t.Errorf("%s: expected suffix:\n%v\n  got:\n%v", file.Name, testMonoGcode, string(got))
+			}
+		case strings.HasPrefix(file.Name, "thumbnail/"):
+			// Thumbnails are not packed
+		case strings.HasSuffix(file.Name, ".png"):
+			config, err := png.DecodeConfig(rc)
+			if err != nil {
+				t.Fatalf("%s: %v", file.Name, err)
+			}
+			// 10 mono pixels => 4 RGB pixels
+			if config.Width != 4 || config.Height != 20 {
+				t.Errorf("%s: expected 4x20, got %vx%v", file.Name, config.Width, config.Height)
+			}
+		}
+	}
+
+	printable, err := formatter.Decode(buffReader, buffReader.Len())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for n := 0; n < testProperties.Size.Layers; n++ {
+		if !bytes.Equal(printable.LayerImage(n).Pix, stripes.LayerImage(n).Pix) {
+			t.Errorf("layer %v: did not survive round trip", n)
+		}
+	}
+}
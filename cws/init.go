@@ -16,6 +16,16 @@ var (
 			Size:  uv3dp.MachineSize{X: 1410, Y: 2550, Xmm: 73.0, Ymm: 132.0},
 		},
 	}
+	machines_cws_mono = map[string]uv3dp.Machine{
+		"bene4mono": {Vendor: "Nova3D",
+			Model: "Bene4 Mono",
+			Size:  uv3dp.MachineSize{X: 1566, Y: 2549, Xmm: 79.86, Ymm: 130.0},
+		},
+		"elfin2mono": {Vendor: "Nova3D",
+			Model: "Elfin2 Mono SE",
+			Size:  uv3dp.MachineSize{X: 1620, Y: 2560, Xmm: 82.62, Ymm: 130.56},
+		},
+	}
 )
 
 func init() {
@@ -24,4 +34,5 @@ func init() {
 	uv3dp.RegisterFormatter(".cws", newFormatter)
 
 	uv3dp.RegisterMachines(machines_cws, ".cws")
+	uv3dp.RegisterMachines(machines_cws_mono, ".cws", "--mono")
 }
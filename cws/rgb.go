@@ -0,0 +1,58 @@
+//
+// Copyright (c) 2020 Jason S. McMullan <jason.mcmullan@gmail.com>
+//
+
+package cws
+
+import (
+	"image"
+	"image/color"
+)
+
+// PackRGB packs three horizontally adjacent mono pixels into the
+// R, G, and B sub-pixels of a single RGB pixel, as used by the
+// mono-LCD machines (ie Bene4 Mono).
+func PackRGB(gray *image.Gray) (packed *image.NRGBA) {
+	bounds := gray.Bounds()
+	size := bounds.Size()
+
+	packed = image.NewNRGBA(image.Rect(0, 0, (size.X+2)/3, size.Y))
+
+	for y := 0; y < size.Y; y++ {
+		src := gray.Pix[gray.PixOffset(bounds.Min.X, bounds.Min.Y+y):]
+		src = src[:size.X]
+		dst := packed.Pix[packed.PixOffset(0, y):]
+		for x, pix := range src {
+			dst[(x/3)*4+(x%3)] = pix
+		}
+		for x := 0; x < packed.Rect.Dx(); x++ {
+			dst[x*4+3] = 0xff
+		}
+	}
+
+	return
+}
+
+// UnpackRGB unpacks the R, G, and B sub-pixels of an RGB image
+// into three horizontally adjacent mono pixels, cropped to bounds.
+func UnpackRGB(packed image.Image, bounds image.Rectangle) (gray *image.Gray) {
+	gray = image.NewGray(bounds)
+
+	pb := packed.Bounds()
+	size := bounds.Size()
+
+	for y := 0; y < size.Y && y < pb.Dy(); y++ {
+		dst := gray.Pix[gray.PixOffset(bounds.Min.X, bounds.Min.Y+y):]
+		for x := 0; x < pb.Dx() && x*3 < size.X; x++ {
+			c := color.NRGBAModel.Convert(packed.At(pb.Min.X+x, pb.Min.Y+y)).(color.NRGBA)
+			sub := []uint8{c.R, c.G, c.B}
+			for n, pix := range sub {
+				if x*3+n < size.X {
+					dst[x*3+n] = pix
+				}
+			}
+		}
+	}
+
+	return
+}
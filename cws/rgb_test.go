@@ -0,0 +1,40 @@
+package cws
+
+import (
+	"bytes"
+	"image"
+	"testing"
+)
+
+func TestPackRGB(t *testing.T) {
+	rect := image.Rect(0, 0, 7, 2)
+	gray := &image.Gray{
+		Pix: []uint8{
+			0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07,
+			0xff, 0x00, 0x00, 0x00, 0xff, 0x00, 0xff,
+		},
+		Stride: rect.Size().X,
+		Rect:   rect,
+	}
+
+	out_pix := []uint8{
+		0x01, 0x02, 0x03, 0xff, 0x04, 0x05, 0x06, 0xff, 0x07, 0x00, 0x00, 0xff,
+		0xff, 0x00, 0x00, 0xff, 0x00, 0xff, 0x00, 0xff, 0xff, 0x00, 0x00, 0xff,
+	}
+
+	packed := PackRGB(gray)
+
+	if packed.Bounds() != image.Rect(0, 0, 3, 2) {
+		t.Fatalf("expected %v, got %v", image.Rect(0, 0, 3, 2), packed.Bounds())
+	}
+
+	if !bytes.Equal(packed.Pix, out_pix) {
+		t.Fatalf("expected %#v, got %#v", out_pix, packed.Pix)
+	}
+
+	unpacked := UnpackRGB(packed, rect)
+
+	if !bytes.Equal(unpacked.Pix, gray.Pix) {
+		t.Fatalf("expected %#v, got %#v", gray.Pix, unpacked.Pix)
+	}
+}
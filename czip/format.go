@@ -93,18 +93,22 @@ func (cfg *czipConfig) Unmarshal(in string) (ok bool) {
 		if line[i] == ':' {
 			attr = line[:i]
 			line = line[i+1:]
-			i = 0
+			i = -1
 		} else if line[i] == ' ' || line[i] == '\t' || line[i] == '\r' || line[i] == '\n' || line[i] == '#' {
 			break
 		}
 	}
 	val := line[:i]
 
-	fmt.Printf(": '%s' => '%s' '%s'\n", in, attr, val)
-	if attr == "" || val == "" {
+	if attr == "" {
 		return false
 	}
 
+	// Empty settings (ie ';fileName:') are left unset
+	if val == "" {
+		return true
+	}
+
 	name := strings.ToUpper(attr[:1]) + attr[1:]
 
 	t := reflect.TypeOf(cfg).Elem()
@@ -166,6 +170,15 @@ func NewFormatter(suffix string) (sf *Format) {
 	return
 }
 
+// PreviewSizes returns the sizes of the previews written by ChiTuBox, as
+// 'preview_cropping.png' and 'preview.png'
+func (sf *Format) PreviewSizes() map[uv3dp.PreviewType]image.Point {
+	return map[uv3dp.PreviewType]image.Point{
+		uv3dp.PreviewTypeTiny: {X: 168, Y: 150},
+		uv3dp.PreviewTypeHuge: {X: 954, Y: 850},
+	}
+}
+
 func (sf *Format) Encode(writer uv3dp.Writer, printable uv3dp.Printable) (err error) {
 	archive := zip.NewWriter(writer)
 	defer archive.Close()
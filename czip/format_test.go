@@ -0,0 +1,44 @@
+//
+// Copyright (c) 2020 Jason S. McMullan <jason.mcmullan@gmail.com>
+//
+
+package czip
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ezrec/uv3dp"
+	"github.com/ezrec/uv3dp/uv3dptest"
+)
+
+func TestPreviewSizes(t *testing.T) {
+	formatter := NewFormatter(".zip")
+
+	// Reference previews are not of the ChiTuBox sizes
+	printable := uv3dptest.NewDecorated(uv3dptest.DefaultBed)
+	printable = uv3dp.NewPreviewPrintable(printable, formatter)
+
+	buff := &bytes.Buffer{}
+	err := formatter.Encode(buff, printable)
+	if err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+
+	got, err := formatter.Decode(bytes.NewReader(buff.Bytes()), int64(buff.Len()))
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+
+	for ptype, size := range formatter.PreviewSizes() {
+		ig, ok := got.Preview(ptype)
+		if !ok {
+			t.Errorf("preview %v: not found", ptype)
+			continue
+		}
+
+		if ig.Bounds().Size() != size {
+			t.Errorf("preview %v: expected %v, got %v", ptype, size, ig.Bounds().Size())
+		}
+	}
+}
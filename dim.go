@@ -0,0 +1,154 @@
+//
+// Copyright (c) 2020 Jason S. McMullan <jason.mcmullan@gmail.com>
+//
+
+package uv3dp
+
+import (
+	"image"
+	"image/color"
+)
+
+// DimPattern is a tile of light levels, repeated across the bed from
+// its origin, that scales the gray level of the pixels it covers
+type DimPattern struct {
+	Tile *image.Gray
+}
+
+// NewSolidPattern dims every pixel to a light level
+func NewSolidPattern(level uint8) (dp *DimPattern) {
+	tile := image.NewGray(image.Rect(0, 0, 1, 1))
+	tile.Pix[0] = level
+
+	dp = &DimPattern{Tile: tile}
+
+	return
+}
+
+// NewCheckerboardPattern dims every other pixel to a light level
+func NewCheckerboardPattern(level uint8) (dp *DimPattern) {
+	return NewChessboardPattern(level, 1)
+}
+
+// NewChessboardPattern dims every other square cell of pixels to a light
+// level
+func NewChessboardPattern(level uint8, cell int) (dp *DimPattern) {
+	if cell < 1 {
+		cell = 1
+	}
+
+	tile := image.NewGray(image.Rect(0, 0, cell*2, cell*2))
+	for y := 0; y < cell*2; y++ {
+		for x := 0; x < cell*2; x++ {
+			pix := uint8(0xff)
+			if (x/cell+y/cell)%2 == 1 {
+				pix = level
+			}
+			tile.Pix[tile.PixOffset(x, y)] = pix
+		}
+	}
+
+	dp = &DimPattern{Tile: tile}
+
+	return
+}
+
+// NewTilePattern dims pixels by an image, with black as the light level,
+// and white as full light
+func NewTilePattern(ig image.Image, level uint8) (dp *DimPattern) {
+	bounds := ig.Bounds()
+	tile := image.NewGray(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+
+	for y := 0; y < bounds.Dy(); y++ {
+		for x := 0; x < bounds.Dx(); x++ {
+			gray := color.GrayModel.Convert(ig.At(bounds.Min.X+x, bounds.Min.Y+y)).(color.Gray).Y
+			tile.Pix[tile.PixOffset(x, y)] = level + uint8((uint(0xff-level)*uint(gray)+0x7f)/0xff)
+		}
+	}
+
+	dp = &DimPattern{Tile: tile}
+
+	return
+}
+
+// Level returns the light level of the pattern at a pixel
+func (dp *DimPattern) Level(x, y int) uint8 {
+	bounds := dp.Tile.Bounds()
+	dx, dy := bounds.Dx(), bounds.Dy()
+
+	x %= dx
+	if x < 0 {
+		x += dx
+	}
+
+	y %= dy
+	if y < 0 {
+		y += dy
+	}
+
+	return dp.Tile.Pix[dp.Tile.PixOffset(bounds.Min.X+x, bounds.Min.Y+y)]
+}
+
+// Dim returns a pixel's gray level, scaled by the pattern
+func (dp *DimPattern) Dim(x, y int, pix uint8) uint8 {
+	return uint8((uint(pix)*uint(dp.Level(x, y)) + 0x7f) / 0xff)
+}
+
+// Dim dims the interior of every island of a layer by a pattern, keeping
+// the walls of the islands at full light
+type Dim struct {
+	Pattern   *DimPattern
+	Wall      float32 // Thickness of the walls, in mm
+	Threshold uint8   // Pixels brighter than this are on
+}
+
+// Apply returns a new image, with the interior of its islands dimmed. The
+// size is that of the printable, for the pixel pitch.
+func (d *Dim) Apply(in *image.Gray, size Size) (out *image.Gray) {
+	// The interior, anti-aliased
+	inset := Compensate{X: -d.Wall, Y: -d.Wall, Threshold: d.Threshold}
+	interior := inset.Apply(in, size)
+
+	bounds := in.Bounds()
+	out = image.NewGray(bounds)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			n := out.PixOffset(x, y)
+			pix := in.Pix[in.PixOffset(x, y)]
+			coverage := uint(interior.Pix[n])
+
+			dimmed := d.Pattern.Dim(x, y, pix)
+			out.Pix[n] = uint8((uint(pix)*(0xff-coverage) + uint(dimmed)*coverage + 0x7f) / 0xff)
+		}
+	}
+
+	return
+}
+
+// DimPrintable dims the interior of the islands of a range of layers
+type DimPrintable struct {
+	Printable
+	Dim    Dim
+	Layers LayerRange // Layers to dim
+}
+
+// NewDimPrintable dims the interior of the islands of a range of layers
+func NewDimPrintable(printable Printable, dim Dim, layers LayerRange) (dp *DimPrintable) {
+	dp = &DimPrintable{
+		Printable: printable,
+		Dim:       dim,
+		Layers:    layers,
+	}
+
+	return
+}
+
+func (dp *DimPrintable) LayerImage(index int) (ig *image.Gray) {
+	ig = dp.Printable.LayerImage(index)
+
+	if dp.Layers.Contains(dp.Printable, index) {
+		ig = dp.Dim.Apply(ig, dp.Printable.Size())
+	}
+
+	return
+}
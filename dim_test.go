@@ -0,0 +1,79 @@
+//
+// Copyright (c) 2020 Jason S. McMullan <jason.mcmullan@gmail.com>
+//
+
+package uv3dp
+
+import (
+	"testing"
+
+	"image"
+)
+
+func TestDimPattern(t *testing.T) {
+	dp := NewCheckerboardPattern(0x40)
+
+	table := []struct {
+		x, y  int
+		level uint8
+	}{
+		{0, 0, 0xff},
+		{1, 0, 0x40},
+		{0, 1, 0x40},
+		{3, 5, 0xff},
+		{-1, 0, 0x40},
+		{-1, -1, 0xff},
+	}
+
+	for _, item := range table {
+		level := dp.Level(item.x, item.y)
+		if level != item.level {
+			t.Errorf("(%v,%v): expected %#x, got %#x", item.x, item.y, item.level, level)
+		}
+	}
+
+	if dp.Dim(0, 0, 0xff) != 0xff || dp.Dim(1, 0, 0xff) != 0x40 || dp.Dim(1, 0, 0x80) != 0x20 {
+		t.Errorf("unexpected dimming %#x %#x %#x", dp.Dim(0, 0, 0xff), dp.Dim(1, 0, 0xff), dp.Dim(1, 0, 0x80))
+	}
+
+	solid := NewSolidPattern(0x80)
+	if solid.Dim(7, 3, 0xff) != 0x80 {
+		t.Errorf("solid: unexpected dimming %#x", solid.Dim(7, 3, 0xff))
+	}
+}
+
+func TestDimPatterns(t *testing.T) {
+	chess := NewChessboardPattern(0x10, 3)
+	for _, item := range []struct {
+		x, y  int
+		level uint8
+	}{
+		{0, 0, 0xff}, {2, 2, 0xff}, {3, 0, 0x10}, {0, 5, 0x10}, {4, 4, 0xff}, {6, 1, 0xff},
+	} {
+		if chess.Level(item.x, item.y) != item.level {
+			t.Errorf("chessboard (%v,%v): expected %#x, got %#x", item.x, item.y, item.level, chess.Level(item.x, item.y))
+		}
+	}
+
+	ig := image.NewGray(image.Rect(5, 5, 7, 6))
+	copy(ig.Pix, []uint8{0x00, 0xff})
+
+	tile := NewTilePattern(ig, 0x40)
+	if tile.Level(0, 0) != 0x40 || tile.Level(1, 0) != 0xff || tile.Level(2, 7) != 0x40 {
+		t.Errorf("tile: unexpected levels %#x %#x %#x", tile.Level(0, 0), tile.Level(1, 0), tile.Level(2, 7))
+	}
+}
+
+func TestDim(t *testing.T) {
+	in, size := compensateBlock()
+
+	// 2 pixel walls
+	d := &Dim{Pattern: NewSolidPattern(0x80), Wall: 0.1, Threshold: 127}
+	out := d.Apply(in, size)
+
+	for x, expected := range []uint8{0, 0, 0, 0, 0, 0xff, 0xff, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0xff, 0xff, 0} {
+		if out.GrayAt(x, 10).Y != expected {
+			t.Errorf("(%v,10): expected %#x, got %#x", x, expected, out.GrayAt(x, 10).Y)
+		}
+	}
+}
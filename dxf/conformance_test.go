@@ -0,0 +1,21 @@
+//
+// Copyright (c) 2020 Jason S. McMullan <jason.mcmullan@gmail.com>
+//
+
+package dxf
+
+import (
+	"testing"
+
+	"github.com/ezrec/uv3dp"
+	"github.com/ezrec/uv3dp/uv3dptest"
+)
+
+func TestConformance(t *testing.T) {
+	newFormatter := func(suffix string) uv3dp.Formatter { return NewFormatter(suffix) }
+
+	uv3dptest.RunFormatterConformance(t, newFormatter, uv3dptest.Capabilities{
+		Suffix:     ".dxf",
+		EncodeOnly: true,
+	})
+}
@@ -0,0 +1,132 @@
+//
+// Copyright (c) 2020 Jason S. McMullan <jason.mcmullan@gmail.com>
+//
+
+package dxf
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ezrec/uv3dp"
+	"github.com/spf13/pflag"
+)
+
+const (
+	defaultIsoLevel = 127.5
+)
+
+type Format struct {
+	*pflag.FlagSet
+
+	IsoLevel float32
+}
+
+func NewFormatter(suffix string) (sf *Format) {
+	flagSet := pflag.NewFlagSet(suffix, pflag.ContinueOnError)
+
+	sf = &Format{
+		FlagSet: flagSet,
+	}
+
+	sf.Float32VarP(&sf.IsoLevel, "iso-level", "i", defaultIsoLevel, "Gray level of the layer edges")
+	sf.SetInterspersed(false)
+
+	return
+}
+
+// Write a DXF group code and value
+func group(out *strings.Builder, code int, value interface{}) {
+	switch v := value.(type) {
+	case float32:
+		fmt.Fprintf(out, "%3d\n%.4f\n", code, v)
+	default:
+		fmt.Fprintf(out, "%3d\n%v\n", code, v)
+	}
+}
+
+// Closed 2D polyline, at the layer Z
+func polyline(out *strings.Builder, layer string, z float32, height float32, contour uv3dp.Contour) {
+	group(out, 0, "POLYLINE")
+	group(out, 8, layer)
+	group(out, 66, 1)
+	group(out, 10, float32(0))
+	group(out, 20, float32(0))
+	group(out, 30, z)
+	group(out, 70, 1) // Closed
+
+	for _, p := range contour {
+		group(out, 0, "VERTEX")
+		group(out, 8, layer)
+		group(out, 10, p.X)
+		group(out, 20, height-p.Y) // DXF has Y up
+		group(out, 30, z)
+	}
+
+	group(out, 0, "SEQEND")
+	group(out, 8, layer)
+}
+
+// Encode writes the outlines of each layer as closed polylines, on a
+// DXF layer per print layer, at the Z of the print layer
+func (sf *Format) Encode(writer uv3dp.Writer, printable uv3dp.Printable) (err error) {
+	size := printable.Size()
+
+	layerChan := make([](chan string), size.Layers)
+	for n := range layerChan {
+		layerChan[n] = make(chan string, 1)
+	}
+
+	go uv3dp.WithAllLayers(printable, func(p uv3dp.Printable, n int) {
+		out := &strings.Builder{}
+		layer := fmt.Sprintf("LAYER%05d", n)
+		z := p.LayerZ(n)
+		for _, polygon := range uv3dp.LayerContours(p, n, sf.IsoLevel) {
+			polyline(out, layer, z, size.Millimeter.Y, polygon.Outline)
+			for _, hole := range polygon.Holes {
+				polyline(out, layer, z, size.Millimeter.Y, hole)
+			}
+		}
+
+		layerChan[n] <- out.String()
+		close(layerChan[n])
+	})
+
+	header := &strings.Builder{}
+	group(header, 0, "SECTION")
+	group(header, 2, "HEADER")
+	group(header, 9, "$ACADVER")
+	group(header, 1, "AC1009")
+	group(header, 9, "$INSUNITS")
+	group(header, 70, 4) // Millimeters
+	group(header, 0, "ENDSEC")
+	group(header, 0, "SECTION")
+	group(header, 2, "ENTITIES")
+
+	_, err = writer.Write([]byte(header.String()))
+	for _, done := range layerChan {
+		entities := <-done
+		if err != nil {
+			continue
+		}
+		_, err = writer.Write([]byte(entities))
+	}
+
+	if err != nil {
+		return
+	}
+
+	trailer := &strings.Builder{}
+	group(trailer, 0, "ENDSEC")
+	group(trailer, 0, "EOF")
+
+	_, err = writer.Write([]byte(trailer.String()))
+
+	return
+}
+
+func (sf *Format) Decode(reader uv3dp.Reader, filesize int64) (printable uv3dp.Printable, err error) {
+	err = errors.New("DXF input is not supported")
+	return
+}
@@ -0,0 +1,108 @@
+//
+// Copyright (c) 2020 Jason S. McMullan <jason.mcmullan@gmail.com>
+//
+
+package dxf
+
+import (
+	"bytes"
+	"image"
+	"strconv"
+	"strings"
+
+	"testing"
+
+	"github.com/ezrec/uv3dp"
+)
+
+type blockPrint struct {
+	uv3dp.Print
+}
+
+// 2x2 pixel block at the top left, with a one pixel border
+func (bp *blockPrint) LayerImage(index int) (gray *image.Gray) {
+	gray = image.NewGray(bp.Bounds())
+	for y := 1; y < 3; y++ {
+		for x := 1; x < 3; x++ {
+			gray.Pix[y*gray.Stride+x] = 0xff
+		}
+	}
+
+	return
+}
+
+func TestEncodeDXF(t *testing.T) {
+	prop := uv3dp.Properties{
+		Size: uv3dp.Size{
+			X: 8, Y: 8,
+			Millimeter:  uv3dp.SizeMillimeter{X: 4.0, Y: 4.0},
+			Layers:      2,
+			LayerHeight: 0.05,
+		},
+	}
+
+	buff := &bytes.Buffer{}
+	err := NewFormatter(".dxf").Encode(buff, &blockPrint{Print: uv3dp.Print{Properties: prop}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(buff.String(), "\n"), "\n")
+	if len(lines)%2 != 0 {
+		t.Fatalf("expected code/value pairs, got %v lines", len(lines))
+	}
+
+	count := map[string]int{}
+	layers := map[string]bool{}
+	var entity string
+	var x, y, z []float64
+	for n := 0; n < len(lines); n += 2 {
+		code, err := strconv.Atoi(strings.TrimSpace(lines[n]))
+		if err != nil {
+			t.Fatalf("line %v: %v", n+1, err)
+		}
+		value := lines[n+1]
+
+		switch {
+		case code == 0:
+			entity = value
+			count[value]++
+		case code == 8:
+			layers[value] = true
+		case entity == "VERTEX":
+			number, _ := strconv.ParseFloat(value, 64)
+			switch code {
+			case 10:
+				x = append(x, number)
+			case 20:
+				y = append(y, number)
+			case 30:
+				z = append(z, number)
+			}
+		}
+	}
+
+	if lines[len(lines)-1] != "EOF" {
+		t.Errorf("expected EOF, got %v", lines[len(lines)-1])
+	}
+
+	// One chamfered square on each layer
+	if count["POLYLINE"] != 2 || count["VERTEX"] != 16 || count["SEQEND"] != 2 {
+		t.Errorf("unexpected entities %v", count)
+	}
+
+	if !layers["LAYER00000"] || !layers["LAYER00001"] || len(layers) != 2 {
+		t.Errorf("unexpected layers %v", layers)
+	}
+
+	// 0.5mm pixels; block is from 0.5mm to 1.5mm, measured from the top
+	for n := range x {
+		if x[n] < 0.5 || x[n] > 1.5 || y[n] < 2.5 || y[n] > 3.5 {
+			t.Errorf("vertex %v: (%v, %v) is outside of the block", n, x[n], y[n])
+		}
+	}
+
+	if z[0] != 0.05 || z[len(z)-1] != 0.1 {
+		t.Errorf("unexpected layer Z %v .. %v", z[0], z[len(z)-1])
+	}
+}
@@ -0,0 +1,16 @@
+//
+// Copyright (c) 2020 Jason S. McMullan <jason.mcmullan@gmail.com>
+//
+
+// Package dxf writes the layer outlines of printables as DXF drawings
+package dxf
+
+import (
+	"github.com/ezrec/uv3dp"
+)
+
+func init() {
+	newFormatter := func(suffix string) uv3dp.Formatter { return NewFormatter(suffix) }
+
+	uv3dp.RegisterFormatter(".dxf", newFormatter)
+}
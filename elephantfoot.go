@@ -0,0 +1,89 @@
+//
+// Copyright (c) 2020 Jason S. McMullan <jason.mcmullan@gmail.com>
+//
+
+package uv3dp
+
+import (
+	"image"
+)
+
+// ElephantFoot insets the outline of the bottom layers, which flare out
+// from over-exposure, tapering the inset away over the layers above them
+type ElephantFoot struct {
+	Distance  float32     // Inset of the bottom layers, in mm
+	Taper     int         // Layers above the bottom layers, over which the inset tapers away
+	Pattern   *DimPattern // If not nil, the inset is dimmed by the pattern, instead of removed
+	Threshold uint8       // Pixels brighter than this are on
+}
+
+// LayerDistance returns the inset of a layer of a printable, in mm
+func (ef *ElephantFoot) LayerDistance(p Printable, index int) (distance float32) {
+	count := p.Bottom().Count
+
+	switch {
+	case index < count:
+		distance = ef.Distance
+	case index-count < ef.Taper:
+		step := index - count + 1
+		distance = ef.Distance * float32(ef.Taper+1-step) / float32(ef.Taper+1)
+	}
+
+	return
+}
+
+// Apply returns a new image, inset by a distance in mm
+func (ef *ElephantFoot) Apply(in *image.Gray, size Size, distance float32) (out *image.Gray) {
+	compensate := Compensate{X: -distance, Y: -distance, Threshold: ef.Threshold}
+	out = compensate.Apply(in, size)
+
+	if ef.Pattern == nil {
+		return
+	}
+
+	// Dim the inset, instead of removing it
+	bounds := in.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			n := out.PixOffset(x, y)
+			pix := in.Pix[in.PixOffset(x, y)]
+			if pix <= ef.Threshold {
+				continue
+			}
+
+			dimmed := ef.Pattern.Dim(x, y, pix)
+			if dimmed > out.Pix[n] {
+				out.Pix[n] = dimmed
+			}
+		}
+	}
+
+	return
+}
+
+// ElephantFootPrintable insets the outline of the bottom layers
+type ElephantFootPrintable struct {
+	Printable
+	ElephantFoot ElephantFoot
+}
+
+// NewElephantFootPrintable insets the outline of the bottom layers
+func NewElephantFootPrintable(printable Printable, ef ElephantFoot) (efp *ElephantFootPrintable) {
+	efp = &ElephantFootPrintable{
+		Printable:    printable,
+		ElephantFoot: ef,
+	}
+
+	return
+}
+
+func (efp *ElephantFootPrintable) LayerImage(index int) (ig *image.Gray) {
+	ig = efp.Printable.LayerImage(index)
+
+	distance := efp.ElephantFoot.LayerDistance(efp.Printable, index)
+	if distance > 0 {
+		ig = efp.ElephantFoot.Apply(ig, efp.Printable.Size(), distance)
+	}
+
+	return
+}
@@ -0,0 +1,48 @@
+//
+// Copyright (c) 2020 Jason S. McMullan <jason.mcmullan@gmail.com>
+//
+
+package uv3dp
+
+import (
+	"testing"
+)
+
+func TestElephantFootDistance(t *testing.T) {
+	p := NewEmptyPrintable(Properties{
+		Size:   Size{Layers: 8},
+		Bottom: Bottom{Count: 2},
+	})
+
+	ef := &ElephantFoot{Distance: 0.4, Taper: 3}
+	expected := []float32{0.4, 0.4, 0.3, 0.2, 0.1, 0, 0, 0}
+
+	for n, distance := range expected {
+		got := ef.LayerDistance(p, n)
+		if got < distance-0.0001 || got > distance+0.0001 {
+			t.Errorf("layer %v: expected %v, got %v", n, distance, got)
+		}
+	}
+}
+
+func TestElephantFootApply(t *testing.T) {
+	in, size := compensateBlock()
+
+	// Inset by 2 pixels
+	ef := &ElephantFoot{Threshold: 127}
+	out := ef.Apply(in, size, 0.1)
+	for x, expected := range []uint8{0, 0, 0, 0, 0, 0, 0, 0xff} {
+		if out.GrayAt(x, 10).Y != expected {
+			t.Errorf("erode: (%v,10) expected %#x, got %#x", x, expected, out.GrayAt(x, 10).Y)
+		}
+	}
+
+	// Dimmed, instead of removed
+	ef.Pattern = NewCheckerboardPattern(0x00)
+	out = ef.Apply(in, size, 0.1)
+	for x, expected := range []uint8{0, 0, 0, 0, 0, 0, 0xff, 0xff} {
+		if out.GrayAt(x, 10).Y != expected {
+			t.Errorf("dim: (%v,10) expected %#x, got %#x", x, expected, out.GrayAt(x, 10).Y)
+		}
+	}
+}
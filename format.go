@@ -35,6 +35,13 @@ type Formatter interface {
 	Encode(writer Writer, printable Printable) (err error)
 }
 
+// FileFormatter is optionally implemented by a Formatter that needs
+// direct access to the filesystem (ie directories, or sidecar files)
+type FileFormatter interface {
+	DecodeFile(filename string) (printable Printable, err error)
+	EncodeFile(filename string, printable Printable) (err error)
+}
+
 // Printable to file format
 type NewFormatter func(suffix string) (formatter Formatter)
 
@@ -48,21 +55,23 @@ func RegisterFormatter(suffix string, newFormatter NewFormatter) {
 	formatterMap[suffix] = newFormatter
 }
 
+// FormatterSuffixes returns the registered suffixes, in order
+func FormatterSuffixes() (list []string) {
+	for suffix := range formatterMap {
+		list = append(list, suffix)
+	}
+	sort.Strings(list)
+
+	return
+}
+
 func FormatterUsage() {
-	if formatterMap != nil {
-		list := []string{}
-		for suffix := range formatterMap {
-			list = append(list, suffix)
-		}
-		sort.Strings(list)
-
-		for _, suffix := range list {
-			newFormatter := formatterMap[suffix]
-			fmt.Fprintln(os.Stderr)
-			fmt.Fprintf(os.Stderr, "Options for '%s':\n", suffix)
-			fmt.Fprintln(os.Stderr)
-			newFormatter(suffix).PrintDefaults()
-		}
+	for _, suffix := range FormatterSuffixes() {
+		newFormatter := formatterMap[suffix]
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintf(os.Stderr, "Options for '%s':\n", suffix)
+		fmt.Fprintln(os.Stderr)
+		newFormatter(suffix).PrintDefaults()
 	}
 }
 
@@ -108,6 +117,12 @@ func (format *Format) Printable() (printable Printable, err error) {
 	var reader *os.File
 	var filesize int64
 
+	fileFormatter, ok := format.Formatter.(FileFormatter)
+	if ok {
+		printable, err = fileFormatter.DecodeFile(format.Filename)
+		return
+	}
+
 	if format.Suffix != "empty" {
 		reader, err = os.Open(format.Filename)
 		if err != nil {
@@ -135,8 +150,16 @@ func (format *Format) Printable() (printable Printable, err error) {
 	return
 }
 
-// Write writes a printable to the file format
+// Write writes a printable to the file format, rendering any missing previews
 func (format *Format) SetPrintable(printable Printable) (err error) {
+	printable = NewPreviewPrintable(printable, format.Formatter)
+
+	fileFormatter, ok := format.Formatter.(FileFormatter)
+	if ok {
+		err = fileFormatter.EncodeFile(format.Filename, printable)
+		return
+	}
+
 	writer, err := os.Create(format.Filename)
 	if err != nil {
 		return
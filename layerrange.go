@@ -0,0 +1,169 @@
+//
+// Copyright (c) 2020 Jason S. McMullan <jason.mcmullan@gmail.com>
+//
+
+package uv3dp
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// layerSpan is an inclusive span of layers, by index or by Z height
+type layerSpan struct {
+	name  string  // 'bottom' or 'normal', if a named span
+	byZ   bool    // First and Last are Z heights in mm
+	first float64 // First layer index (or Z)
+	last  float64 // Last layer index (or Z), or -1 for no last layer
+}
+
+// layerRangeZTolerance allows for rounding in layer Z heights
+const layerRangeZTolerance = 0.0005
+
+// LayerRange selects layers of a printable, by index or by Z height.
+//
+// A layer range is a comma separated list of:
+//
+//	N        Layer index N
+//	N-M      Layer indexes N through M
+//	N-       Layer index N, and all layers after it
+//	-M       All layers, through layer index M
+//	Zmm      The first layer at or above Z (in millimeters)
+//	Zmm-Zmm  Layers with a Z (in millimeters) from the first to the last
+//	bottom   The bottom layers
+//	normal   All layers after the bottom layers
+//	all      All layers
+//
+// Z spans may also be open ended, as for layer indexes. The empty layer
+// range selects all layers.
+type LayerRange struct {
+	text  string
+	spans []layerSpan
+}
+
+func parseLayerBound(text string) (value float64, byZ bool, err error) {
+	if strings.HasSuffix(text, "mm") {
+		byZ = true
+		value, err = strconv.ParseFloat(strings.TrimSuffix(text, "mm"), 32)
+	} else {
+		var index int
+		index, err = strconv.Atoi(text)
+		value = float64(index)
+	}
+
+	if err == nil && value < 0 {
+		err = fmt.Errorf("negative")
+	}
+
+	return
+}
+
+// ParseLayerRange parses a layer range
+func ParseLayerRange(text string) (lr LayerRange, err error) {
+	lr.text = text
+
+	text = strings.TrimSpace(text)
+	if len(text) == 0 {
+		return
+	}
+
+	for _, item := range strings.Split(text, ",") {
+		item = strings.TrimSpace(item)
+
+		switch item {
+		case "all":
+			lr.spans = append(lr.spans, layerSpan{first: 0, last: -1})
+			continue
+		case "bottom", "normal":
+			lr.spans = append(lr.spans, layerSpan{name: item})
+			continue
+		}
+
+		span := layerSpan{last: -1}
+
+		firstText, lastText, isSpan := strings.Cut(item, "-")
+
+		var firstZ, lastZ bool
+		if len(firstText) > 0 {
+			span.first, firstZ, err = parseLayerBound(firstText)
+		}
+		if err == nil && len(lastText) > 0 {
+			span.last, lastZ, err = parseLayerBound(lastText)
+		}
+		if err == nil && len(firstText) == 0 && len(lastText) == 0 {
+			err = fmt.Errorf("empty")
+		}
+		if err == nil && len(firstText) > 0 && len(lastText) > 0 && firstZ != lastZ {
+			err = fmt.Errorf("mixed layer indexes and Z heights")
+		}
+		if err != nil {
+			err = fmt.Errorf("layer range '%v': %w", item, err)
+			return
+		}
+
+		span.byZ = firstZ || lastZ
+
+		if !isSpan {
+			span.last = span.first
+		}
+
+		if span.last >= 0 && span.last < span.first {
+			err = fmt.Errorf("layer range '%v': last is before first", item)
+			return
+		}
+
+		lr.spans = append(lr.spans, span)
+	}
+
+	return
+}
+
+// String returns the text of the layer range
+func (lr LayerRange) String() string {
+	return lr.text
+}
+
+// IsAll is true if the layer range is empty, and selects all layers
+func (lr LayerRange) IsAll() bool {
+	return len(lr.spans) == 0
+}
+
+// Contains returns true if a layer of a printable is in the layer range
+func (lr LayerRange) Contains(p Printable, index int) bool {
+	if lr.IsAll() {
+		return true
+	}
+
+	for _, span := range lr.spans {
+		switch {
+		case span.name == "bottom":
+			if index < p.Bottom().Count {
+				return true
+			}
+		case span.name == "normal":
+			if index >= p.Bottom().Count {
+				return true
+			}
+		case span.byZ:
+			first := span.first - layerRangeZTolerance
+			last := span.last + layerRangeZTolerance
+			z := float64(p.LayerZ(index))
+			if span.last == span.first {
+				// The first layer at or above Z
+				if z >= first && (index == 0 || float64(p.LayerZ(index-1)) < first) {
+					return true
+				}
+			} else if z >= first && (span.last < 0 || z <= last) {
+				return true
+			}
+		default:
+			n := float64(index)
+			if n >= span.first && (span.last < 0 || n <= span.last) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,65 @@
+//
+// Copyright (c) 2020 Jason S. McMullan <jason.mcmullan@gmail.com>
+//
+
+package uv3dp
+
+import (
+	"testing"
+)
+
+func TestLayerRange(t *testing.T) {
+	prop := Properties{
+		Size:   Size{Layers: 10, LayerHeight: 0.05},
+		Bottom: Bottom{Count: 3},
+	}
+	p := NewEmptyPrintable(prop)
+
+	table := []struct {
+		text   string
+		layers string // 'X' for each selected layer
+	}{
+		{"", "XXXXXXXXXX"},
+		{"all", "XXXXXXXXXX"},
+		{"bottom", "XXX......."},
+		{"normal", "...XXXXXXX"},
+		{"4", "....X....."},
+		{"2-4", "..XXX....."},
+		{"7-", ".......XXX"},
+		{"-1", "XX........"},
+		{"0,5-6,9", "X....XX..X"},
+		{"0.15mm", "..X......."},
+		{"0.12mm", "..X......."},
+		{"0.1mm-0.2mm", ".XXX......"},
+		{"0.4mm-", ".......XXX"},
+		{"bottom,9", "XXX......X"},
+	}
+
+	for _, item := range table {
+		lr, err := ParseLayerRange(item.text)
+		if err != nil {
+			t.Errorf("%q: %v", item.text, err)
+			continue
+		}
+
+		got := ""
+		for n := 0; n < prop.Size.Layers; n++ {
+			if lr.Contains(p, n) {
+				got += "X"
+			} else {
+				got += "."
+			}
+		}
+
+		if got != item.layers {
+			t.Errorf("%q: expected %v, got %v", item.text, item.layers, got)
+		}
+	}
+
+	for _, text := range []string{"-", "x", "3-1", "1-2mm", "-1.5", "1,,2"} {
+		_, err := ParseLayerRange(text)
+		if err == nil {
+			t.Errorf("%q: expected an error", text)
+		}
+	}
+}
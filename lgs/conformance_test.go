@@ -0,0 +1,38 @@
+//
+// Copyright (c) 2020 Jason S. McMullan <jason.mcmullan@gmail.com>
+//
+
+package lgs
+
+import (
+	"testing"
+
+	"github.com/ezrec/uv3dp"
+	"github.com/ezrec/uv3dp/uv3dptest"
+)
+
+func TestConformance(t *testing.T) {
+	// Retract height is not saved
+	fixup := func(kind uv3dptest.ExposureKind, exposure *uv3dp.Exposure) {
+		exposure.RetractHeight = 0
+	}
+
+	for suffix, model := range map[string]int{
+		".lgs":    ModelOrange10,
+		".lgs30":  ModelOrange30,
+		".lgs120": ModelOrange120,
+		".lgs4k":  ModelOrange4K,
+	} {
+		model := model
+		newFormatter := func(suffix string) uv3dp.Formatter { return NewFormatter(suffix, model) }
+
+		t.Run(suffix, func(t *testing.T) {
+			uv3dptest.RunFormatterConformance(t, newFormatter, uv3dptest.Capabilities{
+				Suffix:     suffix,
+				GrayLevels: 16, // 4-bit gray
+				Previews:   []uv3dp.PreviewType{uv3dp.PreviewTypeTiny},
+				Fixup:      fixup,
+			})
+		})
+	}
+}
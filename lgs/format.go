@@ -2,7 +2,7 @@
 // Copyright (c) 2020 Jason S. McMullan <jason.mcmullan@gmail.com>
 //
 
-// Package lgs handles input and output of Longer Orange 10 print files
+// Package lgs handles input and output of Longer Orange series print files
 package lgs
 
 import (
@@ -21,13 +21,22 @@ var (
 	headerMagic = []byte{76, 111, 110, 103, 101, 114, 51, 68}
 )
 
+// lgsHeader is the header of all of the Longer models, including the
+// Orange 120 and 4K.
+//
+// The fields named by their offset (Uint_08, Uint_0c, Uint_14, Float_38,
+// Float_48, Float_64 to Float_90, Uint_98 to Uint_a0, and Uint_a8) are
+// still unknown. LongerWare writes the same value to each of them, for
+// every model and for every print setting, so none can be matched to a
+// setting by changing it. They are written with those values, shown in
+// their comments, and are ignored when decoding.
 type lgsHeader struct {
 	Name                  [8]uint8 // 0x00:
-	Uint_08               uint32   // 0x08: 0xff000001 ?
-	Uint_0c               uint32   // 0x0c: 1 ?
-	Uint_10               uint32   // 0x10: 30 ?
-	Uint_14               uint32   // 0x14: 0 ?
-	Uint_18               uint32   // 0x18: 34 ?
+	Uint_08               uint32   // 0x08: 0xff000001
+	Uint_0c               uint32   // 0x0c: 1
+	PrinterModel          uint32   // 0x10: One of the Model* constants
+	Uint_14               uint32   // 0x14: 0
+	MagicKey              uint32   // 0x18: 34
 	PixelPerMmY           float32  // 0x1c:
 	PixelPerMmX           float32  // 0x20:
 	ImageY                float32  // 0x24
@@ -43,31 +52,57 @@ type lgsHeader struct {
 	BottomLiftHeight      float32  // 0x4c
 	LiftHeight            float32  // 0x50
 	LiftSpeed             float32  // 0x54
-	LiftSpeed_            float32  // 0x58
+	RetractSpeed          float32  // 0x58
 	BottomLiftSpeed       float32  // 0x5c
-	BottomLiftSpeed_      float32  // 0x60
-	Float_64              float32  // 0x64: 5?
-	Float_68              float32  // 0x68: 60?
-	Float_6c              float32  // 0x6c: 10?
-	Float_70              float32  // 0x70: 600?
-	Float_74              float32  // 0x74: 600?
-	Float_78              float32  // 0x78: 2?
-	Float_7c              float32  // 0x7c: 0.2?
-	Float_80              float32  // 0x80: 60?
-	Float_84              float32  // 0x84: 1?
-	Float_88              float32  // 0x88: 6?
-	Float_8c              float32  // 0x8c: 150 ?
-	Float_90              float32  // 0x90: 1001 ?
-	Float_94              float32  // 0x94: 140 for Longer 10, 170 for Longer 30?
-	Uint_98               uint32   // 0x98: 0 ?
-	Uint_9c               uint32   // 0x9c: 0 ?
-	Uint_a0               uint32   // 0xa0: 0 ?
+	BottomRetractSpeed    float32  // 0x60
+	Float_64              float32  // 0x64: 5
+	Float_68              float32  // 0x68: 60
+	Float_6c              float32  // 0x6c: 10
+	Float_70              float32  // 0x70: 600
+	Float_74              float32  // 0x74: 600
+	Float_78              float32  // 0x78: 2
+	Float_7c              float32  // 0x7c: 0.2
+	Float_80              float32  // 0x80: 60
+	Float_84              float32  // 0x84: 1
+	Float_88              float32  // 0x88: 6
+	Float_8c              float32  // 0x8c: 150
+	Float_90              float32  // 0x90: 1001
+	MachineZ              float32  // 0x94: Z axis height, in mm
+	Uint_98               uint32   // 0x98: 0
+	Uint_9c               uint32   // 0x9c: 0
+	Uint_a0               uint32   // 0xa0: 0
 	LayerCount            uint32   // 0xa4
-	Uint_a8               uint32   // 0xa8: 4 ?
+	Uint_a8               uint32   // 0xa8: 4
 	PreviewSizeX          uint32   // 0xac
 	PreviewSizeY          uint32   // 0xb0
 }
 
+// Longer printer models, as stored in the header
+const (
+	ModelOrange4K  = 4
+	ModelOrange10  = 10
+	ModelOrange30  = 30
+	ModelOrange120 = 120
+)
+
+var (
+	// Z axis heights, by model
+	machineZ = map[int]float32{
+		ModelOrange4K:  190,
+		ModelOrange10:  140,
+		ModelOrange30:  170,
+		ModelOrange120: 170,
+	}
+
+	// File suffixes, by model
+	modelSuffix = map[int]string{
+		ModelOrange4K:  ".lgs4k",
+		ModelOrange10:  ".lgs",
+		ModelOrange30:  ".lgs30",
+		ModelOrange120: ".lgs120",
+	}
+)
+
 type lgsImage struct {
 	Size uint32 `struct:"sizeof=Rle"`
 	Rle  []byte `struct:"sizefrom=Size"`
@@ -103,9 +138,21 @@ func (f *Formatter) Encode(writer uv3dp.Writer, p uv3dp.Printable) (err error) {
 	exp := p.Exposure()
 	bot := p.Bottom()
 
-	fModel := float32(140)
-	if f.model == 30 {
-		fModel = 170
+	fModel, ok := machineZ[f.model]
+	if !ok {
+		err = fmt.Errorf("unknown Longer printer model %v", f.model)
+		return
+	}
+
+	// Retract at the lift speed, if no retract speed is set
+	retractSpeed := exp.RetractSpeed
+	if retractSpeed == 0 {
+		retractSpeed = exp.LiftSpeed
+	}
+
+	bottomRetractSpeed := bot.Exposure.RetractSpeed
+	if bottomRetractSpeed == 0 {
+		bottomRetractSpeed = bot.Exposure.LiftSpeed
 	}
 
 	preview, ok := p.Preview(uv3dp.PreviewTypeTiny)
@@ -117,9 +164,9 @@ func (f *Formatter) Encode(writer uv3dp.Writer, p uv3dp.Printable) (err error) {
 	header := lgsHeader{
 		Uint_08:               0xff000001,
 		Uint_0c:               1,
-		Uint_10:               uint32(f.model),
+		PrinterModel:          uint32(f.model),
 		Uint_14:               0,
-		Uint_18:               34,
+		MagicKey:              34,
 		PixelPerMmY:           float32(size.Y) / float32(size.Millimeter.Y),
 		PixelPerMmX:           float32(size.X) / float32(size.Millimeter.X),
 		ImageY:                float32(size.Y),
@@ -135,9 +182,9 @@ func (f *Formatter) Encode(writer uv3dp.Writer, p uv3dp.Printable) (err error) {
 		BottomLiftHeight:      bot.Exposure.LiftHeight,
 		LiftHeight:            exp.LiftHeight,
 		LiftSpeed:             exp.LiftSpeed,
-		LiftSpeed_:            exp.LiftSpeed,
+		RetractSpeed:          retractSpeed,
 		BottomLiftSpeed:       bot.Exposure.LiftSpeed,
-		BottomLiftSpeed_:      bot.Exposure.LiftSpeed,
+		BottomRetractSpeed:    bottomRetractSpeed,
 		Float_64:              5,
 		Float_68:              60,
 		Float_6c:              10,
@@ -150,7 +197,7 @@ func (f *Formatter) Encode(writer uv3dp.Writer, p uv3dp.Printable) (err error) {
 		Float_88:              6,
 		Float_8c:              150,
 		Float_90:              1001,
-		Float_94:              fModel,
+		MachineZ:              fModel,
 		LayerCount:            uint32(size.Layers),
 		Uint_a8:               4,
 		PreviewSizeX:          uint32(previewSize.X),
@@ -185,7 +232,8 @@ func (f *Formatter) Encode(writer uv3dp.Writer, p uv3dp.Printable) (err error) {
 
 	uv3dp.WithAllLayers(p, func(p uv3dp.Printable, n int) {
 		var rle []byte
-		rle, err = Rle4Encode(p.LayerImage(n))
+		layer := uv3dp.QuantizeLayer(p.LayerImage(n), 16) // 4-bit gray
+		rle, err = Rle4Encode(layer)
 		if err == nil {
 			layerChan[n] <- rle
 		}
@@ -228,6 +276,17 @@ func (cf *Formatter) Decode(file uv3dp.Reader, filesize int64) (printable uv3dp.
 		return
 	}
 
+	model := int(header.PrinterModel)
+	if model != cf.model {
+		suffix, known := modelSuffix[model]
+		if !known {
+			err = fmt.Errorf("unknown Longer printer model %v", model)
+		} else {
+			err = fmt.Errorf("file is for Longer printer model %v (%v), not model %v", model, suffix, cf.model)
+		}
+		return
+	}
+
 	size := uv3dp.Size{}
 	size.Layers = int(header.LayerCount)
 	size.LayerHeight = header.LayerHeight
@@ -239,6 +298,7 @@ func (cf *Formatter) Decode(file uv3dp.Reader, filesize int64) (printable uv3dp.
 	exp := uv3dp.Exposure{}
 	exp.LiftHeight = header.LiftHeight
 	exp.LiftSpeed = header.LiftSpeed
+	exp.RetractSpeed = header.RetractSpeed
 	exp.LightOnTime = header.ExposureTimeMs / 1000.0
 	exp.LightOffTime = header.LightOffDelayMs / 1000.0
 	exp.LightPWM = 255
@@ -247,6 +307,7 @@ func (cf *Formatter) Decode(file uv3dp.Reader, filesize int64) (printable uv3dp.
 	bot.Count = int(header.BottomHeight / header.LayerHeight)
 	bot.Exposure.LiftHeight = header.BottomLiftHeight
 	bot.Exposure.LiftSpeed = header.BottomLiftSpeed
+	bot.Exposure.RetractSpeed = header.BottomRetractSpeed
 	bot.Exposure.LightOnTime = header.BottomExposureTimeMs / 1000.0
 	bot.Exposure.LightOffTime = header.BottomLightOffDelayMs / 1000.0
 	bot.Exposure.LightPWM = 255
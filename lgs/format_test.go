@@ -0,0 +1,159 @@
+//
+// Copyright (c) 2020 Jason S. McMullan <jason.mcmullan@gmail.com>
+//
+
+package lgs
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"io"
+
+	"testing"
+
+	"github.com/ezrec/uv3dp"
+	"github.com/go-restruct/restruct"
+	"github.com/google/go-cmp/cmp"
+)
+
+type bufferMap struct {
+	Buffer []byte
+	Offset int64
+}
+
+func (bm *bufferMap) ReadAt(buff []byte, off int64) (size int, err error) {
+	size = copy(buff, bm.Buffer[off:])
+	if len(buff) > 0 && size == 0 {
+		err = io.EOF
+	}
+	return
+}
+
+func (bm *bufferMap) Read(buff []byte) (size int, err error) {
+	size, err = bm.ReadAt(buff, bm.Offset)
+	if err != nil {
+		return
+	}
+	bm.Offset += int64(size)
+	return
+}
+
+var (
+	testProperties = uv3dp.Properties{
+		Size: uv3dp.Size{
+			X: 10,
+			Y: 20,
+			Millimeter: uv3dp.SizeMillimeter{
+				X: 20.0,
+				Y: 40.0,
+			},
+			Layers:      4,
+			LayerHeight: 0.05,
+		},
+		Exposure: uv3dp.Exposure{
+			LightOnTime:  16.5,
+			LightOffTime: 2.25,
+			LightPWM:     255,
+			LiftHeight:   5.5,
+			LiftSpeed:    120.0,
+			RetractSpeed: 150.0,
+		},
+		Bottom: uv3dp.Bottom{
+			Count: 2,
+			Exposure: uv3dp.Exposure{
+				LightOnTime:  30.0,
+				LightOffTime: 2.25,
+				LightPWM:     255,
+				LiftHeight:   6.5,
+				LiftSpeed:    60.0,
+				RetractSpeed: 90.0,
+			},
+		},
+		Preview: map[uv3dp.PreviewType]image.Image{
+			uv3dp.PreviewTypeTiny: image.NewNRGBA(image.Rect(0, 0, 4, 3)),
+		},
+	}
+)
+
+func TestEncodeDecode(t *testing.T) {
+	table := []struct {
+		Suffix   string
+		Model    int
+		MachineZ float32
+	}{
+		{".lgs", ModelOrange10, 140},
+		{".lgs30", ModelOrange30, 170},
+		{".lgs120", ModelOrange120, 170},
+		{".lgs4k", ModelOrange4K, 190},
+	}
+
+	empty := uv3dp.NewEmptyPrintable(testProperties)
+
+	for _, item := range table {
+		formatter := NewFormatter(item.Suffix, item.Model)
+
+		buffWriter := &bytes.Buffer{}
+		err := formatter.Encode(buffWriter, empty)
+		if err != nil {
+			t.Fatalf("%v: %v", item.Suffix, err)
+		}
+
+		data := buffWriter.Bytes()
+
+		header := lgsHeader{}
+		err = restruct.Unpack(data, binary.LittleEndian, &header)
+		if err != nil {
+			t.Fatalf("%v: %v", item.Suffix, err)
+		}
+
+		if header.PrinterModel != uint32(item.Model) {
+			t.Errorf("%v: expected model %v, got %v", item.Suffix, item.Model, header.PrinterModel)
+		}
+
+		if header.MachineZ != item.MachineZ {
+			t.Errorf("%v: expected machine Z %v, got %v", item.Suffix, item.MachineZ, header.MachineZ)
+		}
+
+		printable, err := formatter.Decode(&bufferMap{Buffer: data}, int64(len(data)))
+		if err != nil {
+			t.Fatalf("%v: %v", item.Suffix, err)
+		}
+
+		if !cmp.Equal(printable.Size(), testProperties.Size) {
+			t.Errorf("%v: %v", item.Suffix, cmp.Diff(testProperties.Size, printable.Size()))
+		}
+
+		if !cmp.Equal(printable.Exposure(), testProperties.Exposure) {
+			t.Errorf("%v: %v", item.Suffix, cmp.Diff(testProperties.Exposure, printable.Exposure()))
+		}
+
+		if !cmp.Equal(printable.Bottom(), testProperties.Bottom) {
+			t.Errorf("%v: %v", item.Suffix, cmp.Diff(testProperties.Bottom, printable.Bottom()))
+		}
+
+		for n := 0; n < testProperties.Size.Layers; n++ {
+			if !bytes.Equal(printable.LayerImage(n).Pix, empty.LayerImage(n).Pix) {
+				t.Errorf("%v: layer %v mismatch", item.Suffix, n)
+			}
+		}
+	}
+}
+
+func TestDecodeModel(t *testing.T) {
+	empty := uv3dp.NewEmptyPrintable(testProperties)
+
+	buffWriter := &bytes.Buffer{}
+	err := NewFormatter(".lgs4k", ModelOrange4K).Encode(buffWriter, empty)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := buffWriter.Bytes()
+
+	// Files of other models are not decoded
+	_, err = NewFormatter(".lgs", ModelOrange10).Decode(&bufferMap{Buffer: data}, int64(len(data)))
+	if err == nil {
+		t.Errorf("expected an error decoding an Orange 4K file as an Orange 10 file")
+	}
+}
@@ -2,7 +2,7 @@
 // Copyright (c) 2020 Jason S. McMullan <jason.mcmullan@gmail.com>
 //
 
-// Package lgs handles input and output of Longer Orange 10 print files
+// Package lgs handles input and output of Longer Orange series print files
 package lgs
 
 import (
@@ -16,15 +16,27 @@ var (
 	machines_lgs30 = map[string]uv3dp.Machine{
 		"orange30": {Vendor: "Longer", Model: "Orange 30", Size: uv3dp.MachineSize{X: 1440, Y: 2560, Xmm: 68.04, Ymm: 120.96}},
 	}
+	machines_lgs120 = map[string]uv3dp.Machine{
+		"orange120": {Vendor: "Longer", Model: "Orange 120", Size: uv3dp.MachineSize{X: 1440, Y: 2560, Xmm: 68.04, Ymm: 120.96}},
+	}
+	machines_lgs4k = map[string]uv3dp.Machine{
+		"orange4k": {Vendor: "Longer", Model: "Orange 4K", Size: uv3dp.MachineSize{X: 2160, Y: 3840, Xmm: 68.04, Ymm: 120.96}},
+	}
 )
 
 func init() {
-	newFormatter_10 := func(suffix string) (format uv3dp.Formatter) { return NewFormatter(suffix, 10) }
-	newFormatter_30 := func(suffix string) (format uv3dp.Formatter) { return NewFormatter(suffix, 30) }
+	newFormatter_10 := func(suffix string) (format uv3dp.Formatter) { return NewFormatter(suffix, ModelOrange10) }
+	newFormatter_30 := func(suffix string) (format uv3dp.Formatter) { return NewFormatter(suffix, ModelOrange30) }
+	newFormatter_120 := func(suffix string) (format uv3dp.Formatter) { return NewFormatter(suffix, ModelOrange120) }
+	newFormatter_4k := func(suffix string) (format uv3dp.Formatter) { return NewFormatter(suffix, ModelOrange4K) }
 
 	uv3dp.RegisterFormatter(".lgs", newFormatter_10)
 	uv3dp.RegisterFormatter(".lgs30", newFormatter_30)
+	uv3dp.RegisterFormatter(".lgs120", newFormatter_120)
+	uv3dp.RegisterFormatter(".lgs4k", newFormatter_4k)
 
 	uv3dp.RegisterMachines(machines_lgs, ".lgs")
 	uv3dp.RegisterMachines(machines_lgs30, ".lgs30")
+	uv3dp.RegisterMachines(machines_lgs120, ".lgs120")
+	uv3dp.RegisterMachines(machines_lgs4k, ".lgs4k")
 }
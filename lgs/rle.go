@@ -2,7 +2,7 @@
 // Copyright (c) 2020 Jason S. McMullan <jason.mcmullan@gmail.com>
 //
 
-// Package lgs handles input and output of Longer Orange 10 print files
+// Package lgs handles input and output of Longer Orange series print files
 package lgs
 
 import (
@@ -11,6 +11,13 @@ import (
 	"image/color"
 )
 
+// Rle4Encode encodes a layer as 4-bit gray spans.
+//
+// Each byte holds the gray level in the upper nibble, and 4 bits of the
+// span length in the lower nibble. Spans longer than 15 pixels are
+// emitted as a series of bytes of the same gray level, most significant
+// nibble first, so there is no limit on the span length (a full Orange 4K
+// layer is a single span of 23 bits).
 func Rle4Encode(pic *image.Gray) (data []byte, err error) {
 	bounds := pic.Bounds()
 
@@ -42,6 +49,7 @@ func Rle4Encode(pic *image.Gray) (data []byte, err error) {
 	return
 }
 
+// Rle4Decode decodes 4-bit gray spans into a layer of the given bounds
 func Rle4Decode(data []byte, bounds image.Rectangle) (gi *image.Gray) {
 
 	gi = image.NewGray(bounds)
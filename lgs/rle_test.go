@@ -0,0 +1,90 @@
+//
+// Copyright (c) 2020 Jason S. McMullan <jason.mcmullan@gmail.com>
+//
+
+package lgs
+
+import (
+	"bytes"
+	"image"
+	"testing"
+)
+
+func TestRle4Encode(t *testing.T) {
+	rect := image.Rect(0, 0, 8, 4)
+	gray := &image.Gray{
+		Pix: []uint8{
+			0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, // 00
+			0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, // 08
+			0xff, 0xff, 0x00, 0x00, 0x00, 0x7f, 0x7f, 0x00, // 10
+			0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, // 18
+		},
+		Stride: rect.Size().X,
+		Rect:   rect,
+	}
+
+	out_rle := []byte{
+		0xf1, 0xf2,
+		0x03,
+		0x72,
+		0x09,
+	}
+
+	rle, err := Rle4Encode(gray)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(rle, out_rle) {
+		t.Fatalf("expected %#v, got %#v", out_rle, rle)
+	}
+
+	out := Rle4Decode(rle, rect)
+
+	expected := []uint8{
+		0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, // 00
+		0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, // 08
+		0xff, 0xff, 0x00, 0x00, 0x00, 0x77, 0x77, 0x00, // 10
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, // 18
+	}
+
+	if !bytes.Equal(out.Pix, expected) {
+		t.Fatalf("expected %#v, got %#v", expected, out.Pix)
+	}
+}
+
+func TestRle4Encode4K(t *testing.T) {
+	rect := image.Rect(0, 0, 2160, 3840)
+
+	// All empty
+	gray := image.NewGray(rect)
+
+	rle, err := Rle4Encode(gray)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 2160 * 3840 = 0x7e9000
+	out_rle := []byte{0x07, 0x0e, 0x09, 0x00, 0x00, 0x00}
+	if !bytes.Equal(rle, out_rle) {
+		t.Fatalf("expected %#v, got %#v", out_rle, rle)
+	}
+
+	// Single lit pixel at the end of the layer
+	gray.Pix[len(gray.Pix)-1] = 0xff
+
+	rle, err = Rle4Encode(gray)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out_rle = []byte{0x07, 0x0e, 0x08, 0x0f, 0x0f, 0x0f, 0xf1}
+	if !bytes.Equal(rle, out_rle) {
+		t.Fatalf("expected %#v, got %#v", out_rle, rle)
+	}
+
+	out := Rle4Decode(rle, rect)
+	if !bytes.Equal(out.Pix, gray.Pix) {
+		t.Fatalf("4K layer did not survive round trip")
+	}
+}
@@ -14,9 +14,10 @@ type MachineSize struct {
 }
 
 type Machine struct {
-	Vendor string
-	Model  string
-	Size   MachineSize
+	Vendor     string
+	Model      string
+	Size       MachineSize
+	Uniformity *Uniformity // LCD light uniformity, if measured
 }
 
 type MachineFormat struct {
@@ -57,3 +58,17 @@ func RegisterMachines(machineMap map[string]Machine, extension string, args ...s
 
 	return
 }
+
+// AttachUniformity attaches a measured LCD light uniformity to the profile
+// of a registered machine
+func AttachUniformity(name string, uniformity *Uniformity) (err error) {
+	machineFormat, ok := MachineFormats[name]
+	if !ok {
+		err = fmt.Errorf("machine '%s' is not a known machine type", name)
+		return
+	}
+
+	machineFormat.Machine.Uniformity = uniformity
+
+	return
+}
@@ -0,0 +1,40 @@
+//
+// Copyright (c) 2020 Jason S. McMullan <jason.mcmullan@gmail.com>
+//
+
+package uv3dp
+
+import (
+	"encoding/json"
+	"reflect"
+)
+
+// metadataTypes are the registered types of metadata values, by key
+var metadataTypes = map[string]reflect.Type{}
+
+// RegisterMetadata registers the type of the metadata value of a key, by
+// an example value, so that UnmarshalMetadata can restore it from JSON
+func RegisterMetadata(key string, example interface{}) {
+	metadataTypes[key] = reflect.TypeOf(example)
+}
+
+// UnmarshalMetadata restores a metadata value from JSON, as the type
+// registered for its key. Values of keys that are not registered are
+// restored as generic JSON values (ie map[string]interface{}).
+func UnmarshalMetadata(key string, data []byte) (value interface{}, err error) {
+	kind, ok := metadataTypes[key]
+	if !ok {
+		err = json.Unmarshal(data, &value)
+		return
+	}
+
+	ptr := reflect.New(kind)
+	err = json.Unmarshal(data, ptr.Interface())
+	if err != nil {
+		return
+	}
+
+	value = ptr.Elem().Interface()
+
+	return
+}
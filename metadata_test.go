@@ -0,0 +1,44 @@
+//
+// Copyright (c) 2020 Jason S. McMullan <jason.mcmullan@gmail.com>
+//
+
+package uv3dp
+
+import (
+	"encoding/json"
+	"reflect"
+
+	"testing"
+)
+
+type metadataTest struct {
+	Name  string
+	Count int
+}
+
+func TestUnmarshalMetadata(t *testing.T) {
+	RegisterMetadata("test/map", map[string]string{})
+	RegisterMetadata("test/struct", &metadataTest{})
+	defer delete(metadataTypes, "test/map")
+	defer delete(metadataTypes, "test/struct")
+
+	for key, value := range map[string]interface{}{
+		"test/map":    map[string]string{"a": "1", "b": "2"},
+		"test/struct": &metadataTest{Name: "test", Count: 3},
+		"test/other":  map[string]interface{}{"a": "1", "b": 2.0},
+	} {
+		data, err := json.Marshal(value)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := UnmarshalMetadata(key, data)
+		if err != nil {
+			t.Fatalf("%v: %v", key, err)
+		}
+
+		if !reflect.DeepEqual(got, value) {
+			t.Errorf("%v: expected %#v, got %#v", key, value, got)
+		}
+	}
+}
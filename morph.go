@@ -0,0 +1,232 @@
+//
+// Copyright (c) 2020 Jason S. McMullan <jason.mcmullan@gmail.com>
+//
+
+package uv3dp
+
+import (
+	"image"
+)
+
+// MorphOperation is a morphological operation on a layer image
+type MorphOperation int
+
+const (
+	MorphErode  = MorphOperation(iota) // Shrink islands, and grow holes
+	MorphDilate                        // Grow islands, and shrink holes
+	MorphOpen                          // Erode, then dilate: removes specks and thin spurs
+	MorphClose                         // Dilate, then erode: fills pinholes and thin gaps
+)
+
+// MorphKernel is the shape of the neighbourhood of a morphological operation
+type MorphKernel int
+
+const (
+	MorphKernelSquare = MorphKernel(iota) // (2*Radius+1) pixels square
+	MorphKernelCross                      // Horizontal and vertical arms of Radius pixels
+	MorphKernelDisc                       // Pixel centres within Radius+0.5 pixels
+)
+
+// Morph is a morphological operation, with its kernel.
+//
+// Pixels outside of the image are not part of any neighbourhood, so
+// islands touching the edges of the image are not eroded from there.
+type Morph struct {
+	Operation MorphOperation
+	Kernel    MorphKernel
+	Radius    int   // Radius of the kernel, in pixels
+	Gray      bool  // Keep gray levels (min/max), instead of thresholding
+	Threshold uint8 // Pixels brighter than this are on, unless Gray
+}
+
+// spans returns the half width of the kernel for each row, from -Radius to
+// Radius
+func (m *Morph) spans() (spans []int) {
+	r := m.Radius
+	spans = make([]int, 2*r+1)
+
+	for dy := -r; dy <= r; dy++ {
+		span := r
+
+		switch m.Kernel {
+		case MorphKernelCross:
+			if dy != 0 {
+				span = 0
+			}
+		case MorphKernelDisc:
+			// (dx*dx + dy*dy) <= (r + 0.5)^2
+			for span*span+dy*dy > r*r+r {
+				span--
+			}
+		}
+
+		spans[dy+r] = span
+	}
+
+	return
+}
+
+// extremeRow sets each out[x] to the min (or max) of in[x-span .. x+span],
+// ignoring pixels outside of the row. 'pad' is a work area.
+func extremeRow(out []uint8, in []uint8, span int, isMin bool, pad []uint8) {
+	neutral := uint8(0x00)
+	if isMin {
+		neutral = 0xff
+	}
+
+	op := func(a, b uint8) uint8 {
+		if (a < b) == isMin {
+			return a
+		}
+		return b
+	}
+
+	// van Herk/Gil-Werman: running extremes from the start (g) and from the
+	// end (h) of each block of 'k' pixels
+	k := 2*span + 1
+	size := len(in) + 2*span
+	g := pad[0:size]
+	h := pad[size : size*2]
+
+	for n := 0; n < span; n++ {
+		g[n] = neutral
+		g[size-1-n] = neutral
+	}
+	copy(g[span:], in)
+	copy(h, g)
+
+	for n := 1; n < size; n++ {
+		if n%k != 0 {
+			g[n] = op(g[n], g[n-1])
+		}
+	}
+
+	for n := size - 2; n >= 0; n-- {
+		if (n+1)%k != 0 {
+			h[n] = op(h[n], h[n+1])
+		}
+	}
+
+	for x := range out {
+		out[x] = op(h[x], g[x+k-1])
+	}
+}
+
+// extreme returns the min (or max) of the kernel neighbourhood of each pixel
+func (m *Morph) extreme(in *image.Gray, isMin bool) (out *image.Gray) {
+	bounds := in.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+
+	// Horizontal extremes, by half width of the kernel rows
+	spans := m.spans()
+	pad := make([]uint8, 2*(width+2*m.Radius))
+	rows := map[int]*image.Gray{}
+	for _, span := range spans {
+		_, ok := rows[span]
+		if ok {
+			continue
+		}
+
+		row := image.NewGray(bounds)
+		for y := 0; y < height; y++ {
+			extremeRow(row.Pix[y*row.Stride:y*row.Stride+width], in.Pix[y*in.Stride:y*in.Stride+width], span, isMin, pad)
+		}
+		rows[span] = row
+	}
+
+	// Combine the kernel rows
+	out = image.NewGray(bounds)
+	copy(out.Pix, rows[spans[m.Radius]].Pix)
+
+	for n, span := range spans {
+		dy := n - m.Radius
+		if dy == 0 {
+			continue
+		}
+
+		row := rows[span]
+		for y := 0; y < height; y++ {
+			sy := y + dy
+			if sy < 0 || sy >= height {
+				continue
+			}
+
+			dst := out.Pix[y*out.Stride : y*out.Stride+width]
+			src := row.Pix[sy*row.Stride : sy*row.Stride+width]
+			for x, pix := range src {
+				if isMin && pix < dst[x] || !isMin && pix > dst[x] {
+					dst[x] = pix
+				}
+			}
+		}
+	}
+
+	return
+}
+
+// Apply returns a new image, with the operation applied
+func (m *Morph) Apply(in *image.Gray) (out *image.Gray) {
+	bounds := in.Bounds()
+
+	// A copy, with a stride of the image width
+	out = image.NewGray(bounds)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		offset := in.PixOffset(bounds.Min.X, y)
+		copy(out.Pix[(y-bounds.Min.Y)*out.Stride:], in.Pix[offset:offset+bounds.Dx()])
+	}
+
+	if !m.Gray {
+		for n, pix := range out.Pix {
+			if pix > m.Threshold {
+				out.Pix[n] = 0xff
+			} else {
+				out.Pix[n] = 0x00
+			}
+		}
+	}
+
+	if m.Radius <= 0 {
+		return
+	}
+
+	switch m.Operation {
+	case MorphErode:
+		out = m.extreme(out, true)
+	case MorphDilate:
+		out = m.extreme(out, false)
+	case MorphOpen:
+		out = m.extreme(m.extreme(out, true), false)
+	case MorphClose:
+		out = m.extreme(m.extreme(out, false), true)
+	}
+
+	return
+}
+
+// MorphPrintable applies a morphological operation to a range of layers
+type MorphPrintable struct {
+	Printable
+	Morph  Morph
+	Layers LayerRange // Layers to apply the operation to
+}
+
+// NewMorphPrintable applies a morphological operation to a range of layers
+func NewMorphPrintable(printable Printable, morph Morph, layers LayerRange) (mp *MorphPrintable) {
+	mp = &MorphPrintable{
+		Printable: printable,
+		Morph:     morph,
+		Layers:    layers,
+	}
+
+	return
+}
+
+func (mp *MorphPrintable) LayerImage(index int) (ig *image.Gray) {
+	ig = mp.Printable.LayerImage(index)
+
+	if mp.Layers.Contains(mp.Printable, index) {
+		ig = mp.Morph.Apply(ig)
+	}
+
+	return
+}
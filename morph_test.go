@@ -0,0 +1,141 @@
+//
+// Copyright (c) 2020 Jason S. McMullan <jason.mcmullan@gmail.com>
+//
+
+package uv3dp
+
+import (
+	"testing"
+
+	"image"
+	"math/rand"
+)
+
+// naiveExtreme is the min (or max) of each pixel's neighbourhood, by brute force
+func naiveExtreme(in *image.Gray, m Morph, isMin bool) (out *image.Gray) {
+	bounds := in.Bounds()
+	out = image.NewGray(bounds)
+	spans := m.spans()
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			value := in.GrayAt(x, y).Y
+			for dy := -m.Radius; dy <= m.Radius; dy++ {
+				span := spans[dy+m.Radius]
+				for dx := -span; dx <= span; dx++ {
+					pt := image.Pt(x+dx, y+dy)
+					if !pt.In(bounds) {
+						continue
+					}
+					pix := in.GrayAt(pt.X, pt.Y).Y
+					if isMin && pix < value || !isMin && pix > value {
+						value = pix
+					}
+				}
+			}
+			out.Pix[out.PixOffset(x, y)] = value
+		}
+	}
+
+	return
+}
+
+func TestMorphSpans(t *testing.T) {
+	table := []struct {
+		kernel MorphKernel
+		radius int
+		spans  []int
+	}{
+		{MorphKernelSquare, 2, []int{2, 2, 2, 2, 2}},
+		{MorphKernelCross, 2, []int{0, 0, 2, 0, 0}},
+		{MorphKernelDisc, 1, []int{1, 1, 1}},
+		{MorphKernelDisc, 2, []int{1, 2, 2, 2, 1}},
+		{MorphKernelDisc, 3, []int{1, 2, 3, 3, 3, 2, 1}},
+	}
+
+	for _, item := range table {
+		m := Morph{Kernel: item.kernel, Radius: item.radius}
+		spans := m.spans()
+		for n, span := range spans {
+			if span != item.spans[n] {
+				t.Errorf("kernel %v radius %v: expected %v, got %v", item.kernel, item.radius, item.spans, spans)
+				break
+			}
+		}
+	}
+}
+
+func TestMorphExtreme(t *testing.T) {
+	in := image.NewGray(image.Rect(0, 0, 23, 17))
+	rand.New(rand.NewSource(1)).Read(in.Pix)
+
+	for _, kernel := range []MorphKernel{MorphKernelSquare, MorphKernelCross, MorphKernelDisc} {
+		for radius := 0; radius < 5; radius++ {
+			m := Morph{Kernel: kernel, Radius: radius, Gray: true}
+			for _, isMin := range []bool{true, false} {
+				expected := naiveExtreme(in, m, isMin)
+				got := m.extreme(in, isMin)
+				for n := range expected.Pix {
+					if expected.Pix[n] != got.Pix[n] {
+						t.Errorf("kernel %v radius %v min %v: pixel %v expected %#x, got %#x", kernel, radius, isMin, n, expected.Pix[n], got.Pix[n])
+						break
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestMorphApply(t *testing.T) {
+	in := grayFrom(`Speck and gap
+XXXXXX XXXXXX
+XXXXXX XXXXXX
+XXXXXX XXXXXX
+XXXXXX XXXXXX
+
+           X
+`)
+
+	// Opening removes the speck, and keeps the blocks
+	open := Morph{Operation: MorphOpen, Kernel: MorphKernelSquare, Radius: 1, Threshold: 127}
+	got := open.Apply(in)
+	expected := grayFrom(`Opened
+XXXXXX XXXXXX
+XXXXXX XXXXXX
+XXXXXX XXXXXX
+XXXXXX XXXXXX
+
+`)
+	for n := range expected.Pix {
+		if got.Pix[n] != expected.Pix[n] {
+			t.Fatalf("open: pixel %v expected %#x, got %#x", n, expected.Pix[n], got.Pix[n])
+		}
+	}
+
+	// Closing fills the gap, above the bottom corners of the blocks
+	close := Morph{Operation: MorphClose, Kernel: MorphKernelCross, Radius: 1, Threshold: 127}
+	got = close.Apply(in)
+	for y := 0; y < 3; y++ {
+		if got.GrayAt(6, y).Y != 0xff {
+			t.Errorf("close: (6,%v) was not filled", y)
+		}
+	}
+
+	// Thresholding, and gray levels
+	gray := image.NewGray(image.Rect(0, 0, 3, 1))
+	copy(gray.Pix, []uint8{0x40, 0x80, 0xc0})
+
+	got = (&Morph{Operation: MorphErode, Radius: 0, Threshold: 0x7f}).Apply(gray)
+	if got.Pix[0] != 0x00 || got.Pix[1] != 0xff || got.Pix[2] != 0xff {
+		t.Errorf("threshold: unexpected %#v", got.Pix)
+	}
+
+	got = (&Morph{Operation: MorphDilate, Radius: 1, Gray: true}).Apply(gray)
+	if got.Pix[0] != 0x80 || got.Pix[1] != 0xc0 || got.Pix[2] != 0xc0 {
+		t.Errorf("gray dilate: unexpected %#v", got.Pix)
+	}
+
+	if gray.Pix[0] != 0x40 {
+		t.Errorf("input image was modified")
+	}
+}
@@ -0,0 +1,394 @@
+//
+// Copyright (c) 2020 Jason S. McMullan <jason.mcmullan@gmail.com>
+//
+
+package nanodlp
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"time"
+
+	"github.com/ezrec/uv3dp"
+	"github.com/spf13/pflag"
+)
+
+var (
+	time_Now = time.Now
+)
+
+const (
+	defaultPixelSize   = 47.25 // Pixel pitch in microns
+	defaultLayerHeight = 0.05  // Layer height in mm
+)
+
+// Plate is the NanoDLP 'plate.json' description of a sliced plate
+type Plate struct {
+	PlateID        int
+	ProfileID      int
+	Path           string
+	LayersCount    int
+	TotalSolidArea float32
+	PWidth         int     // Slice width, in pixels
+	PHeight        int     // Slice height, in pixels
+	XRes           float32 // Pixel pitch in X, in microns
+	YRes           float32 // Pixel pitch in Y, in microns
+	Updated        int64   // Unix timestamp
+}
+
+// Profile is the NanoDLP 'profile.json' resin profile
+type Profile struct {
+	ProfileID            int
+	Title                string
+	Desc                 string
+	Depth                int     // Layer thickness, in microns
+	SupportDepth         int     // Support layer thickness, in microns
+	SupportLayerNumber   int     // Number of support (bottom) layers
+	TransitionalLayer    int     // Number of layers to transition from support to normal cure
+	CureTime             float32 // Seconds
+	SupportCureTime      float32 // Seconds
+	WaitAfterCure        float32 // Seconds
+	SupportWaitAfterCure float32 // Seconds
+	WaitBeforePrint      float32 // Seconds
+	ZLiftDistance        float32 // mm
+	SupportZLiftDistance float32 // mm
+	ZLiftSpeed           float32 // mm/min
+	SupportZLiftSpeed    float32 // mm/min
+	ZRetractSpeed        float32 // mm/min
+	SupportZRetractSpeed float32 // mm/min
+}
+
+var (
+	defaultProfile = Profile{
+		Title:                "uv3dp",
+		Depth:                50,
+		SupportDepth:         50,
+		SupportLayerNumber:   3,
+		CureTime:             8,
+		SupportCureTime:      60,
+		WaitAfterCure:        1,
+		SupportWaitAfterCure: 1,
+		ZLiftDistance:        5,
+		SupportZLiftDistance: 5,
+		ZLiftSpeed:           60,
+		SupportZLiftSpeed:    60,
+		ZRetractSpeed:        150,
+		SupportZRetractSpeed: 150,
+	}
+)
+
+type Print struct {
+	uv3dp.Print
+	layerPng []([]byte)
+}
+
+type Format struct {
+	*pflag.FlagSet
+
+	Plain       bool
+	PixelSize   float32
+	LayerHeight float32
+}
+
+func NewFormatter(suffix string) (sf *Format) {
+	flagSet := pflag.NewFlagSet(suffix, pflag.ContinueOnError)
+
+	sf = &Format{
+		FlagSet: flagSet,
+	}
+
+	sf.BoolVarP(&sf.Plain, "plain", "P", false, "Write a plain plate archive (numbered slices only, no plate.json or profile.json)")
+	sf.Float32VarP(&sf.PixelSize, "pixel-size", "x", defaultPixelSize, "Pixel pitch in microns, when not specified by the archive")
+	sf.Float32VarP(&sf.LayerHeight, "layer-height", "l", defaultLayerHeight, "Layer height in mm, when not specified by the archive")
+	sf.SetInterspersed(false)
+
+	return
+}
+
+func (sf *Format) Encode(writer uv3dp.Writer, printable uv3dp.Printable) (err error) {
+	archive := zip.NewWriter(writer)
+	defer archive.Close()
+
+	size := printable.Size()
+	exp := printable.Exposure()
+	bot := printable.Bottom()
+
+	// Create all the layers
+	uv3dp.WithEachLayer(printable, func(p uv3dp.Printable, n int) {
+		filename := fmt.Sprintf("%d.png", n+1)
+
+		var writer io.Writer
+		writer, err = archive.Create(filename)
+		if err != nil {
+			return
+		}
+
+		err = png.Encode(writer, p.LayerImage(n))
+		if err != nil {
+			return
+		}
+	})
+
+	if err != nil || sf.Plain {
+		return
+	}
+
+	plate := Plate{}
+	anon, ok := printable.Metadata("nanodlp/Plate")
+	if ok {
+		plateptr, ok := anon.(*Plate)
+		if ok {
+			plate = *plateptr
+		}
+	}
+
+	plate.Path = "uv3dp"
+	plate.LayersCount = size.Layers
+	plate.PWidth = size.X
+	plate.PHeight = size.Y
+	plate.XRes = size.Millimeter.X * 1000.0 / float32(size.X)
+	plate.YRes = size.Millimeter.Y * 1000.0 / float32(size.Y)
+	plate.Updated = time_Now().Unix()
+
+	profile := defaultProfile
+	anon, ok = printable.Metadata("nanodlp/Profile")
+	if ok {
+		profileptr, ok := anon.(*Profile)
+		if ok {
+			profile = *profileptr
+		}
+	}
+
+	depth := int(size.LayerHeight*1000.0 + 0.5)
+
+	profile.ProfileID = plate.ProfileID
+	profile.Depth = depth
+	profile.SupportDepth = depth
+	profile.SupportLayerNumber = bot.Count
+	profile.TransitionalLayer = bot.Transition
+	profile.CureTime = exp.LightOnTime
+	profile.SupportCureTime = bot.Exposure.LightOnTime
+	profile.WaitAfterCure = exp.LightOffTime
+	profile.SupportWaitAfterCure = bot.Exposure.LightOffTime
+	profile.ZLiftDistance = exp.LiftHeight
+	profile.SupportZLiftDistance = bot.Exposure.LiftHeight
+	profile.ZLiftSpeed = exp.LiftSpeed
+	profile.SupportZLiftSpeed = bot.Exposure.LiftSpeed
+	profile.ZRetractSpeed = exp.RetractSpeed
+	profile.SupportZRetractSpeed = bot.Exposure.RetractSpeed
+
+	// Save the plate
+	writer, err = archive.Create("plate.json")
+	if err != nil {
+		return
+	}
+
+	err = json.NewEncoder(writer).Encode(&plate)
+	if err != nil {
+		return
+	}
+
+	// Save the profile
+	writer, err = archive.Create("profile.json")
+	if err != nil {
+		return
+	}
+
+	err = json.NewEncoder(writer).Encode(&profile)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func loadJSON(filemap map[string](*zip.File), filename string, msg interface{}) (found bool, err error) {
+	zfile, found := filemap[filename]
+	if !found {
+		return
+	}
+
+	reader, err := zfile.Open()
+	if err != nil {
+		return
+	}
+	defer reader.Close()
+
+	err = json.NewDecoder(reader).Decode(msg)
+	if err != nil {
+		err = fmt.Errorf("%s: %w", filename, err)
+	}
+
+	return
+}
+
+func (sf *Format) Decode(reader uv3dp.Reader, filesize int64) (printable uv3dp.Printable, err error) {
+	archive, err := zip.NewReader(reader, filesize)
+	if err != nil {
+		return
+	}
+
+	fileMap := make(map[string](*zip.File))
+
+	for _, file := range archive.File {
+		fileMap[file.Name] = file
+	}
+
+	var plate Plate
+	hasPlate, err := loadJSON(fileMap, "plate.json", &plate)
+	if err != nil {
+		return
+	}
+
+	profile := defaultProfile
+	hasProfile, err := loadJSON(fileMap, "profile.json", &profile)
+	if err != nil {
+		return
+	}
+
+	layers := plate.LayersCount
+	if !hasPlate {
+		// Plain plate archives only have the numbered slices
+		for {
+			_, ok := fileMap[fmt.Sprintf("%d.png", layers+1)]
+			if !ok {
+				break
+			}
+			layers++
+		}
+	}
+
+	if layers == 0 {
+		err = errors.New("no slices found in archive")
+		return
+	}
+
+	// Collect the layer files
+	layerPng := make([]([]byte), layers)
+	for n := 0; n < cap(layerPng); n++ {
+		name := fmt.Sprintf("%d.png", n+1)
+		file, ok := fileMap[name]
+		if !ok {
+			err = fmt.Errorf("%s: Missing from archive", name)
+			return
+		}
+		var reader io.ReadCloser
+		reader, err = file.Open()
+		if err != nil {
+			return
+		}
+		defer reader.Close()
+
+		layerPng[n], err = io.ReadAll(reader)
+		if err != nil {
+			return
+		}
+	}
+
+	if plate.PWidth == 0 || plate.PHeight == 0 {
+		var config image.Config
+		config, err = png.DecodeConfig(bytes.NewReader(layerPng[0]))
+		if err != nil {
+			err = fmt.Errorf("1.png: %w", err)
+			return
+		}
+		plate.PWidth = config.Width
+		plate.PHeight = config.Height
+	}
+
+	if plate.XRes == 0 {
+		plate.XRes = sf.PixelSize
+	}
+
+	if plate.YRes == 0 {
+		plate.YRes = sf.PixelSize
+	}
+
+	prop := uv3dp.Properties{}
+
+	size := &prop.Size
+	size.X = plate.PWidth
+	size.Y = plate.PHeight
+	size.Layers = layers
+
+	size.Millimeter.X = float32(size.X) * plate.XRes / 1000.0
+	size.Millimeter.Y = float32(size.Y) * plate.YRes / 1000.0
+
+	if hasProfile {
+		size.LayerHeight = float32(profile.Depth) / 1000.0
+	} else {
+		size.LayerHeight = sf.LayerHeight
+	}
+
+	exp := &prop.Exposure
+	exp.LightOnTime = profile.CureTime
+	exp.LightOffTime = profile.WaitAfterCure
+	exp.LightPWM = 255
+	exp.LiftHeight = profile.ZLiftDistance
+	exp.LiftSpeed = profile.ZLiftSpeed
+	exp.RetractSpeed = profile.ZRetractSpeed
+
+	bot := &prop.Bottom
+	bot.Count = profile.SupportLayerNumber
+	bot.Transition = profile.TransitionalLayer
+	bot.Exposure.LightOnTime = profile.SupportCureTime
+	bot.Exposure.LightOffTime = profile.SupportWaitAfterCure
+	bot.Exposure.LightPWM = 255
+	bot.Exposure.LiftHeight = profile.SupportZLiftDistance
+	bot.Exposure.LiftSpeed = profile.SupportZLiftSpeed
+	bot.Exposure.RetractSpeed = profile.SupportZRetractSpeed
+
+	prop.Metadata = make(map[string](interface{}))
+	if hasPlate {
+		prop.Metadata["nanodlp/Plate"] = &plate
+	}
+	if hasProfile {
+		prop.Metadata["nanodlp/Profile"] = &profile
+	}
+
+	nanodlp := &Print{
+		Print:    uv3dp.Print{Properties: prop},
+		layerPng: layerPng,
+	}
+
+	printable = nanodlp
+
+	return
+}
+
+func (nanodlp *Print) Close() {
+}
+
+func asGray(in image.Image) (out *image.Gray) {
+	bounds := in.Bounds()
+	out = image.NewGray(bounds)
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			out.Set(x, y, color.GrayModel.Convert(in.At(x, y)))
+		}
+	}
+
+	return
+}
+
+func (nanodlp *Print) LayerImage(index int) (grayImage *image.Gray) {
+	pngImage, err := png.Decode(bytes.NewReader(nanodlp.layerPng[index]))
+	if err != nil {
+		panic(err)
+	}
+
+	grayImage, ok := pngImage.(*image.Gray)
+	if !ok {
+		grayImage = asGray(pngImage)
+	}
+
+	return
+}
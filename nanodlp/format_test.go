@@ -0,0 +1,212 @@
+//
+// Copyright (c) 2020 Jason S. McMullan <jason.mcmullan@gmail.com>
+//
+
+package nanodlp
+
+import (
+	"archive/zip"
+	"bytes"
+	"image"
+	"image/png"
+	"io"
+	"strings"
+	"time"
+
+	"testing"
+
+	"github.com/ezrec/uv3dp"
+	"github.com/google/go-cmp/cmp"
+)
+
+type bufferReader struct {
+	data []byte
+}
+
+func (br *bufferReader) ReadAt(p []byte, off int64) (n int, err error) {
+	copy(p, br.data[off:])
+	n = len(p)
+
+	return
+}
+
+func (br *bufferReader) Read(p []byte) (n int, err error) {
+	return 0, io.EOF
+}
+
+func (br *bufferReader) Len() int64 {
+	return int64(len(br.data))
+}
+
+var (
+	testProperties = uv3dp.Properties{
+		Size: uv3dp.Size{
+			X: 10,
+			Y: 20,
+			Millimeter: uv3dp.SizeMillimeter{
+				X: 20.0,
+				Y: 40.0,
+			},
+			Layers:      4, // 2 bottom, 2 normal
+			LayerHeight: 0.05,
+		},
+		Exposure: uv3dp.Exposure{
+			LightOnTime:  16.500,
+			LightOffTime: 2.250,
+			LightPWM:     255,
+			LiftHeight:   5.5,
+			LiftSpeed:    120.0,
+			RetractSpeed: 200.0,
+		},
+		Bottom: uv3dp.Bottom{
+			Count:      2,
+			Transition: 1,
+			Exposure: uv3dp.Exposure{
+				LightOnTime:  30.000,
+				LightOffTime: 2.250,
+				LightPWM:     255,
+				LiftHeight:   6.5,
+				LiftSpeed:    60.0,
+				RetractSpeed: 200.0,
+			},
+		},
+	}
+)
+
+const (
+	testPlateJson = `{"PlateID":0,"ProfileID":0,"Path":"uv3dp","LayersCount":4,"TotalSolidArea":0,"PWidth":10,"PHeight":20,"XRes":2000,"YRes":2000,"Updated":-62135596800}
+`
+	testProfileJson = `{"ProfileID":0,"Title":"uv3dp","Desc":"","Depth":50,"SupportDepth":50,"SupportLayerNumber":2,"TransitionalLayer":1,"CureTime":16.5,"SupportCureTime":30,"WaitAfterCure":2.25,"SupportWaitAfterCure":2.25,"WaitBeforePrint":0,"ZLiftDistance":5.5,"SupportZLiftDistance":6.5,"ZLiftSpeed":120,"SupportZLiftSpeed":60,"ZRetractSpeed":200,"SupportZRetractSpeed":200}
+`
+)
+
+func encodeToMap(t *testing.T, formatter *Format, printable uv3dp.Printable) (buff []byte, fileMap map[string](*zip.File)) {
+	buffWriter := &bytes.Buffer{}
+	err := formatter.Encode(buffWriter, printable)
+	if err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+
+	buff = buffWriter.Bytes()
+	buffReader := &bufferReader{buff}
+
+	archive, err := zip.NewReader(buffReader, buffReader.Len())
+	if err != nil {
+		t.Fatalf("zip: %v", err)
+	}
+
+	fileMap = map[string](*zip.File){}
+	for _, file := range archive.File {
+		fileMap[file.Name] = file
+	}
+
+	return
+}
+
+func TestEncodeEmptyNanoDLP(t *testing.T) {
+	time_Now = func() (now time.Time) { return }
+
+	buffPng := &bytes.Buffer{}
+	png.Encode(buffPng, image.NewGray(testProperties.Bounds()))
+	png_empty := buffPng.Bytes()
+
+	expected_zip := map[string]([]byte){
+		"plate.json":   []byte(testPlateJson),
+		"profile.json": []byte(testProfileJson),
+		"1.png":        png_empty,
+		"2.png":        png_empty,
+		"3.png":        png_empty,
+		"4.png":        png_empty,
+	}
+
+	empty := uv3dp.NewEmptyPrintable(testProperties)
+
+	_, fileMap := encodeToMap(t, NewFormatter(".nanodlp"), empty)
+
+	for name, expected := range expected_zip {
+		file, found := fileMap[name]
+		if !found {
+			t.Errorf("%v: Not found in encoded archive", name)
+			continue
+		}
+
+		rc, _ := file.Open()
+		defer rc.Close()
+		got, _ := io.ReadAll(rc)
+
+		if !bytes.Equal(expected, got) {
+			if strings.HasSuffix(name, ".json") {
+				t.Errorf("%s: expected:\n%v\n  got:\n%v", name, string(expected), string(got))
+			} else {
+				t.Errorf("%s: expected %d bytes, got %d bytes", name, len(expected), len(got))
+			}
+		}
+	}
+}
+
+func TestDecodeNanoDLP(t *testing.T) {
+	time_Now = func() (now time.Time) { return }
+
+	empty := uv3dp.NewEmptyPrintable(testProperties)
+
+	formatter := NewFormatter(".nanodlp")
+	buff, _ := encodeToMap(t, formatter, empty)
+
+	buffReader := &bufferReader{buff}
+	printable, err := formatter.Decode(buffReader, buffReader.Len())
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+
+	if !cmp.Equal(printable.Size(), testProperties.Size) {
+		t.Errorf("size: %v", cmp.Diff(printable.Size(), testProperties.Size))
+	}
+
+	if !cmp.Equal(printable.Exposure(), testProperties.Exposure) {
+		t.Errorf("exposure: %v", cmp.Diff(printable.Exposure(), testProperties.Exposure))
+	}
+
+	if !cmp.Equal(printable.Bottom(), testProperties.Bottom) {
+		t.Errorf("bottom: %v", cmp.Diff(printable.Bottom(), testProperties.Bottom))
+	}
+}
+
+func TestDecodePlainNanoDLP(t *testing.T) {
+	empty := uv3dp.NewEmptyPrintable(testProperties)
+
+	formatter := NewFormatter(".nanodlp")
+	formatter.Plain = true
+
+	buff, fileMap := encodeToMap(t, formatter, empty)
+
+	for _, name := range []string{"plate.json", "profile.json"} {
+		_, found := fileMap[name]
+		if found {
+			t.Errorf("%v: unexpected in plain archive", name)
+		}
+	}
+
+	formatter = NewFormatter(".nanodlp")
+	buffReader := &bufferReader{buff}
+	printable, err := formatter.Decode(buffReader, buffReader.Len())
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+
+	size := printable.Size()
+	if size.Layers != testProperties.Size.Layers {
+		t.Errorf("layers: expected %v, got %v", testProperties.Size.Layers, size.Layers)
+	}
+
+	if size.X != testProperties.Size.X || size.Y != testProperties.Size.Y {
+		t.Errorf("pixels: expected %vx%v, got %vx%v", testProperties.Size.X, testProperties.Size.Y, size.X, size.Y)
+	}
+
+	if size.LayerHeight != defaultLayerHeight {
+		t.Errorf("layer height: expected %v, got %v", defaultLayerHeight, size.LayerHeight)
+	}
+
+	if printable.Bottom().Count != defaultProfile.SupportLayerNumber {
+		t.Errorf("bottom count: expected %v, got %v", defaultProfile.SupportLayerNumber, printable.Bottom().Count)
+	}
+}
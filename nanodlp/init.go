@@ -0,0 +1,19 @@
+//
+// Copyright (c) 2020 Jason S. McMullan <jason.mcmullan@gmail.com>
+//
+
+// Package nanodlp handles input and output of NanoDLP plate archives
+package nanodlp
+
+import (
+	"github.com/ezrec/uv3dp"
+)
+
+func init() {
+	newFormatter := func(suffix string) uv3dp.Formatter { return NewFormatter(suffix) }
+
+	uv3dp.RegisterFormatter(".nanodlp", newFormatter)
+
+	uv3dp.RegisterMetadata("nanodlp/Plate", &Plate{})
+	uv3dp.RegisterMetadata("nanodlp/Profile", &Profile{})
+}
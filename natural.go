@@ -0,0 +1,62 @@
+//
+// Copyright (c) 2020 Jason S. McMullan <jason.mcmullan@gmail.com>
+//
+
+package uv3dp
+
+import (
+	"strings"
+)
+
+// Split a string into its leading run of digits (or non-digits), and the rest
+func naturalChunk(s string) (chunk string, rest string, digits bool) {
+	if len(s) == 0 {
+		return
+	}
+
+	isDigit := func(c byte) bool { return c >= '0' && c <= '9' }
+
+	digits = isDigit(s[0])
+	n := 1
+	for ; n < len(s) && isDigit(s[n]) == digits; n++ {
+	}
+
+	chunk = s[:n]
+	rest = s[n:]
+
+	return
+}
+
+// NaturalLess compares two strings, treating runs of digits as numbers,
+// so that 'slice2.png' sorts before 'slice10.png'
+func NaturalLess(a, b string) bool {
+	for len(a) > 0 && len(b) > 0 {
+		var ca, cb string
+		var da, db bool
+
+		ca, a, da = naturalChunk(a)
+		cb, b, db = naturalChunk(b)
+
+		if da && db {
+			ta := strings.TrimLeft(ca, "0")
+			tb := strings.TrimLeft(cb, "0")
+			if len(ta) != len(tb) {
+				return len(ta) < len(tb)
+			}
+			if ta != tb {
+				return ta < tb
+			}
+			// Equal values; fewer leading zeros first
+			if len(ca) != len(cb) {
+				return len(ca) < len(cb)
+			}
+			continue
+		}
+
+		if ca != cb {
+			return ca < cb
+		}
+	}
+
+	return len(a) < len(b)
+}
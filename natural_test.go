@@ -0,0 +1,23 @@
+//
+// Copyright (c) 2020 Jason S. McMullan <jason.mcmullan@gmail.com>
+//
+
+package uv3dp
+
+import (
+	"sort"
+	"strings"
+
+	"testing"
+)
+
+func TestNaturalLess(t *testing.T) {
+	names := []string{"slice10.png", "slice2.png", "slice1.png", "slice01.png", "a.png", "slice1b.png"}
+	expected := []string{"a.png", "slice1.png", "slice1b.png", "slice01.png", "slice2.png", "slice10.png"}
+
+	sort.Slice(names, func(i, j int) bool { return NaturalLess(names[i], names[j]) })
+
+	if strings.Join(names, " ") != strings.Join(expected, " ") {
+		t.Errorf("expected %v, got %v", expected, names)
+	}
+}
@@ -125,6 +125,14 @@ func NewFormatter(suffix string) (pf *Formatter) {
 	return
 }
 
+// PreviewSizes returns the sizes of the previews shown by the printer
+func (pf *Formatter) PreviewSizes() map[uv3dp.PreviewType]image.Point {
+	return map[uv3dp.PreviewType]image.Point{
+		uv3dp.PreviewTypeTiny: {X: 200, Y: 125},
+		uv3dp.PreviewTypeHuge: {X: 400, Y: 300},
+	}
+}
+
 // Save a uv3dp.Printable in CBD DLP format
 func (pf *Formatter) Encode(writer uv3dp.Writer, printable uv3dp.Printable) (err error) {
 	size := printable.Size()
@@ -223,7 +231,8 @@ func (pf *Formatter) Encode(writer uv3dp.Writer, printable uv3dp.Printable) (err
 	}
 
 	uv3dp.WithAllLayers(printable, func(p uv3dp.Printable, n int) {
-		rle, hash, bitsOn := rleEncodeGraymap(p.LayerImage(n))
+		layer := uv3dp.QuantizeLayer(p.LayerImage(n), 128) // 7-bit gray
+		rle, hash, bitsOn := rleEncodeGraymap(layer)
 		doneMap[n] <- layerInfo{
 			Z:        p.LayerZ(n),
 			Exposure: p.LayerExposure(n),
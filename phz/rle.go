@@ -99,7 +99,7 @@ func rleDecodeGraymap(bounds image.Rectangle, rle []byte) (gm *image.Gray, err e
 		if (code & 0x80) == 0x80 {
 			// Convert from 0..124 to 8bpp
 			lastColor = ((code & 0x7f) << 1) | (code & 1)
-			if lastColor >= 0xfc {
+			if lastColor >= 0xf8 {
 				// Make 'white' actually white
 				lastColor = 0xff
 			}
@@ -0,0 +1,245 @@
+//
+// Copyright (c) 2020 Jason S. McMullan <jason.mcmullan@gmail.com>
+//
+
+package uv3dp
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// PixelOperation is an arithmetic operation on the pixels of a layer image
+type PixelOperation int
+
+const (
+	PixelSet       = PixelOperation(iota) // Replace pixels with the operand
+	PixelAnd                              // Minimum of the pixel and the operand
+	PixelOr                               // Maximum of the pixel and the operand
+	PixelXor                              // Difference of the pixel and the operand
+	PixelSubtract                         // Pixel less the operand, down to black
+	PixelInvert                           // Negative of the pixel
+	PixelLUT                              // Gray level from the Table
+	PixelGamma                            // Gray level from a gamma curve
+	PixelThreshold                        // White if brighter than the Threshold, otherwise black
+)
+
+// PixelTable maps every gray level to a new gray level
+type PixelTable [256]uint8
+
+// NewGammaTable returns the table of the gamma curve 255*(pix/255)^gamma
+func NewGammaTable(gamma float64) (table PixelTable) {
+	for n := range table {
+		table[n] = uint8(math.Round(math.Pow(float64(n)/0xff, gamma) * 0xff))
+	}
+
+	return
+}
+
+// ParsePixelTable parses a comma separated list of 'in:out' gray level
+// points, joined by straight lines, into a table. Gray levels before the
+// first point, or after the last, are those of the nearest point.
+func ParsePixelTable(text string) (table PixelTable, err error) {
+	type point struct {
+		in, out int
+	}
+
+	var points []point
+	for _, item := range strings.Split(text, ",") {
+		item = strings.TrimSpace(item)
+
+		inText, outText, ok := strings.Cut(item, ":")
+		if !ok {
+			err = fmt.Errorf("lookup table point '%v': expected 'in:out'", item)
+			return
+		}
+
+		var in, out uint64
+		in, err = strconv.ParseUint(strings.TrimSpace(inText), 0, 8)
+		if err == nil {
+			out, err = strconv.ParseUint(strings.TrimSpace(outText), 0, 8)
+		}
+		if err != nil {
+			err = fmt.Errorf("lookup table point '%v': %w", item, err)
+			return
+		}
+
+		points = append(points, point{in: int(in), out: int(out)})
+	}
+
+	sort.SliceStable(points, func(i, j int) bool { return points[i].in < points[j].in })
+
+	for n := 1; n < len(points); n++ {
+		if points[n].in == points[n-1].in {
+			err = fmt.Errorf("lookup table point %v: repeated", points[n].in)
+			return
+		}
+	}
+
+	for pix := range table {
+		next := sort.Search(len(points), func(i int) bool { return points[i].in >= pix })
+
+		var out int
+		switch {
+		case next == len(points):
+			out = points[next-1].out
+		case next == 0 || points[next].in == pix:
+			out = points[next].out
+		default:
+			p0, p1 := points[next-1], points[next]
+			out = p0.out + int(math.Round(float64((p1.out-p0.out)*(pix-p0.in))/float64(p1.in-p0.in)))
+		}
+
+		table[pix] = uint8(out)
+	}
+
+	return
+}
+
+// Pixel is an arithmetic operation on the pixels of a layer image, with a
+// constant operand or a mask.
+//
+// A mask is placed on the bed, and pixels outside of it are unchanged.
+type Pixel struct {
+	Operation PixelOperation
+	Value     uint8       // Constant operand
+	Mask      *image.Gray // Mask operand, instead of the Value, if set
+	Position  image.Point // Position of the top left of the Mask, in pixels
+	Table     PixelTable  // Table of PixelLUT
+	Gamma     float64     // Gamma of PixelGamma
+	Threshold uint8       // Threshold of PixelThreshold
+}
+
+// NewPixelMask converts an image to a mask, by its gray levels
+func NewPixelMask(ig image.Image) (mask *image.Gray) {
+	bounds := ig.Bounds()
+	mask = image.NewGray(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+
+	for y := 0; y < bounds.Dy(); y++ {
+		for x := 0; x < bounds.Dx(); x++ {
+			mask.Pix[mask.PixOffset(x, y)] = color.GrayModel.Convert(ig.At(bounds.Min.X+x, bounds.Min.Y+y)).(color.Gray).Y
+		}
+	}
+
+	return
+}
+
+// function returns the operation, on a pixel and its operand
+func (px *Pixel) function() (op func(pix, operand uint8) uint8) {
+	switch px.Operation {
+	case PixelSet:
+		op = func(pix, operand uint8) uint8 { return operand }
+	case PixelAnd:
+		op = func(pix, operand uint8) uint8 {
+			if operand < pix {
+				return operand
+			}
+			return pix
+		}
+	case PixelOr:
+		op = func(pix, operand uint8) uint8 {
+			if operand > pix {
+				return operand
+			}
+			return pix
+		}
+	case PixelXor:
+		op = func(pix, operand uint8) uint8 {
+			if operand > pix {
+				return operand - pix
+			}
+			return pix - operand
+		}
+	case PixelSubtract:
+		op = func(pix, operand uint8) uint8 {
+			if operand > pix {
+				return 0x00
+			}
+			return pix - operand
+		}
+	case PixelInvert:
+		op = func(pix, operand uint8) uint8 { return 0xff - pix }
+	case PixelLUT, PixelGamma:
+		table := px.Table
+		if px.Operation == PixelGamma {
+			table = NewGammaTable(px.Gamma)
+		}
+		op = func(pix, operand uint8) uint8 { return table[pix] }
+	case PixelThreshold:
+		op = func(pix, operand uint8) uint8 {
+			if pix > px.Threshold {
+				return 0xff
+			}
+			return 0x00
+		}
+	default:
+		op = func(pix, operand uint8) uint8 { return pix }
+	}
+
+	return
+}
+
+// Apply returns a new image, with the operation applied
+func (px *Pixel) Apply(in *image.Gray) (out *image.Gray) {
+	bounds := in.Bounds()
+	op := px.function()
+
+	out = image.NewGray(bounds)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		offset := in.PixOffset(bounds.Min.X, y)
+		copy(out.Pix[out.PixOffset(bounds.Min.X, y):], in.Pix[offset:offset+bounds.Dx()])
+	}
+
+	if px.Mask == nil {
+		for n, pix := range out.Pix {
+			out.Pix[n] = op(pix, px.Value)
+		}
+		return
+	}
+
+	// Mask pixels, in the coordinates of the layer
+	delta := px.Position.Sub(px.Mask.Bounds().Min)
+	area := px.Mask.Bounds().Add(delta).Intersect(bounds)
+
+	for y := area.Min.Y; y < area.Max.Y; y++ {
+		for x := area.Min.X; x < area.Max.X; x++ {
+			n := out.PixOffset(x, y)
+			out.Pix[n] = op(out.Pix[n], px.Mask.Pix[px.Mask.PixOffset(x-delta.X, y-delta.Y)])
+		}
+	}
+
+	return
+}
+
+// PixelPrintable applies a pixel operation to a range of layers
+type PixelPrintable struct {
+	Printable
+	Pixel  Pixel
+	Layers LayerRange // Layers to apply the operation to
+}
+
+// NewPixelPrintable applies a pixel operation to a range of layers
+func NewPixelPrintable(printable Printable, pixel Pixel, layers LayerRange) (pp *PixelPrintable) {
+	pp = &PixelPrintable{
+		Printable: printable,
+		Pixel:     pixel,
+		Layers:    layers,
+	}
+
+	return
+}
+
+func (pp *PixelPrintable) LayerImage(index int) (ig *image.Gray) {
+	ig = pp.Printable.LayerImage(index)
+
+	if pp.Layers.Contains(pp.Printable, index) {
+		ig = pp.Pixel.Apply(ig)
+	}
+
+	return
+}
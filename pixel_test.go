@@ -0,0 +1,96 @@
+//
+// Copyright (c) 2020 Jason S. McMullan <jason.mcmullan@gmail.com>
+//
+
+package uv3dp
+
+import (
+	"image"
+
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestPixelApply(t *testing.T) {
+	in := image.NewGray(image.Rect(0, 0, 4, 1))
+	copy(in.Pix, []uint8{0x00, 0x40, 0xc0, 0xff})
+
+	table := []struct {
+		name  string
+		pixel Pixel
+		pix   []uint8
+	}{
+		{"set", Pixel{Operation: PixelSet, Value: 0x80}, []uint8{0x80, 0x80, 0x80, 0x80}},
+		{"and", Pixel{Operation: PixelAnd, Value: 0x80}, []uint8{0x00, 0x40, 0x80, 0x80}},
+		{"or", Pixel{Operation: PixelOr, Value: 0x80}, []uint8{0x80, 0x80, 0xc0, 0xff}},
+		{"xor", Pixel{Operation: PixelXor, Value: 0xff}, []uint8{0xff, 0xbf, 0x3f, 0x00}},
+		{"subtract", Pixel{Operation: PixelSubtract, Value: 0x80}, []uint8{0x00, 0x00, 0x40, 0x7f}},
+		{"invert", Pixel{Operation: PixelInvert}, []uint8{0xff, 0xbf, 0x3f, 0x00}},
+		{"gamma", Pixel{Operation: PixelGamma, Gamma: 2.0}, []uint8{0x00, 0x10, 0x91, 0xff}},
+		{"threshold", Pixel{Operation: PixelThreshold, Threshold: 0x40}, []uint8{0x00, 0x00, 0xff, 0xff}},
+	}
+
+	for _, item := range table {
+		out := item.pixel.Apply(in)
+		if !cmp.Equal(out.Pix, item.pix) {
+			t.Errorf("%v: expected %#v, got %#v", item.name, item.pix, out.Pix)
+		}
+	}
+
+	if !cmp.Equal(in.Pix, []uint8{0x00, 0x40, 0xc0, 0xff}) {
+		t.Errorf("input image was changed")
+	}
+}
+
+func TestPixelMask(t *testing.T) {
+	in := image.NewGray(image.Rect(0, 0, 4, 3))
+	for n := range in.Pix {
+		in.Pix[n] = 0x80
+	}
+
+	// A mask, partly off the bed
+	mask := image.NewGray(image.Rect(0, 0, 2, 2))
+	copy(mask.Pix, []uint8{0x00, 0xff, 0xff, 0x00})
+
+	pixel := Pixel{Operation: PixelOr, Mask: mask, Position: image.Pt(3, 1)}
+	out := pixel.Apply(in)
+
+	expected := []uint8{
+		0x80, 0x80, 0x80, 0x80,
+		0x80, 0x80, 0x80, 0x80,
+		0x80, 0x80, 0x80, 0xff,
+	}
+	if !cmp.Equal(out.Pix, expected) {
+		t.Errorf("expected %#v, got %#v", expected, out.Pix)
+	}
+}
+
+func TestParsePixelTable(t *testing.T) {
+	table, err := ParsePixelTable("0:255, 255:0")
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	for n, pix := range table {
+		if int(pix) != 0xff-n {
+			t.Fatalf("invert: %#x: expected %#x, got %#x", n, 0xff-n, pix)
+		}
+	}
+
+	table, err = ParsePixelTable("192:255,64:0")
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	for n, expected := range map[int]uint8{0: 0x00, 64: 0x00, 128: 0x80, 192: 0xff, 255: 0xff} {
+		if table[n] != expected {
+			t.Errorf("ramp: %#x: expected %#x, got %#x", n, expected, table[n])
+		}
+	}
+
+	for _, text := range []string{"", "12", "0:256", "-1:0", "5:0,5:1", "a:b"} {
+		_, err = ParsePixelTable(text)
+		if err == nil {
+			t.Errorf("%q: expected an error", text)
+		}
+	}
+}
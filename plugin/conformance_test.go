@@ -0,0 +1,71 @@
+//
+// Copyright (c) 2020 Jason S. McMullan <jason.mcmullan@gmail.com>
+//
+
+package plugin
+
+import (
+	"testing"
+
+	"github.com/spf13/pflag"
+
+	"github.com/ezrec/uv3dp"
+	"github.com/ezrec/uv3dp/uv3dptest"
+)
+
+// streamFormatter keeps all of a printable, by saving its layer stream
+type streamFormatter struct {
+	*pflag.FlagSet
+}
+
+func newStreamFormatter(suffix string) uv3dp.Formatter {
+	return &streamFormatter{FlagSet: pflag.NewFlagSet(suffix, pflag.ContinueOnError)}
+}
+
+func (sf *streamFormatter) Encode(writer uv3dp.Writer, printable uv3dp.Printable) (err error) {
+	err = writeStream(writer, printable, FramePNG)
+	return
+}
+
+func (sf *streamFormatter) Decode(reader uv3dp.Reader, filesize int64) (printable uv3dp.Printable, err error) {
+	printable, err = readStream(reader)
+	return
+}
+
+func TestConformance(t *testing.T) {
+	dir := t.TempDir()
+
+	newPluginFormatter := func(t *testing.T, name string) uv3dp.NewFormatter {
+		path := writeTestPlugin(t, dir, name)
+
+		desc, err := Describe(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return func(suffix string) uv3dp.Formatter { return NewFormatter(suffix, path, desc) }
+	}
+
+	// Through a plugin of a compiled in format
+	t.Run("uvj", func(t *testing.T) {
+		uv3dptest.RunFormatterConformance(t, newPluginFormatter(t, "tst"), uv3dptest.Capabilities{
+			Suffix:        ".tst",
+			LayerExposure: true,
+			LayerZ:        true,
+			Transition:    true,
+			Previews:      []uv3dp.PreviewType{uv3dp.PreviewTypeTiny, uv3dp.PreviewTypeHuge},
+		})
+	})
+
+	// Through a plugin that keeps all that the layer stream carries
+	t.Run("stream", func(t *testing.T) {
+		uv3dptest.RunFormatterConformance(t, newPluginFormatter(t, "tss"), uv3dptest.Capabilities{
+			Suffix:        ".tss",
+			LayerExposure: true,
+			LayerZ:        true,
+			Transition:    true,
+			Previews:      []uv3dp.PreviewType{uv3dp.PreviewTypeTiny, uv3dp.PreviewTypeHuge},
+			Metadata:      true,
+		})
+	})
+}
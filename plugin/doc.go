@@ -0,0 +1,50 @@
+//
+// Copyright (c) 2020 Jason S. McMullan <jason.mcmullan@gmail.com>
+//
+
+// Package plugin registers formats provided by external executables.
+//
+// A plugin is an executable named 'uv3dp-format-NAME', found in the
+// 'uv3dp/plugins' directory of the user's configuration directory, or on
+// the PATH. Formats compiled into uv3dp take priority over plugins.
+//
+// Plugins are run with one of three commands:
+//
+//	uv3dp-format-NAME describe
+//	uv3dp-format-NAME decode SUFFIX [OPTIONS...] FILE
+//	uv3dp-format-NAME encode SUFFIX [OPTIONS...] FILE
+//
+// 'describe' writes a JSON Description to stdout, listing the file
+// suffixes and the options of the format. Options are given to 'decode'
+// and 'encode' as '--name=value', and only when set by the user.
+//
+// 'decode' reads FILE, and writes a layer stream to stdout. 'encode'
+// reads a layer stream from stdin, and writes FILE. If FILE is '-', the
+// file is read from stdin, or written to stdout, instead.
+//
+// A layer stream is a line of JSON (a Header), followed by frames. Each
+// frame is a line of text, and for all but the 'end' frame, that many
+// bytes of data:
+//
+//	layer INDEX ENCODING LENGTH
+//	preview "NAME" png LENGTH
+//	end
+//
+// Metadata is sent in the Header as JSON, and is restored as the types
+// registered by the formats with uv3dp.RegisterMetadata. Metadata of
+// other keys is restored as generic JSON values (ie map[string]interface{}).
+//
+// Layers are in order, and ENCODING is 'png' (an 8-bit gray PNG), or
+// 'raw' (an 8-bit gray pixel per byte, row by row). Streams sent to a
+// plugin use the encoding requested in its Description. The Z of each
+// layer must be above the bed (greater than 0, so the first layer is at
+// its layer height, and not at 0), and above the Z of the prior layer.
+//
+// A plugin that fails writes a message to stderr, and exits with a
+// non-zero status. Plugins written in Go can use Serve to implement the
+// protocol for a uv3dp.Formatter.
+//
+// Plugins are added by calling Register, after all of the compiled in
+// formats have been registered. Their descriptions are cached, and a
+// plugin is only described again when its executable changes.
+package plugin
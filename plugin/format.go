@@ -0,0 +1,412 @@
+//
+// Copyright (c) 2020 Jason S. McMullan <jason.mcmullan@gmail.com>
+//
+
+package plugin
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/pflag"
+
+	"github.com/ezrec/uv3dp"
+)
+
+// Prefix is the start of the name of all plugin executables
+const Prefix = "uv3dp-format-"
+
+// optionValue is a plugin option, checked against its type
+type optionValue struct {
+	value string
+	kind  string
+}
+
+func (ov *optionValue) String() string {
+	return ov.value
+}
+
+func (ov *optionValue) Set(value string) (err error) {
+	switch ov.kind {
+	case "bool":
+		_, err = strconv.ParseBool(value)
+	case "int":
+		_, err = strconv.ParseInt(value, 0, 64)
+	case "float":
+		_, err = strconv.ParseFloat(value, 64)
+	}
+	if err != nil {
+		return
+	}
+
+	ov.value = value
+
+	return
+}
+
+func (ov *optionValue) Type() string {
+	return ov.kind
+}
+
+// Format is a format provided by a plugin executable
+type Format struct {
+	*pflag.FlagSet
+
+	suffix string
+	path   string
+	desc   *Description
+}
+
+// NewFormatter returns a formatter for a suffix of a plugin
+func NewFormatter(suffix string, path string, desc *Description) (pf *Format) {
+	flagSet := pflag.NewFlagSet(suffix, pflag.ContinueOnError)
+	flagSet.SetInterspersed(false)
+
+	pf = &Format{
+		FlagSet: flagSet,
+		suffix:  suffix,
+		path:    path,
+		desc:    desc,
+	}
+
+	for _, option := range desc.Options {
+		flag := pf.VarPF(&optionValue{value: option.Default, kind: option.Type}, option.Name, option.Shorthand, option.Usage)
+		flag.DefValue = option.Default
+		if option.Type == "bool" {
+			flag.NoOptDefVal = "true"
+		}
+	}
+
+	return
+}
+
+// run executes a plugin command
+func run(path string, args []string, stdin io.Reader, stdout io.Writer) (err error) {
+	stderr := &bytes.Buffer{}
+
+	cmd := exec.Command(path, args...)
+	cmd.Stdin = stdin
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	err = cmd.Run()
+	if err != nil {
+		message := strings.TrimSpace(stderr.String())
+		if len(message) > 0 {
+			err = fmt.Errorf("%v %v: %v (%v)", filepath.Base(path), args[0], message, err)
+		} else {
+			err = fmt.Errorf("%v %v: %w", filepath.Base(path), args[0], err)
+		}
+		return
+	}
+
+	return
+}
+
+// run executes a command of the plugin, on a FILE
+func (pf *Format) run(command string, file string, stdin io.Reader, stdout io.Writer) (err error) {
+	args := []string{command, pf.suffix}
+	pf.Visit(func(flag *pflag.Flag) {
+		args = append(args, fmt.Sprintf("--%s=%s", flag.Name, flag.Value.String()))
+	})
+	args = append(args, file)
+
+	err = run(pf.path, args, stdin, stdout)
+
+	return
+}
+
+// decode runs the plugin's 'decode' command, and reads its layer stream
+func (pf *Format) decode(file string, stdin io.Reader) (printable uv3dp.Printable, err error) {
+	stdout := &bytes.Buffer{}
+
+	err = pf.run("decode", file, stdin, stdout)
+	if err != nil {
+		return
+	}
+
+	printable, err = readStream(stdout)
+	if err != nil {
+		err = fmt.Errorf("%v decode: %w", filepath.Base(pf.path), err)
+		return
+	}
+
+	return
+}
+
+// encode runs the plugin's 'encode' command, and writes its layer stream
+func (pf *Format) encode(file string, stdout io.Writer, printable uv3dp.Printable) (err error) {
+	reader, writer := io.Pipe()
+
+	go func() {
+		writer.CloseWithError(writeStream(writer, printable, pf.desc.Frames))
+	}()
+
+	err = pf.run("encode", file, reader, stdout)
+
+	// Let the stream writer finish, if the plugin stopped reading early
+	reader.CloseWithError(io.ErrClosedPipe)
+
+	return
+}
+
+func (pf *Format) Decode(reader uv3dp.Reader, filesize int64) (printable uv3dp.Printable, err error) {
+	return pf.decode("-", io.NewSectionReader(reader, 0, filesize))
+}
+
+func (pf *Format) DecodeFile(filename string) (printable uv3dp.Printable, err error) {
+	return pf.decode(filename, nil)
+}
+
+func (pf *Format) Encode(writer uv3dp.Writer, printable uv3dp.Printable) (err error) {
+	return pf.encode("-", writer, printable)
+}
+
+func (pf *Format) EncodeFile(filename string, printable uv3dp.Printable) (err error) {
+	return pf.encode(filename, nil, printable)
+}
+
+// Describe runs the plugin's 'describe' command
+func Describe(path string) (desc *Description, err error) {
+	stdout := &bytes.Buffer{}
+
+	err = run(path, []string{"describe"}, nil, stdout)
+	if err != nil {
+		return
+	}
+
+	desc = &Description{}
+	err = json.Unmarshal(stdout.Bytes(), desc)
+	if err != nil {
+		err = fmt.Errorf("%v describe: %w", filepath.Base(path), err)
+		return
+	}
+
+	switch {
+	case desc.Protocol != Protocol:
+		err = fmt.Errorf("protocol %v is not supported", desc.Protocol)
+	case len(desc.Suffixes) == 0:
+		err = errors.New("no suffixes")
+	case desc.Frames != "" && desc.Frames != FramePNG && desc.Frames != FrameRaw:
+		err = fmt.Errorf("unknown frame encoding '%v'", desc.Frames)
+	}
+
+	for _, option := range desc.Options {
+		switch option.Type {
+		case "string", "bool", "int", "float":
+		default:
+			err = fmt.Errorf("option '%v': unknown type '%v'", option.Name, option.Type)
+		}
+	}
+
+	if err != nil {
+		err = fmt.Errorf("%v describe: %w", filepath.Base(path), err)
+		desc = nil
+		return
+	}
+
+	if desc.Frames == "" {
+		desc.Frames = FramePNG
+	}
+
+	return
+}
+
+// Dirs returns the directories searched for plugins, in order
+func Dirs() (dirs []string) {
+	config, err := os.UserConfigDir()
+	if err == nil {
+		dirs = append(dirs, filepath.Join(config, "uv3dp", "plugins"))
+	}
+
+	dirs = append(dirs, filepath.SplitList(os.Getenv("PATH"))...)
+
+	return
+}
+
+// Discover finds the plugin executables in a list of directories, by
+// plugin name. The first plugin found with a name is used.
+func Discover(dirs []string) (plugins map[string]string) {
+	plugins = map[string]string{}
+
+	for _, dir := range dirs {
+		if dir == "" {
+			continue
+		}
+
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+
+		for _, entry := range entries {
+			name := entry.Name()
+			if !strings.HasPrefix(name, Prefix) {
+				continue
+			}
+
+			name = strings.TrimSuffix(strings.TrimPrefix(name, Prefix), ".exe")
+			_, found := plugins[name]
+			if found || name == "" {
+				continue
+			}
+
+			path := filepath.Join(dir, entry.Name())
+			info, err := os.Stat(path)
+			if err != nil || !info.Mode().IsRegular() || info.Mode().Perm()&0111 == 0 {
+				continue
+			}
+
+			plugins[name] = path
+		}
+	}
+
+	return
+}
+
+// cachedDescription is the Description of a plugin executable, and the
+// size and modification time of the executable when it was described
+type cachedDescription struct {
+	ModTime     time.Time
+	Size        int64
+	Description *Description
+}
+
+// CacheFile returns the file of the cached plugin descriptions
+func CacheFile() (filename string) {
+	dir, err := os.UserCacheDir()
+	if err == nil {
+		filename = filepath.Join(dir, "uv3dp", "plugins.json")
+	}
+
+	return
+}
+
+// loadCache reads the cached plugin descriptions, by executable path
+func loadCache(filename string) (cache map[string]cachedDescription) {
+	cache = map[string]cachedDescription{}
+
+	if filename == "" {
+		return
+	}
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return
+	}
+
+	// A broken cache is described again
+	err = json.Unmarshal(data, &cache)
+	if err != nil {
+		cache = map[string]cachedDescription{}
+	}
+
+	return
+}
+
+// saveCache writes the cached plugin descriptions
+func saveCache(filename string, cache map[string]cachedDescription) (err error) {
+	data, err := json.MarshalIndent(cache, "", "  ")
+	if err != nil {
+		return
+	}
+
+	err = os.MkdirAll(filepath.Dir(filename), 0755)
+	if err != nil {
+		return
+	}
+
+	err = os.WriteFile(filename, data, 0644)
+
+	return
+}
+
+// Register adds the formats of the plugins in a list of directories.
+// Suffixes that are already registered are not replaced.
+//
+// Descriptions are kept in a cache file (see CacheFile, or "" for none),
+// and plugins are only described again when their executable changes.
+func Register(dirs []string, cacheFile string) (errs []error) {
+	registered := map[string]bool{}
+	for _, suffix := range uv3dp.FormatterSuffixes() {
+		registered[suffix] = true
+	}
+
+	plugins := Discover(dirs)
+
+	var names []string
+	for name := range plugins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	cache := loadCache(cacheFile)
+	changed := false
+	found := map[string]bool{}
+
+	for _, name := range names {
+		path := plugins[name]
+		found[path] = true
+
+		info, err := os.Stat(path)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		cached, ok := cache[path]
+		desc := cached.Description
+		if !ok || desc == nil || !cached.ModTime.Equal(info.ModTime()) || cached.Size != info.Size() {
+			desc, err = Describe(path)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+
+			cache[path] = cachedDescription{
+				ModTime:     info.ModTime(),
+				Size:        info.Size(),
+				Description: desc,
+			}
+			changed = true
+		}
+
+		for _, suffix := range desc.Suffixes {
+			if registered[suffix] {
+				continue
+			}
+			registered[suffix] = true
+
+			uv3dp.RegisterFormatter(suffix, func(suffix string) uv3dp.Formatter {
+				return NewFormatter(suffix, path, desc)
+			})
+		}
+	}
+
+	// Forget plugins that have gone
+	for path := range cache {
+		if !found[path] {
+			delete(cache, path)
+			changed = true
+		}
+	}
+
+	if changed && cacheFile != "" {
+		err := saveCache(cacheFile, cache)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("description cache: %w", err))
+		}
+	}
+
+	return
+}
@@ -0,0 +1,414 @@
+//
+// Copyright (c) 2020 Jason S. McMullan <jason.mcmullan@gmail.com>
+//
+
+package plugin
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"testing"
+
+	"github.com/ezrec/uv3dp"
+	"github.com/ezrec/uv3dp/uvj"
+)
+
+// The test binary is also the test plugin, when run by the plugin script
+const testPluginEnv = "UV3DP_PLUGIN_TEST"
+
+func newTestFormatter(suffix string) uv3dp.Formatter {
+	formatter := uvj.NewUVJFormatter(suffix)
+	formatter.StringP("comment", "c", "", "Comment (ignored)")
+	return formatter
+}
+
+func TestMain(m *testing.M) {
+	var err error
+
+	// The environment variable names the plugin to serve
+	switch os.Getenv(testPluginEnv) {
+	case "":
+		os.Exit(m.Run())
+	case "tss":
+		err = Serve(newStreamFormatter, ".tss")
+	default:
+		err = Serve(newTestFormatter, ".tst")
+	}
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+// testPlugin writes a plugin script, that runs the test binary
+func testPlugin(t *testing.T) (dir string, path string) {
+	dir = t.TempDir()
+	path = writeTestPlugin(t, dir, "tst")
+
+	return
+}
+
+// writeTestPlugin writes the script of a named test plugin
+func writeTestPlugin(t *testing.T, dir string, name string) (path string) {
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path = filepath.Join(dir, Prefix+name)
+	script := fmt.Sprintf("#!/bin/sh\n%s=%s exec '%s' \"$@\"\n", testPluginEnv, name, exe)
+
+	err = os.WriteFile(path, []byte(script), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return
+}
+
+func testPrintable(t *testing.T) (printable uv3dp.Printable) {
+	bottom := uv3dp.Exposure{LightOnTime: 20, LightPWM: 255}
+	normal := uv3dp.Exposure{LightOnTime: 4, LightPWM: 255}
+
+	b := uv3dp.NewBuilder(uv3dp.MachineSize{X: 8, Y: 6, Xmm: 0.8, Ymm: 0.6})
+	for n := 0; n < 5; n++ {
+		exposure := normal
+		if n == 0 {
+			exposure = bottom
+		}
+
+		gray := image.NewGray(b.Bounds())
+		gray.Pix[n*9] = 0xff
+		gray.Pix[47-n] = 0x80
+
+		err := b.AddLayer(float32(n+1)*0.05, exposure, gray)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tiny := image.NewRGBA(image.Rect(0, 0, 4, 3))
+	tiny.SetRGBA(1, 2, color.RGBA{R: 0x40, G: 0x80, B: 0xc0, A: 0xff})
+	b.SetPreview(uv3dp.PreviewTypeTiny, tiny)
+
+	printable, err := b.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return
+}
+
+func comparePrintable(t *testing.T, expected uv3dp.Printable, got uv3dp.Printable) {
+	if got.Size() != expected.Size() {
+		t.Fatalf("expected size %+v, got %+v", expected.Size(), got.Size())
+	}
+
+	if got.Exposure() != expected.Exposure() {
+		t.Errorf("expected exposure %+v, got %+v", expected.Exposure(), got.Exposure())
+	}
+
+	if got.Bottom() != expected.Bottom() {
+		t.Errorf("expected bottom %+v, got %+v", expected.Bottom(), got.Bottom())
+	}
+
+	for n := 0; n < expected.Size().Layers; n++ {
+		if got.LayerZ(n) != expected.LayerZ(n) {
+			t.Errorf("layer %v: expected Z %v, got %v", n, expected.LayerZ(n), got.LayerZ(n))
+		}
+
+		if got.LayerExposure(n) != expected.LayerExposure(n) {
+			t.Errorf("layer %v: expected exposure %+v, got %+v", n, expected.LayerExposure(n), got.LayerExposure(n))
+		}
+
+		if !bytes.Equal(got.LayerImage(n).Pix, expected.LayerImage(n).Pix) {
+			t.Errorf("layer %v: images differ", n)
+		}
+	}
+
+	tiny, ok := got.Preview(uv3dp.PreviewTypeTiny)
+	if !ok {
+		t.Fatalf("expected a tiny preview")
+	}
+
+	r, g, b, _ := tiny.At(1, 2).RGBA()
+	if r>>8 != 0x40 || g>>8 != 0x80 || b>>8 != 0xc0 {
+		t.Errorf("unexpected tiny preview color %v", tiny.At(1, 2))
+	}
+}
+
+func TestStream(t *testing.T) {
+	printable := testPrintable(t)
+
+	for _, frames := range []string{FramePNG, FrameRaw} {
+		buff := &bytes.Buffer{}
+		err := writeStream(buff, printable, frames)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !strings.Contains(buff.String(), "\nlayer 0 "+frames+" ") {
+			t.Errorf("%v: expected %v layer frames", frames, frames)
+		}
+
+		got, err := readStream(buff)
+		if err != nil {
+			t.Fatalf("%v: %v", frames, err)
+		}
+
+		comparePrintable(t, printable, got)
+	}
+
+	// Streams must be complete
+	buff := &bytes.Buffer{}
+	writeStream(buff, printable, FramePNG)
+	truncated := bytes.TrimSuffix(buff.Bytes(), []byte("end\n"))
+
+	_, err := readStream(bytes.NewReader(truncated))
+	if err == nil {
+		t.Errorf("expected an error from a stream without an end")
+	}
+
+	// Corrupt frames are an error of the stream
+	var index, length int
+	title := buff.Bytes()[bytes.Index(buff.Bytes(), []byte("\nlayer 0 "))+1:]
+	_, err = fmt.Sscanf(string(title), "layer %d png %d", &index, &length)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := title[bytes.IndexByte(title, '\n')+1:][:length]
+	copy(data[length/2:], bytes.Repeat([]byte{0xff}, length/2))
+
+	_, err = readStream(bytes.NewReader(buff.Bytes()))
+	if err == nil || !strings.Contains(err.Error(), "layer 0") {
+		t.Errorf("expected an error from a corrupt layer frame, got %v", err)
+	}
+}
+
+func TestStreamMetadata(t *testing.T) {
+	uv3dp.RegisterMetadata("plugin/typed", map[string]string{})
+
+	b := uv3dp.NewBuilder(uv3dp.MachineSize{X: 8, Y: 6, Xmm: 0.8, Ymm: 0.6})
+	b.SetMetadata("plugin/typed", map[string]string{"a": "1"})
+	b.SetMetadata("plugin/generic", map[string]string{"b": "2"})
+	b.AddLayer(0.05, uv3dp.Exposure{LightOnTime: 4, LightPWM: 255}, image.NewGray(b.Bounds()))
+
+	printable, err := b.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buff := &bytes.Buffer{}
+	err = writeStream(buff, printable, FramePNG)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := readStream(buff)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Registered types are kept
+	typed, _ := got.Metadata("plugin/typed")
+	mapped, ok := typed.(map[string]string)
+	if !ok || mapped["a"] != "1" {
+		t.Errorf("expected map[string]string metadata, got %#v", typed)
+	}
+
+	// Others are generic JSON values
+	generic, _ := got.Metadata("plugin/generic")
+	anon, ok := generic.(map[string]interface{})
+	if !ok || anon["b"] != "2" {
+		t.Errorf("expected map[string]interface{} metadata, got %#v", generic)
+	}
+}
+
+func TestDiscover(t *testing.T) {
+	dir, path := testPlugin(t)
+
+	// Not executable, and not a plugin
+	os.WriteFile(filepath.Join(dir, Prefix+"data"), []byte{}, 0644)
+	os.WriteFile(filepath.Join(dir, "uv3dp-other"), []byte{}, 0755)
+
+	// Plugins in earlier directories are used first
+	other := t.TempDir()
+	os.WriteFile(filepath.Join(other, Prefix+"tst"), []byte{}, 0755)
+
+	plugins := Discover([]string{dir, "", other})
+	if len(plugins) != 1 || plugins["tst"] != path {
+		t.Errorf("unexpected plugins %v", plugins)
+	}
+}
+
+func TestPlugin(t *testing.T) {
+	dir, path := testPlugin(t)
+
+	desc, err := Describe(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(desc.Suffixes) != 1 || desc.Suffixes[0] != ".tst" || desc.Frames != FramePNG {
+		t.Errorf("unexpected description %+v", desc)
+	}
+
+	if len(desc.Options) != 1 || desc.Options[0].Name != "comment" || desc.Options[0].Shorthand != "c" {
+		t.Errorf("unexpected options %+v", desc.Options)
+	}
+
+	// Options are checked, and passed to the plugin
+	pf := NewFormatter(".tst", path, desc)
+	err = pf.Parse([]string{"-c", "hello"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	printable := testPrintable(t)
+
+	// Through stdin and stdout
+	buff := &bytes.Buffer{}
+	err = pf.Encode(buff, printable)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := pf.Decode(bytes.NewReader(buff.Bytes()), int64(buff.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	comparePrintable(t, printable, got)
+
+	// Through a file
+	filename := filepath.Join(dir, "test.tst")
+	err = pf.EncodeFile(filename, printable)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err = pf.DecodeFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	comparePrintable(t, printable, got)
+
+	// Plugin errors are reported
+	_, err = pf.DecodeFile(filepath.Join(dir, "missing.tst"))
+	if err == nil || !strings.Contains(err.Error(), "missing.tst") {
+		t.Errorf("expected an error naming the missing file, got %v", err)
+	}
+
+	// Unknown options are not passed
+	err = NewFormatter(".tst", path, desc).Parse([]string{"--unknown"})
+	if err == nil {
+		t.Errorf("expected an error from an unknown option")
+	}
+}
+
+func TestRegister(t *testing.T) {
+	dir, _ := testPlugin(t)
+
+	errs := Register([]string{dir}, "")
+	if len(errs) != 0 {
+		t.Fatal(errs)
+	}
+
+	format, err := uv3dp.NewFormat(filepath.Join(dir, "test.tst"), []string{"--comment=test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	printable := testPrintable(t)
+	err = format.SetPrintable(printable)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := format.Printable()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	comparePrintable(t, printable, got)
+
+	// Plugins that fail to describe themselves are reported
+	broken := filepath.Join(t.TempDir())
+	os.WriteFile(filepath.Join(broken, Prefix+"broken"), []byte("#!/bin/sh\necho oops >&2\nexit 1\n"), 0755)
+
+	errs = Register([]string{broken}, "")
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "oops") {
+		t.Errorf("expected an error from the broken plugin, got %v", errs)
+	}
+}
+
+func TestRegisterCache(t *testing.T) {
+	dir, path := testPlugin(t)
+	cacheFile := filepath.Join(t.TempDir(), "uv3dp", "plugins.json")
+
+	errs := Register([]string{dir}, cacheFile)
+	if len(errs) != 0 {
+		t.Fatal(errs)
+	}
+
+	cache := loadCache(cacheFile)
+	cached, ok := cache[path]
+	if !ok || cached.Description == nil || cached.Description.Suffixes[0] != ".tst" {
+		t.Fatalf("expected a cached description of %v, got %+v", path, cache)
+	}
+
+	// Unchanged plugins are not described again
+	cached.Description = &Description{Protocol: Protocol, Suffixes: []string{".tstcached"}, Frames: FramePNG}
+	cache[path] = cached
+	err := saveCache(cacheFile, cache)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	errs = Register([]string{dir}, cacheFile)
+	if len(errs) != 0 {
+		t.Fatal(errs)
+	}
+
+	_, err = uv3dp.NewFormat(filepath.Join(dir, "test.tstcached"), nil)
+	if err != nil {
+		t.Errorf("expected the cached description to be used: %v", err)
+	}
+
+	// Changed plugins are described again
+	later := cached.ModTime.Add(time.Second)
+	err = os.Chtimes(path, later, later)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	errs = Register([]string{dir}, cacheFile)
+	if len(errs) != 0 {
+		t.Fatal(errs)
+	}
+
+	cached = loadCache(cacheFile)[path]
+	if cached.Description.Suffixes[0] != ".tst" || !cached.ModTime.Equal(later) {
+		t.Errorf("expected the plugin to be described again, got %+v", cached)
+	}
+
+	// Plugins that have gone are forgotten
+	errs = Register([]string{t.TempDir()}, cacheFile)
+	if len(errs) != 0 {
+		t.Fatal(errs)
+	}
+
+	if len(loadCache(cacheFile)) != 0 {
+		t.Errorf("expected the cache to be emptied")
+	}
+}
@@ -0,0 +1,374 @@
+//
+// Copyright (c) 2020 Jason S. McMullan <jason.mcmullan@gmail.com>
+//
+
+package plugin
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/png"
+	"io"
+	"strings"
+
+	"github.com/ezrec/uv3dp"
+)
+
+// Protocol is the version of the plugin protocol
+const Protocol = 1
+
+// Frame encodings of layer images
+const (
+	FramePNG = "png" // 8-bit gray PNG
+	FrameRaw = "raw" // 8-bit gray pixels, row by row
+)
+
+// Option describes an option of a plugin's format
+type Option struct {
+	Name      string
+	Shorthand string `json:",omitempty"`
+	Type      string // 'string', 'bool', 'int', or 'float'
+	Default   string `json:",omitempty"`
+	Usage     string `json:",omitempty"`
+}
+
+// Description is the output of a plugin's 'describe' command
+type Description struct {
+	Protocol int
+	Suffixes []string
+	Frames   string   `json:",omitempty"` // Layer encoding, 'png' (default) or 'raw'
+	Options  []Option `json:",omitempty"`
+}
+
+// HeaderLayer is the Z height and exposure of a layer
+type HeaderLayer struct {
+	Z        float32
+	Exposure uv3dp.Exposure
+}
+
+// Header is the first line of a layer stream
+type Header struct {
+	Properties uv3dp.Properties // Without previews
+	Layers     []HeaderLayer
+	Previews   []uv3dp.PreviewType `json:",omitempty"`
+}
+
+func encodeFrame(gray *image.Gray, frames string) (data []byte, err error) {
+	if frames == FrameRaw {
+		bounds := gray.Bounds()
+		data = make([]byte, 0, bounds.Dx()*bounds.Dy())
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			offset := gray.PixOffset(bounds.Min.X, y)
+			data = append(data, gray.Pix[offset:offset+bounds.Dx()]...)
+		}
+		return
+	}
+
+	buff := &bytes.Buffer{}
+	err = png.Encode(buff, gray)
+	if err != nil {
+		return
+	}
+
+	data = buff.Bytes()
+
+	return
+}
+
+func decodeFrame(data []byte, frames string, bounds image.Rectangle) (gray *image.Gray, err error) {
+	if frames == FrameRaw {
+		if len(data) != bounds.Dx()*bounds.Dy() {
+			err = fmt.Errorf("raw frame: expected %v bytes, got %v", bounds.Dx()*bounds.Dy(), len(data))
+			return
+		}
+		gray = &image.Gray{Pix: append([]uint8{}, data...), Stride: bounds.Dx(), Rect: bounds}
+		return
+	}
+
+	ig, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		return
+	}
+
+	if ig.Bounds() != bounds {
+		err = fmt.Errorf("png frame: expected bounds %v, got %v", bounds, ig.Bounds())
+		return
+	}
+
+	gray, ok := ig.(*image.Gray)
+	if !ok {
+		gray = image.NewGray(bounds)
+		draw.Draw(gray, bounds, ig, bounds.Min, draw.Src)
+	}
+
+	return
+}
+
+func writeFrame(writer io.Writer, title string, data []byte) (err error) {
+	_, err = fmt.Fprintf(writer, "%s %d\n", title, len(data))
+	if err != nil {
+		return
+	}
+
+	_, err = writer.Write(data)
+
+	return
+}
+
+// writeStream writes a printable as a layer stream
+func writeStream(writer io.Writer, printable uv3dp.Printable, frames string) (err error) {
+	size := printable.Size()
+
+	header := Header{
+		Properties: uv3dp.Properties{
+			Size:     size,
+			Exposure: printable.Exposure(),
+			Bottom:   printable.Bottom(),
+			Metadata: map[string]interface{}{},
+		},
+		Layers:   make([]HeaderLayer, size.Layers),
+		Previews: printable.PreviewKeys(),
+	}
+
+	for _, key := range printable.MetadataKeys() {
+		header.Properties.Metadata[key], _ = printable.Metadata(key)
+	}
+
+	for n := range header.Layers {
+		header.Layers[n] = HeaderLayer{
+			Z:        printable.LayerZ(n),
+			Exposure: printable.LayerExposure(n),
+		}
+	}
+
+	data, err := json.Marshal(&header)
+	if err != nil {
+		return
+	}
+
+	_, err = fmt.Fprintf(writer, "%s\n", data)
+	if err != nil {
+		return
+	}
+
+	type layerFrame struct {
+		data []byte
+		err  error
+	}
+
+	// Encode the frames in parallel, and write them in order
+	layerChan := make([](chan layerFrame), size.Layers)
+	for n := range layerChan {
+		layerChan[n] = make(chan layerFrame, 1)
+	}
+
+	go uv3dp.WithAllLayers(printable, func(p uv3dp.Printable, n int) {
+		data, err := encodeFrame(p.LayerImage(n), frames)
+		layerChan[n] <- layerFrame{data: data, err: err}
+		close(layerChan[n])
+	})
+
+	for n := range layerChan {
+		frame := <-layerChan[n]
+		if err != nil {
+			continue
+		}
+
+		err = frame.err
+		if err != nil {
+			continue
+		}
+
+		err = writeFrame(writer, fmt.Sprintf("layer %d %s", n, frames), frame.data)
+	}
+
+	if err != nil {
+		return
+	}
+
+	for _, key := range header.Previews {
+		ig, ok := printable.Preview(key)
+		if !ok {
+			continue
+		}
+
+		buff := &bytes.Buffer{}
+		err = png.Encode(buff, ig)
+		if err != nil {
+			return
+		}
+
+		err = writeFrame(writer, fmt.Sprintf("preview %q png", string(key)), buff.Bytes())
+		if err != nil {
+			return
+		}
+	}
+
+	_, err = fmt.Fprintf(writer, "end\n")
+
+	return
+}
+
+// readStream reads a printable from a layer stream
+func readStream(reader io.Reader) (printable uv3dp.Printable, err error) {
+	buffered := bufio.NewReader(reader)
+
+	line, err := buffered.ReadBytes('\n')
+	if err != nil {
+		err = fmt.Errorf("stream header: %w", err)
+		return
+	}
+
+	var header Header
+	err = json.Unmarshal(line, &header)
+	if err != nil {
+		err = fmt.Errorf("stream header: %w", err)
+		return
+	}
+
+	prop := &header.Properties
+	builder := uv3dp.NewBuilder(uv3dp.MachineSize{
+		X:   prop.Size.X,
+		Y:   prop.Size.Y,
+		Xmm: prop.Size.Millimeter.X,
+		Ymm: prop.Size.Millimeter.Y,
+	})
+	builder.SetExposure(prop.Exposure)
+	builder.SetBottom(prop.Bottom)
+	builder.SetLayerHeight(prop.Size.LayerHeight)
+
+	// Metadata values are restored as their registered types
+	var raw struct {
+		Properties struct {
+			Metadata map[string]json.RawMessage
+		}
+	}
+	err = json.Unmarshal(line, &raw)
+	if err != nil {
+		err = fmt.Errorf("stream header: %w", err)
+		return
+	}
+
+	for key, data := range raw.Properties.Metadata {
+		var value interface{}
+		value, err = uv3dp.UnmarshalMetadata(key, data)
+		if err != nil {
+			err = fmt.Errorf("stream metadata '%v': %w", key, err)
+			return
+		}
+		builder.SetMetadata(key, value)
+	}
+
+	bounds := builder.Bounds()
+
+	for {
+		line, err = buffered.ReadBytes('\n')
+		if err != nil {
+			err = fmt.Errorf("stream frame: %w", err)
+			return
+		}
+
+		title := strings.TrimSuffix(string(line), "\n")
+		if title == "end" {
+			break
+		}
+
+		var kind string
+		var length int
+		var data []byte
+
+		_, err = fmt.Sscan(title, &kind)
+		if err != nil {
+			err = fmt.Errorf("stream frame '%v': %w", title, err)
+			return
+		}
+
+		switch kind {
+		case "layer":
+			var index int
+			var frames string
+			_, err = fmt.Sscanf(title, "layer %d %s %d", &index, &frames, &length)
+			if err == nil && index != builder.Layers() {
+				err = fmt.Errorf("expected layer %v", builder.Layers())
+			}
+			if err == nil && index >= len(header.Layers) {
+				err = fmt.Errorf("only %v layers in the header", len(header.Layers))
+			}
+			if err == nil && frames != FramePNG && frames != FrameRaw {
+				err = fmt.Errorf("unknown encoding")
+			}
+			if err == nil && frames == FrameRaw && length != bounds.Dx()*bounds.Dy() {
+				err = fmt.Errorf("expected %v bytes", bounds.Dx()*bounds.Dy())
+			}
+			if err == nil {
+				data, err = readData(buffered, length)
+			}
+			if err == nil {
+				// Frames are checked now, so that a corrupt frame is an
+				// error of the decode, and not of a later use of the layer
+				_, err = decodeFrame(data, frames, bounds)
+			}
+			if err != nil {
+				err = fmt.Errorf("stream frame '%v': %w", title, err)
+				return
+			}
+
+			layer := header.Layers[index]
+			if layer.Exposure.LightPWM == 0 {
+				layer.Exposure.LightPWM = 255
+			}
+
+			err = builder.AddLayerFunc(layer.Z, layer.Exposure, func() *image.Gray {
+				// The frame was already checked, so can not fail
+				gray, _ := decodeFrame(data, frames, bounds)
+				return gray
+			})
+			if err != nil {
+				return
+			}
+		case "preview":
+			var name string
+			_, err = fmt.Sscanf(title, "preview %q png %d", &name, &length)
+			if err == nil {
+				data, err = readData(buffered, length)
+			}
+			var ig image.Image
+			if err == nil {
+				ig, err = png.Decode(bytes.NewReader(data))
+			}
+			if err != nil {
+				err = fmt.Errorf("stream frame '%v': %w", title, err)
+				return
+			}
+			builder.SetPreview(uv3dp.PreviewType(name), ig)
+		default:
+			err = fmt.Errorf("stream frame '%v': unknown frame", title)
+			return
+		}
+	}
+
+	if builder.Layers() != len(header.Layers) {
+		err = fmt.Errorf("stream: expected %v layers, got %v", len(header.Layers), builder.Layers())
+		return
+	}
+
+	printable, err = builder.Build()
+
+	return
+}
+
+func readData(reader io.Reader, length int) (data []byte, err error) {
+	if length < 0 {
+		err = fmt.Errorf("illegal length %v", length)
+		return
+	}
+
+	data = make([]byte, length)
+	_, err = io.ReadFull(reader, data)
+
+	return
+}
@@ -0,0 +1,182 @@
+//
+// Copyright (c) 2020 Jason S. McMullan <jason.mcmullan@gmail.com>
+//
+
+package plugin
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/pflag"
+
+	"github.com/ezrec/uv3dp"
+)
+
+// flagSetter is implemented by formatters that embed a *pflag.FlagSet
+type flagSetter interface {
+	VisitAll(fn func(*pflag.Flag))
+}
+
+// optionType maps a pflag type to a plugin option type
+func optionType(kind string) string {
+	switch kind {
+	case "bool":
+		return "bool"
+	case "int", "int8", "int16", "int32", "int64", "uint", "uint8", "uint16", "uint32", "uint64":
+		return "int"
+	case "float32", "float64":
+		return "float"
+	default:
+		return "string"
+	}
+}
+
+// describe returns the description of a formatter
+func describe(newFormatter uv3dp.NewFormatter, suffixes []string) (desc *Description) {
+	desc = &Description{
+		Protocol: Protocol,
+		Suffixes: suffixes,
+		Frames:   FramePNG,
+	}
+
+	flags, ok := newFormatter(suffixes[0]).(flagSetter)
+	if !ok {
+		return
+	}
+
+	flags.VisitAll(func(flag *pflag.Flag) {
+		desc.Options = append(desc.Options, Option{
+			Name:      flag.Name,
+			Shorthand: flag.Shorthand,
+			Type:      optionType(flag.Value.Type()),
+			Default:   flag.DefValue,
+			Usage:     flag.Usage,
+		})
+	})
+
+	return
+}
+
+// serve runs a plugin command
+func serve(newFormatter uv3dp.NewFormatter, suffixes []string, args []string, stdin io.Reader, stdout io.Writer) (err error) {
+	if len(suffixes) == 0 {
+		err = errors.New("no suffixes")
+		return
+	}
+
+	if len(args) == 1 && args[0] == "describe" {
+		err = json.NewEncoder(stdout).Encode(describe(newFormatter, suffixes))
+		return
+	}
+
+	if len(args) < 3 || (args[0] != "decode" && args[0] != "encode") {
+		err = errors.New("usage: describe | decode SUFFIX [OPTIONS...] FILE | encode SUFFIX [OPTIONS...] FILE")
+		return
+	}
+
+	command, suffix, options, file := args[0], args[1], args[2:len(args)-1], args[len(args)-1]
+
+	known := false
+	for _, item := range suffixes {
+		known = known || item == suffix
+	}
+	if !known {
+		err = fmt.Errorf("%v: suffix not supported", suffix)
+		return
+	}
+
+	formatter := newFormatter(suffix)
+	err = formatter.Parse(options)
+	if err != nil {
+		return
+	}
+
+	fileFormatter, isFile := formatter.(uv3dp.FileFormatter)
+
+	switch command {
+	case "decode":
+		var printable uv3dp.Printable
+		printable, err = decodeFile(formatter, file, stdin)
+		if err != nil {
+			return
+		}
+
+		writer := bufio.NewWriter(stdout)
+		err = writeStream(writer, printable, FramePNG)
+		if err != nil {
+			return
+		}
+		err = writer.Flush()
+	case "encode":
+		var printable uv3dp.Printable
+		printable, err = readStream(stdin)
+		if err != nil {
+			return
+		}
+
+		switch {
+		case file == "-":
+			err = formatter.Encode(stdout, printable)
+		case isFile:
+			err = fileFormatter.EncodeFile(file, printable)
+		default:
+			var writer *os.File
+			writer, err = os.Create(file)
+			if err != nil {
+				return
+			}
+			defer writer.Close()
+
+			err = formatter.Encode(writer, printable)
+		}
+	}
+
+	return
+}
+
+// decodeFile decodes a FILE, or stdin
+func decodeFile(formatter uv3dp.Formatter, file string, stdin io.Reader) (printable uv3dp.Printable, err error) {
+	fileFormatter, isFile := formatter.(uv3dp.FileFormatter)
+	if file != "-" && isFile {
+		printable, err = fileFormatter.DecodeFile(file)
+		return
+	}
+
+	// Printables may read their layers later, so read all of the file
+	var data []byte
+	if file == "-" {
+		data, err = io.ReadAll(stdin)
+	} else {
+		data, err = os.ReadFile(file)
+	}
+	if err != nil {
+		return
+	}
+
+	printable, err = formatter.Decode(bytes.NewReader(data), int64(len(data)))
+
+	return
+}
+
+// Serve implements the plugin protocol for a formatter, using the
+// process arguments, stdin, and stdout. It is the whole of the 'main'
+// function of a plugin written in Go:
+//
+//	func main() {
+//		err := plugin.Serve(NewXYZFormatter, ".xyz")
+//		if err != nil {
+//			fmt.Fprintln(os.Stderr, err)
+//			os.Exit(1)
+//		}
+//	}
+func Serve(newFormatter uv3dp.NewFormatter, suffixes ...string) (err error) {
+	err = serve(newFormatter, suffixes, os.Args[1:], os.Stdin, os.Stdout)
+
+	return
+}
@@ -0,0 +1,510 @@
+//
+// Copyright (c) 2020 Jason S. McMullan <jason.mcmullan@gmail.com>
+//
+
+package uv3dp
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"sort"
+	"sync"
+
+	"golang.org/x/image/draw"
+)
+
+// PreviewSizer is optionally implemented by a Formatter that requires
+// a specific set of previews, of specific sizes
+type PreviewSizer interface {
+	PreviewSizes() map[PreviewType]image.Point
+}
+
+// DefaultPreviewSize is the size of rendered previews, for formats
+// that do not expect a specific size (the ChiTu sizes)
+var DefaultPreviewSize = map[PreviewType]image.Point{
+	PreviewTypeTiny: {X: 200, Y: 125},
+	PreviewTypeHuge: {X: 400, Y: 300},
+}
+
+// PreviewView is the viewpoint of a rendered preview
+type PreviewView int
+
+const (
+	PreviewViewIsometric = PreviewView(iota) // From above, front and right
+	PreviewViewTop                           // From directly above
+)
+
+const (
+	previewMaxLayers = 256 // Maximum layers sampled for a preview
+	previewMargin    = 0.05
+)
+
+var (
+	previewBackground = color.RGBA{R: 0x20, G: 0x20, B: 0x28, A: 0xff}
+	previewModel      = [3]float64{0x60, 0xb0, 0xe0}
+	previewLight      = [3]float64{0.35, 0.55, 0.76} // Unit vector, towards the light
+)
+
+// previewHeights is the highest and lowest lit Z, in mm, of each pixel
+// of a printable's layers. Unlit pixels are NaN.
+type previewHeights struct {
+	width, height int
+	pitch         [2]float64 // Pixel size in mm
+	top, bottom   []float32
+	bounds        image.Rectangle // Lit pixels
+}
+
+func newPreviewHeights(p Printable) (ph *previewHeights) {
+	size := p.Size()
+
+	ph = &previewHeights{
+		width:  size.X,
+		height: size.Y,
+		pitch: [2]float64{
+			float64(size.Millimeter.X) / float64(size.X),
+			float64(size.Millimeter.Y) / float64(size.Y),
+		},
+		top:    make([]float32, size.X*size.Y),
+		bottom: make([]float32, size.X*size.Y),
+	}
+
+	nan := float32(math.NaN())
+	for n := range ph.top {
+		ph.top[n] = nan
+		ph.bottom[n] = nan
+	}
+
+	// Sample a limited number of layers, always including the top layer
+	step := (size.Layers + previewMaxLayers - 1) / previewMaxLayers
+	if step < 1 {
+		step = 1
+	}
+	sampled := func(n int) bool {
+		return n%step == 0 || n == size.Layers-1
+	}
+
+	var mutex sync.Mutex
+	WithAllLayers(p, func(p Printable, n int) {
+		if !sampled(n) {
+			return
+		}
+
+		gray := p.LayerImage(n)
+		z := p.LayerZ(n)
+
+		bounds := gray.Bounds()
+		mutex.Lock()
+		for y := 0; y < ph.height && y < bounds.Dy(); y++ {
+			row := gray.Pix[gray.PixOffset(bounds.Min.X, bounds.Min.Y+y):]
+			lit := image.Rectangle{Min: image.Pt(ph.width, y), Max: image.Pt(0, y+1)}
+			for x := 0; x < ph.width && x < bounds.Dx(); x++ {
+				if row[x] < 0x80 {
+					continue
+				}
+				if x < lit.Min.X {
+					lit.Min.X = x
+				}
+				lit.Max.X = x + 1
+				offset := y*ph.width + x
+				top := ph.top[offset]
+				if top != top || z > top {
+					ph.top[offset] = z
+				}
+				bottom := ph.bottom[offset]
+				if bottom != bottom || z < bottom {
+					ph.bottom[offset] = z
+				}
+			}
+			if !lit.Empty() {
+				ph.bounds = ph.bounds.Union(lit)
+			}
+		}
+		mutex.Unlock()
+	})
+
+	return
+}
+
+// previewCells are blocks of pixels, with the highest top and lowest bottom
+type previewCells struct {
+	width, height int
+	pitch         [2]float64 // Cell size in mm
+	origin        [2]float64 // Center of the cell grid, in mm
+	top, bottom   []float64
+}
+
+func (ph *previewHeights) cells(block int) (pc *previewCells) {
+	bounds := ph.bounds
+	pc = &previewCells{
+		width:  (bounds.Dx() + block - 1) / block,
+		height: (bounds.Dy() + block - 1) / block,
+		pitch:  [2]float64{ph.pitch[0] * float64(block), ph.pitch[1] * float64(block)},
+	}
+
+	pc.origin = [2]float64{
+		pc.pitch[0] * float64(pc.width) / 2,
+		pc.pitch[1] * float64(pc.height) / 2,
+	}
+
+	pc.top = make([]float64, pc.width*pc.height)
+	pc.bottom = make([]float64, pc.width*pc.height)
+	for n := range pc.top {
+		pc.top[n] = math.NaN()
+		pc.bottom[n] = math.NaN()
+	}
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			top := float64(ph.top[y*ph.width+x])
+			if math.IsNaN(top) {
+				continue
+			}
+			bottom := float64(ph.bottom[y*ph.width+x])
+			offset := ((y-bounds.Min.Y)/block)*pc.width + (x-bounds.Min.X)/block
+			if math.IsNaN(pc.top[offset]) || top > pc.top[offset] {
+				pc.top[offset] = top
+			}
+			if math.IsNaN(pc.bottom[offset]) || bottom < pc.bottom[offset] {
+				pc.bottom[offset] = bottom
+			}
+		}
+	}
+
+	return
+}
+
+// Height of the top of a cell, or NaN outside of the model
+func (pc *previewCells) at(x, y int) float64 {
+	if x < 0 || y < 0 || x >= pc.width || y >= pc.height {
+		return math.NaN()
+	}
+
+	return pc.top[y*pc.width+x]
+}
+
+// previewCanvas is a depth buffered image
+type previewCanvas struct {
+	*image.RGBA
+	depth []float64
+}
+
+func newPreviewCanvas(size image.Point) (pv *previewCanvas) {
+	pv = &previewCanvas{
+		RGBA:  image.NewRGBA(image.Rectangle{Max: size}),
+		depth: make([]float64, size.X*size.Y),
+	}
+
+	for n := range pv.depth {
+		pv.depth[n] = math.Inf(-1)
+	}
+
+	for n := 0; n < len(pv.Pix); n += 4 {
+		pv.Pix[n+0] = previewBackground.R
+		pv.Pix[n+1] = previewBackground.G
+		pv.Pix[n+2] = previewBackground.B
+		pv.Pix[n+3] = previewBackground.A
+	}
+
+	return
+}
+
+// Plot a square splat, if it is nearer than what is already drawn
+func (pv *previewCanvas) splat(sx, sy float64, radius float64, depth float64, c color.RGBA) {
+	x0 := int(math.Floor(sx - radius + 0.5))
+	y0 := int(math.Floor(sy - radius + 0.5))
+	x1 := int(math.Floor(sx + radius + 0.5))
+	y1 := int(math.Floor(sy + radius + 0.5))
+
+	size := pv.Rect.Size()
+	for y := y0; y < y1; y++ {
+		for x := x0; x < x1; x++ {
+			if x < 0 || y < 0 || x >= size.X || y >= size.Y {
+				continue
+			}
+			offset := y*size.X + x
+			if depth < pv.depth[offset] {
+				continue
+			}
+			pv.depth[offset] = depth
+			pv.SetRGBA(x, y, c)
+		}
+	}
+}
+
+// Lambertian shading of the model color
+func previewShade(normal [3]float64) color.RGBA {
+	length := math.Sqrt(normal[0]*normal[0] + normal[1]*normal[1] + normal[2]*normal[2])
+	light := 0.0
+	for n := range normal {
+		light += normal[n] / length * previewLight[n]
+	}
+
+	intensity := 0.3 + 0.7*math.Max(0, light)
+
+	return color.RGBA{
+		R: uint8(math.Min(255, previewModel[0]*intensity)),
+		G: uint8(math.Min(255, previewModel[1]*intensity)),
+		B: uint8(math.Min(255, previewModel[2]*intensity)),
+		A: 0xff,
+	}
+}
+
+// Render the heights as seen from the view
+func (ph *previewHeights) render(size image.Point, view PreviewView) (preview *image.RGBA) {
+	pv := newPreviewCanvas(size)
+	preview = pv.RGBA
+
+	if ph.bounds.Empty() || size.X < 1 || size.Y < 1 {
+		return
+	}
+
+	// Model extent, in mm
+	extent := [3]float64{
+		float64(ph.bounds.Dx()) * ph.pitch[0],
+		float64(ph.bounds.Dy()) * ph.pitch[1],
+		0,
+	}
+
+	for y := ph.bounds.Min.Y; y < ph.bounds.Max.Y; y++ {
+		for x := ph.bounds.Min.X; x < ph.bounds.Max.X; x++ {
+			top := float64(ph.top[y*ph.width+x])
+			if top > extent[2] {
+				extent[2] = top
+			}
+		}
+	}
+
+	// Projection of a point in mm, relative to the model center, to the
+	// screen, with a depth that is greater for nearer points.
+	const cos30 = 0.8660254037844386
+	var project func(x, y, z float64) (sx, sy, depth float64)
+	var span [2]float64
+	switch view {
+	case PreviewViewTop:
+		project = func(x, y, z float64) (float64, float64, float64) {
+			return x, y, z
+		}
+		span = [2]float64{extent[0], extent[1]}
+	default:
+		project = func(x, y, z float64) (float64, float64, float64) {
+			return (x - y) * cos30, (x+y)/2 - z + extent[2]/2, x + y + z
+		}
+		span = [2]float64{(extent[0] + extent[1]) * cos30, (extent[0]+extent[1])/2 + extent[2]}
+	}
+
+	// Screen pixels per mm, to fit the model within the margins
+	fit := 1 - previewMargin*2
+	scale := math.Min(float64(size.X)*fit/span[0], float64(size.Y)*fit/span[1])
+
+	// Cells no larger than a screen pixel
+	pitch := math.Max(ph.pitch[0], ph.pitch[1])
+	block := int(math.Max(1, math.Floor(0.75/(pitch*scale))))
+	pc := ph.cells(block)
+
+	// Splats cover the projection of a cell, and at least a pixel
+	radius := math.Max(1, math.Max(pc.pitch[0], pc.pitch[1])*scale*cos30)
+
+	center := [2]float64{float64(size.X) / 2, float64(size.Y) / 2}
+	plot := func(x, y, z float64, c color.RGBA) {
+		sx, sy, depth := project(x, y, z)
+		pv.splat(center[0]+sx*scale, center[1]+sy*scale, radius, depth, c)
+	}
+
+	// Side walls are drawn with a splat every half screen pixel
+	step := 0.5 / scale
+
+	for cy := 0; cy < pc.height; cy++ {
+		for cx := 0; cx < pc.width; cx++ {
+			top := pc.at(cx, cy)
+			if math.IsNaN(top) {
+				continue
+			}
+
+			x := (float64(cx)+0.5)*pc.pitch[0] - pc.origin[0]
+			y := (float64(cy)+0.5)*pc.pitch[1] - pc.origin[1]
+
+			// Top surface normal, from the neighboring heights
+			slope := func(a, b float64, pitch float64) float64 {
+				if math.IsNaN(a) {
+					a = top
+				}
+				if math.IsNaN(b) {
+					b = top
+				}
+				return (a - b) / (2 * pitch)
+			}
+
+			normal := [3]float64{
+				slope(pc.at(cx-1, cy), pc.at(cx+1, cy), pc.pitch[0]),
+				slope(pc.at(cx, cy-1), pc.at(cx, cy+1), pc.pitch[1]),
+				1,
+			}
+
+			plot(x, y, top, previewShade(normal))
+
+			if view == PreviewViewTop {
+				continue
+			}
+
+			// Walls facing the right, and the front
+			bottom := pc.bottom[cy*pc.width+cx]
+			walls := []struct {
+				Neighbor float64
+				Normal   [3]float64
+				Offset   [2]float64
+			}{
+				{pc.at(cx+1, cy), [3]float64{1, 0, 0}, [2]float64{pc.pitch[0] / 2, 0}},
+				{pc.at(cx, cy+1), [3]float64{0, 1, 0}, [2]float64{0, pc.pitch[1] / 2}},
+			}
+
+			for _, wall := range walls {
+				low := bottom
+				if !math.IsNaN(wall.Neighbor) && wall.Neighbor > low {
+					low = wall.Neighbor
+				}
+
+				shade := previewShade(wall.Normal)
+				for z := top - step; z > low-step; z -= step {
+					plot(x+wall.Offset[0], y+wall.Offset[1], math.Max(z, low), shade)
+				}
+			}
+		}
+	}
+
+	return
+}
+
+// RenderPreview renders a shaded preview of the layers of a printable
+func RenderPreview(p Printable, size image.Point, view PreviewView) (preview *image.RGBA) {
+	preview = newPreviewHeights(p).render(size, view)
+
+	return
+}
+
+// LetterboxPreview scales an image to fit a size, keeping its aspect
+// ratio, and centers it between black bars
+func LetterboxPreview(ig image.Image, size image.Point) (preview *image.RGBA) {
+	preview = image.NewRGBA(image.Rectangle{Max: size})
+	draw.Draw(preview, preview.Bounds(), image.NewUniform(color.Black), image.Point{}, draw.Src)
+
+	src := ig.Bounds()
+	if src.Empty() || size.X < 1 || size.Y < 1 {
+		return
+	}
+
+	scale := math.Min(float64(size.X)/float64(src.Dx()), float64(size.Y)/float64(src.Dy()))
+	fit := image.Pt(
+		int(math.Max(1, math.Round(float64(src.Dx())*scale))),
+		int(math.Max(1, math.Round(float64(src.Dy())*scale))),
+	)
+
+	dst := image.Rectangle{Max: fit}.Add(size.Sub(fit).Div(2))
+	draw.CatmullRom.Scale(preview, dst, ig, src, draw.Src, nil)
+
+	return
+}
+
+// PreviewPrintable renders any missing previews of a printable, and fits
+// existing previews to the sizes required by a format
+type PreviewPrintable struct {
+	Printable
+
+	View  PreviewView
+	Sizes map[PreviewType]image.Point // Sizes of the previews
+	Fit   bool                        // Fit existing previews to their sizes
+
+	once     sync.Once
+	heights  *previewHeights
+	mutex    sync.Mutex
+	rendered map[PreviewType]image.Image
+}
+
+// NewPreviewPrintable provides the previews required by a formatter
+func NewPreviewPrintable(p Printable, formatter Formatter) (pp *PreviewPrintable) {
+	pp = &PreviewPrintable{
+		Printable: p,
+		View:      PreviewViewIsometric,
+		Sizes:     DefaultPreviewSize,
+		rendered:  map[PreviewType]image.Image{},
+	}
+
+	sizer, ok := formatter.(PreviewSizer)
+	if ok {
+		pp.Sizes = sizer.PreviewSizes()
+		pp.Fit = true
+	}
+
+	return
+}
+
+func (pp *PreviewPrintable) PreviewKeys() (keys []PreviewType) {
+	keys = pp.Printable.PreviewKeys()
+
+	for key := range pp.Sizes {
+		_, ok := pp.Printable.Preview(key)
+		if !ok {
+			keys = append(keys, key)
+		}
+	}
+
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+
+	return
+}
+
+func (pp *PreviewPrintable) Preview(index PreviewType) (ig image.Image, ok bool) {
+	ig, ok = pp.Printable.Preview(index)
+	size, sized := pp.Sizes[index]
+	if ok && (!pp.Fit || !sized || ig.Bounds().Size() == size) {
+		return
+	}
+
+	if !sized {
+		return
+	}
+
+	// All previews are rendered from the same layer heights
+	if !ok {
+		pp.once.Do(func() {
+			pp.heights = newPreviewHeights(pp.Printable)
+		})
+	}
+
+	pp.mutex.Lock()
+	defer pp.mutex.Unlock()
+
+	cached, found := pp.rendered[index]
+	switch {
+	case found:
+		ig = cached
+	case ok:
+		ig = LetterboxPreview(ig, size)
+	default:
+		ig = pp.heights.render(size, pp.View)
+	}
+
+	pp.rendered[index] = ig
+	ok = true
+
+	return
+}
+
+// stalePrintable has no previews
+type stalePrintable struct {
+	Printable
+}
+
+func (sp *stalePrintable) Preview(index PreviewType) (ig image.Image, ok bool) {
+	return
+}
+
+func (sp *stalePrintable) PreviewKeys() (keys []PreviewType) {
+	return
+}
+
+// StalePreviews removes the previews of a printable whose geometry has
+// been changed, so that they are rendered again when saved
+func StalePreviews(p Printable) Printable {
+	return &stalePrintable{Printable: p}
+}
@@ -0,0 +1,191 @@
+//
+// Copyright (c) 2020 Jason S. McMullan <jason.mcmullan@gmail.com>
+//
+
+package uv3dp
+
+import (
+	"image"
+	"image/color"
+
+	"testing"
+)
+
+// 20x20 pixel block, in the middle of a 40x40 pixel, 4x4mm bed
+func newBlockPrint(layers int) (pp *patternPrint) {
+	gray := image.NewGray(image.Rect(0, 0, 40, 40))
+	for y := 10; y < 30; y++ {
+		for x := 10; x < 30; x++ {
+			gray.Pix[y*gray.Stride+x] = 0xff
+		}
+	}
+
+	pp = &patternPrint{
+		Print: Print{
+			Properties: Properties{
+				Size: Size{
+					X: 40, Y: 40,
+					Millimeter:  SizeMillimeter{X: 4.0, Y: 4.0},
+					Layers:      layers,
+					LayerHeight: 0.1,
+				},
+			},
+		},
+		Gray: gray,
+	}
+
+	return
+}
+
+func countColor(preview *image.RGBA, c color.RGBA) (count int) {
+	bounds := preview.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			if preview.RGBAAt(x, y) == c {
+				count++
+			}
+		}
+	}
+
+	return
+}
+
+func TestRenderPreview(t *testing.T) {
+	size := image.Pt(100, 80)
+
+	top := previewShade([3]float64{0, 0, 1})
+	right := previewShade([3]float64{1, 0, 0})
+	front := previewShade([3]float64{0, 1, 0})
+
+	// Top view is the face of the block, fit to the preview
+	preview := RenderPreview(newBlockPrint(20), size, PreviewViewTop)
+	if preview.Bounds().Size() != size {
+		t.Fatalf("expected size %v, got %v", size, preview.Bounds().Size())
+	}
+
+	if preview.RGBAAt(50, 40) != top {
+		t.Errorf("top: expected the block in the center, got %v", preview.RGBAAt(50, 40))
+	}
+
+	if preview.RGBAAt(2, 40) != previewBackground || preview.RGBAAt(50, 2) != previewBackground {
+		t.Errorf("top: expected the background at the edges")
+	}
+
+	if countColor(preview, right) != 0 || countColor(preview, front) != 0 {
+		t.Errorf("top: expected no walls to be visible")
+	}
+
+	// Isometric view shows the top, and two of the walls
+	preview = RenderPreview(newBlockPrint(20), size, PreviewViewIsometric)
+	for name, c := range map[string]color.RGBA{"top": top, "right": right, "front": front} {
+		if countColor(preview, c) < 50 {
+			t.Errorf("isometric: expected the %v face to be visible", name)
+		}
+	}
+
+	// Taller blocks have more wall, relative to the top
+	tall := RenderPreview(newBlockPrint(80), size, PreviewViewIsometric)
+	if countColor(tall, right)*countColor(preview, top) <= countColor(preview, right)*countColor(tall, top) {
+		t.Errorf("isometric: expected a taller block to have larger walls")
+	}
+
+	// Nothing lit, nothing shown
+	preview = RenderPreview(newBlockPrint(0), size, PreviewViewIsometric)
+	if countColor(preview, previewBackground) != size.X*size.Y {
+		t.Errorf("empty: expected only the background")
+	}
+}
+
+type sizedFormatter struct {
+	Formatter
+}
+
+func (sf *sizedFormatter) PreviewSizes() map[PreviewType]image.Point {
+	return map[PreviewType]image.Point{
+		PreviewTypeTiny:       {X: 8, Y: 6},
+		PreviewType("medium"): {X: 64, Y: 48},
+	}
+}
+
+func TestPreviewPrintable(t *testing.T) {
+	block := newBlockPrint(10)
+	tiny := image.NewRGBA(image.Rect(0, 0, 4, 3))
+	huge := image.NewRGBA(image.Rect(0, 0, 40, 30))
+	block.Properties.Preview = map[PreviewType]image.Image{
+		PreviewTypeTiny: tiny,
+		PreviewTypeHuge: huge,
+	}
+
+	// Without required sizes, existing previews are kept
+	pp := NewPreviewPrintable(block, nil)
+	ig, ok := pp.Preview(PreviewTypeTiny)
+	if !ok || ig != image.Image(tiny) {
+		t.Errorf("expected the existing tiny preview")
+	}
+
+	// With required sizes, existing previews are fit to them
+	pp = NewPreviewPrintable(block, &sizedFormatter{})
+	ig, ok = pp.Preview(PreviewTypeTiny)
+	if !ok || ig.Bounds().Size() != image.Pt(8, 6) {
+		t.Errorf("expected the tiny preview to be fit to 8x6")
+	}
+
+	// Previews without a required size are kept
+	ig, ok = pp.Preview(PreviewTypeHuge)
+	if !ok || ig != image.Image(huge) {
+		t.Errorf("expected the existing huge preview")
+	}
+
+	// Missing previews are rendered at the required size
+	ig, ok = pp.Preview("medium")
+	if !ok || ig.Bounds().Size() != image.Pt(64, 48) {
+		t.Fatalf("expected a rendered 64x48 medium preview")
+	}
+
+	again, _ := pp.Preview("medium")
+	if again != ig {
+		t.Errorf("expected the rendered preview to be reused")
+	}
+
+	keys := pp.PreviewKeys()
+	if len(keys) != 3 || keys[0] != PreviewTypeHuge || keys[1] != "medium" || keys[2] != PreviewTypeTiny {
+		t.Errorf("unexpected preview keys %v", keys)
+	}
+
+	// Stale previews are rendered again, at the default size
+	pp = NewPreviewPrintable(StalePreviews(block), nil)
+	ig, ok = pp.Preview(PreviewTypeTiny)
+	if !ok || ig.Bounds().Size() != DefaultPreviewSize[PreviewTypeTiny] {
+		t.Errorf("expected a rendered tiny preview at the default size")
+	}
+}
+
+func TestLetterboxPreview(t *testing.T) {
+	// White 4x2 image into an 8x8 preview has bars above and below
+	white := image.NewRGBA(image.Rect(0, 0, 4, 2))
+	for n := range white.Pix {
+		white.Pix[n] = 0xff
+	}
+
+	preview := LetterboxPreview(white, image.Pt(8, 8))
+	if preview.Bounds().Size() != image.Pt(8, 8) {
+		t.Fatalf("unexpected size %v", preview.Bounds().Size())
+	}
+
+	black := color.RGBA{A: 0xff}
+	for _, item := range []struct {
+		X, Y  int
+		Color color.RGBA
+	}{
+		{4, 0, black},
+		{4, 1, black},
+		{0, 2, color.RGBA{0xff, 0xff, 0xff, 0xff}},
+		{7, 5, color.RGBA{0xff, 0xff, 0xff, 0xff}},
+		{4, 6, black},
+		{4, 7, black},
+	} {
+		if got := preview.RGBAAt(item.X, item.Y); got != item.Color {
+			t.Errorf("(%v,%v): expected %v, got %v", item.X, item.Y, item.Color, got)
+		}
+	}
+}
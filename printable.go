@@ -16,6 +16,7 @@ type Printable interface {
 	Exposure() Exposure
 	Bottom() Bottom
 	Preview(index PreviewType) (image.Image, bool)
+	PreviewKeys() []PreviewType
 	MetadataKeys() []string
 	Metadata(key string) (data interface{}, ok bool)
 	LayerZ(index int) float32
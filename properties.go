@@ -7,6 +7,7 @@ package uv3dp
 import (
 	"image"
 	"math"
+	"sort"
 	"time"
 )
 
@@ -74,11 +75,38 @@ type Bottom struct {
 	Transition int // Number of transition layers above the bottom layer
 }
 
-type PreviewType uint
+// DefaultExposure is the exposure of printables that have none of their
+// own, such as those sliced from a model
+var DefaultExposure = Exposure{
+	LightOnTime:  8.0,
+	LightOffTime: 1.0,
+	LightPWM:     255,
+	LiftHeight:   5.0,
+	LiftSpeed:    60.0,
+	RetractSpeed: 150.0,
+}
+
+// DefaultBottom is the bottom layer exposure of printables that have none
+// of their own
+var DefaultBottom = Bottom{
+	Count: 3,
+	Exposure: Exposure{
+		LightOnTime:  60.0,
+		LightOffTime: 1.0,
+		LightPWM:     255,
+		LiftHeight:   5.0,
+		LiftSpeed:    60.0,
+		RetractSpeed: 150.0,
+	},
+}
+
+// PreviewType is the name of a preview image. Formats may use any name,
+// but the common previews are the tiny and huge ones.
+type PreviewType string
 
 const (
-	PreviewTypeTiny = PreviewType(iota)
-	PreviewTypeHuge
+	PreviewTypeTiny = PreviewType("tiny")
+	PreviewTypeHuge = PreviewType("huge")
 )
 
 type Properties struct {
@@ -112,6 +140,17 @@ func (prop *Properties) MetadataKeys() (keys []string) {
 	return
 }
 
+// PreviewKeys returns the names of the previews, in order
+func (prop *Properties) PreviewKeys() (keys []PreviewType) {
+	for key := range prop.Preview {
+		keys = append(keys, key)
+	}
+
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+
+	return
+}
+
 // Get image bounds
 func (prop *Properties) Bounds() image.Rectangle {
 	return image.Rect(0, 0, prop.Size.X, prop.Size.Y)
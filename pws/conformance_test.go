@@ -0,0 +1,56 @@
+//
+// Copyright (c) 2020 Jason S. McMullan <jason.mcmullan@gmail.com>
+//
+
+package pws
+
+import (
+	"fmt"
+
+	"testing"
+
+	"github.com/ezrec/uv3dp"
+	"github.com/ezrec/uv3dp/uv3dptest"
+)
+
+func TestConformance(t *testing.T) {
+	newFormatter := func(suffix string) uv3dp.Formatter { return NewFormatter(suffix) }
+
+	// Retract height is not saved
+	fixup := func(kind uv3dptest.ExposureKind, exposure *uv3dp.Exposure) {
+		exposure.RetractHeight = 0
+
+		switch kind {
+		case uv3dptest.ExposureBottom:
+			// The bottom layers use the normal lift, unless overridden
+			exposure.LiftHeight = 0
+			exposure.LiftSpeed = 0
+		case uv3dptest.ExposureLayer:
+			// Layers only override the light on time, and the lift
+			exposure.LightOffTime = 0
+			exposure.RetractSpeed = 0
+		}
+	}
+
+	for _, item := range []struct {
+		Suffix     string
+		Args       []string
+		GrayLevels int
+	}{
+		{".pws", nil, 2},
+		{".pws", []string{"--anti-alias", "8"}, 8},
+		{".pw0", nil, 2},
+		{".pw0", []string{"--anti-alias", "4"}, 4},
+	} {
+		t.Run(fmt.Sprintf("%v%v", item.Suffix, item.Args), func(t *testing.T) {
+			uv3dptest.RunFormatterConformance(t, newFormatter, uv3dptest.Capabilities{
+				Suffix:        item.Suffix,
+				Args:          item.Args,
+				GrayLevels:    item.GrayLevels,
+				LayerExposure: true,
+				Previews:      []uv3dp.PreviewType{uv3dp.PreviewTypeTiny},
+				Fixup:         fixup,
+			})
+		})
+	}
+}
@@ -237,11 +237,15 @@ func (slice *Slice) SetImage(gray *image.Gray) (err error) {
 	var data []byte
 	switch slice.Format {
 	case SliceFormatPWS:
+		// Each anti-alias bit plane adds a gray level
+		gray = uv3dp.QuantizeLayer(gray, slice.AntiAlias+1)
 		for level := 0; level < slice.AntiAlias; level++ {
 			rle, _, _ := rle1EncodeBitmap(gray, level, slice.AntiAlias)
 			data = append(data, rle...)
 		}
 	case SliceFormatPW0:
+		// 4-bit gray
+		gray = uv3dp.QuantizeLayer(gray, 16)
 		data, err = rle4EncodeBitmaps(gray, slice.AntiAlias)
 		if err != nil {
 			return
@@ -317,6 +321,13 @@ func NewFormatter(suffix string) (sf *Format) {
 	return
 }
 
+// PreviewSizes returns the size of the (only) preview
+func (sf *Format) PreviewSizes() map[uv3dp.PreviewType]image.Point {
+	return map[uv3dp.PreviewType]image.Point{
+		uv3dp.PreviewTypeTiny: {X: defaultPreviewWidth, Y: defaultPreviewHeight},
+	}
+}
+
 func (sf *Format) Encode(writer uv3dp.Writer, printable uv3dp.Printable) (err error) {
 	size := printable.Size()
 	exposure := printable.Exposure()
@@ -358,7 +369,7 @@ func (sf *Format) Encode(writer uv3dp.Writer, printable uv3dp.Printable) (err er
 		exposure := p.LayerExposure(n)
 		l := Layer{
 			LiftHeight:  exposure.LiftHeight,
-			LiftSpeed:   exposure.LiftSpeed,
+			LiftSpeed:   exposure.LiftSpeed / 60.0,
 			LightOnTime: exposure.LightOnTime,
 			LayerHeight: header.LayerHeight,
 		}
@@ -585,7 +596,7 @@ func (pws *Print) LayerExposure(index int) (exposure uv3dp.Exposure) {
 	return
 }
 
-func (pws *Print) Image(index int) (slice *image.Gray) {
+func (pws *Print) LayerImage(index int) (slice *image.Gray) {
 	slice, err := pws.layers[index].slice.GetImage()
 	if err != nil {
 		panic(fmt.Sprintf("pws: layer %v: %s", index+1, err))
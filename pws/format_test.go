@@ -59,7 +59,7 @@ var (
 		0, 0, 0, 0,
 		0, 0, 0, 0}
 	emptyRawPreview = make([]byte, defaultPreviewWidth*defaultPreviewHeight*2)
-	emptyRawFooter  = []byte{0x4c, 0x41, 0x59, 0x45, 0x52, 0x44, 0x45, 0x46, 0x0, 0x0, 0x0, 0x0, 0x84, 0x0, 0x0, 0x0, 0x4, 0x0, 0x0, 0x0, 0x50, 0x27, 0x1, 0x0, 0x2, 0x0, 0x0, 0x0, 0x0, 0x0, 0xb0, 0x40, 0x0, 0x0, 0xf0, 0x42, 0x0, 0x0, 0x84, 0x41, 0xcd, 0xcc, 0x4c, 0x3d, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x52, 0x27, 0x1, 0x0, 0x2, 0x0, 0x0, 0x0, 0x0, 0x0, 0xb0, 0x40, 0x0, 0x0, 0xf0, 0x42, 0x0, 0x0, 0x84, 0x41, 0xcd, 0xcc, 0x4c, 0x3d, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x54, 0x27, 0x1, 0x0, 0x2, 0x0, 0x0, 0x0, 0x0, 0x0, 0xb0, 0x40, 0x0, 0x0, 0xf0, 0x42, 0x0, 0x0, 0x84, 0x41, 0xcd, 0xcc, 0x4c, 0x3d, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x56, 0x27, 0x1, 0x0, 0x2, 0x0, 0x0, 0x0, 0x0, 0x0, 0xb0, 0x40, 0x0, 0x0, 0xf0, 0x42, 0x0, 0x0, 0x84, 0x41, 0xcd, 0xcc, 0x4c, 0x3d, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x7d, 0x4b, 0x7d, 0x4b, 0x7d, 0x4b, 0x7d, 0x4b}
+	emptyRawFooter  = []byte{0x4c, 0x41, 0x59, 0x45, 0x52, 0x44, 0x45, 0x46, 0x0, 0x0, 0x0, 0x0, 0x84, 0x0, 0x0, 0x0, 0x4, 0x0, 0x0, 0x0, 0x50, 0x27, 0x1, 0x0, 0x2, 0x0, 0x0, 0x0, 0x0, 0x0, 0xb0, 0x40, 0x0, 0x0, 0x0, 0x40, 0x0, 0x0, 0x84, 0x41, 0xcd, 0xcc, 0x4c, 0x3d, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x52, 0x27, 0x1, 0x0, 0x2, 0x0, 0x0, 0x0, 0x0, 0x0, 0xb0, 0x40, 0x0, 0x0, 0x0, 0x40, 0x0, 0x0, 0x84, 0x41, 0xcd, 0xcc, 0x4c, 0x3d, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x54, 0x27, 0x1, 0x0, 0x2, 0x0, 0x0, 0x0, 0x0, 0x0, 0xb0, 0x40, 0x0, 0x0, 0x0, 0x40, 0x0, 0x0, 0x84, 0x41, 0xcd, 0xcc, 0x4c, 0x3d, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x56, 0x27, 0x1, 0x0, 0x2, 0x0, 0x0, 0x0, 0x0, 0x0, 0xb0, 0x40, 0x0, 0x0, 0x0, 0x40, 0x0, 0x0, 0x84, 0x41, 0xcd, 0xcc, 0x4c, 0x3d, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x7d, 0x4b, 0x7d, 0x4b, 0x7d, 0x4b, 0x7d, 0x4b}
 
 	emptyRaw = append(append(emptyRawHeader, emptyRawPreview...), emptyRawFooter...)
 )
@@ -0,0 +1,160 @@
+//
+// Copyright (c) 2020 Jason S. McMullan <jason.mcmullan@gmail.com>
+//
+
+package uv3dp
+
+import (
+	"image"
+)
+
+// QuantizeMode is the method used to reduce the gray levels of a layer to
+// the depth of a format.
+//
+// When set with SetQuantizeMode, QuantizeThreshold defers to each
+// format's own thresholds (which may not be those of its Quantize method),
+// so that encoded layers are as they were before quantize modes existed.
+type QuantizeMode int
+
+const (
+	QuantizeThreshold      = QuantizeMode(iota) // Truncate to the level at or below each pixel
+	QuantizeNearest                             // Round to the nearest level
+	QuantizeOrdered                             // Ordered dithering, with an 8x8 Bayer matrix
+	QuantizeFloydSteinberg                      // Floyd-Steinberg error diffusion dithering
+)
+
+// defaultQuantizeMode is used by the formats, as they encode layers
+var defaultQuantizeMode = QuantizeThreshold
+
+// SetQuantizeMode sets the mode used by formats to quantize their layers
+func SetQuantizeMode(mode QuantizeMode) {
+	defaultQuantizeMode = mode
+}
+
+// quantizeBayer is the 8x8 Bayer matrix of ordered dithering
+var quantizeBayer = [8][8]uint8{
+	{0, 32, 8, 40, 2, 34, 10, 42},
+	{48, 16, 56, 24, 50, 18, 58, 26},
+	{12, 44, 4, 36, 14, 46, 6, 38},
+	{60, 28, 52, 20, 62, 30, 54, 22},
+	{3, 35, 11, 43, 1, 33, 9, 41},
+	{51, 19, 59, 27, 49, 17, 57, 25},
+	{15, 47, 7, 39, 13, 45, 5, 37},
+	{63, 31, 55, 23, 61, 29, 53, 21},
+}
+
+// quantizeStep returns the gray level of a step of a number of steps
+func quantizeStep(step, steps uint) uint8 {
+	return uint8((step*0xff + steps/2) / steps)
+}
+
+// QuantizeLevel returns the nearest of a number of evenly spaced gray
+// levels, from black to white, to a gray level
+func QuantizeLevel(pix uint8, levels int) uint8 {
+	if levels < 2 || levels >= 256 {
+		return pix
+	}
+
+	steps := uint(levels - 1)
+	step := (uint(pix)*steps + 0x7f) / 0xff
+
+	return quantizeStep(step, steps)
+}
+
+// Quantize returns a new image, with its gray levels quantized to a number
+// of evenly spaced gray levels
+func Quantize(in *image.Gray, levels int) (out *image.Gray) {
+	return QuantizeNearest.Quantize(in, levels)
+}
+
+// QuantizeLayer returns a new image, with its gray levels quantized to a
+// number of evenly spaced gray levels by the mode set by SetQuantizeMode.
+//
+// Formats with fewer than 256 gray levels quantize each layer with this
+// as they encode it. In the threshold mode, the layer is left for the
+// format to truncate, by its own thresholds, as it always has.
+func QuantizeLayer(in *image.Gray, levels int) (out *image.Gray) {
+	if defaultQuantizeMode == QuantizeThreshold {
+		out = in
+		return
+	}
+
+	return defaultQuantizeMode.Quantize(in, levels)
+}
+
+// Quantize returns a new image, with its gray levels quantized to a number
+// of evenly spaced gray levels. Black and white, and the levels themselves,
+// are kept by all modes.
+func (mode QuantizeMode) Quantize(in *image.Gray, levels int) (out *image.Gray) {
+	bounds := in.Bounds()
+	width := bounds.Dx()
+	out = image.NewGray(bounds)
+
+	if levels < 2 || levels >= 256 {
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			offset := in.PixOffset(bounds.Min.X, y)
+			copy(out.Pix[out.PixOffset(bounds.Min.X, y):], in.Pix[offset:offset+width])
+		}
+		return
+	}
+
+	steps := uint(levels - 1)
+
+	// Errors (in 1/16ths of a gray level) to diffuse into this row, and
+	// into the next row, with a pixel of padding at either end
+	var thisRow, nextRow []int
+	if mode == QuantizeFloydSteinberg {
+		thisRow = make([]int, width+2)
+		nextRow = make([]int, width+2)
+	}
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			pix := in.Pix[in.PixOffset(x, y)]
+
+			var level uint8
+			switch mode {
+			case QuantizeThreshold:
+				level = quantizeStep(uint(pix)*steps/0xff, steps)
+			case QuantizeOrdered:
+				// The fraction of the way to the next step, against the
+				// (centred) Bayer matrix threshold
+				scaled := uint(pix) * steps
+				step := scaled / 0xff
+				if (scaled%0xff)*128 > (uint(quantizeBayer[y&7][x&7])*2+1)*0xff {
+					step++
+				}
+				level = quantizeStep(step, steps)
+			case QuantizeFloydSteinberg:
+				n := x - bounds.Min.X + 1
+				value := int(pix) + thisRow[n]/16
+				if value < 0x00 {
+					value = 0x00
+				} else if value > 0xff {
+					value = 0xff
+				}
+
+				level = QuantizeLevel(uint8(value), levels)
+
+				err := value - int(level)
+				thisRow[n+1] += err * 7
+				nextRow[n-1] += err * 3
+				nextRow[n] += err * 5
+				nextRow[n+1] += err * 1
+			default:
+				level = QuantizeLevel(pix, levels)
+			}
+
+			out.Pix[out.PixOffset(x, y)] = level
+		}
+
+		if mode == QuantizeFloydSteinberg {
+			thisRow, nextRow = nextRow, thisRow
+			for n := range nextRow {
+				nextRow[n] = 0
+			}
+		}
+	}
+
+	return
+}
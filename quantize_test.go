@@ -0,0 +1,141 @@
+//
+// Copyright (c) 2020 Jason S. McMullan <jason.mcmullan@gmail.com>
+//
+
+package uv3dp
+
+import (
+	"image"
+
+	"testing"
+)
+
+func TestQuantizeLevel(t *testing.T) {
+	table := []struct {
+		levels int
+		pix    uint8
+		level  uint8
+	}{
+		{0, 0x42, 0x42},
+		{256, 0x42, 0x42},
+		{2, 0x7f, 0x00},
+		{2, 0x80, 0xff},
+		{8, 0x00, 0x00},
+		{8, 0x40, 0x49},
+		{8, 0x80, 0x92},
+		{8, 0xff, 0xff},
+		{16, 0x18, 0x11},
+		{16, 0x1a, 0x22},
+	}
+
+	for _, item := range table {
+		level := QuantizeLevel(item.pix, item.levels)
+		if level != item.level {
+			t.Errorf("%v levels, %#x: expected %#x, got %#x", item.levels, item.pix, item.level, level)
+		}
+	}
+
+	// Levels are kept
+	for levels := 2; levels <= 16; levels++ {
+		for step := 0; step < levels; step++ {
+			pix := uint8((step*0xff + (levels-1)/2) / (levels - 1))
+			if QuantizeLevel(pix, levels) != pix {
+				t.Errorf("%v levels: level %#x was not kept", levels, pix)
+			}
+		}
+	}
+}
+
+func TestQuantizeMode(t *testing.T) {
+	// A horizontal ramp, from black to white
+	ramp := image.NewGray(image.Rect(0, 0, 256, 16))
+	for y := 0; y < 16; y++ {
+		for x := 0; x < 256; x++ {
+			ramp.Pix[ramp.PixOffset(x, y)] = uint8(x)
+		}
+	}
+
+	modes := map[string]QuantizeMode{
+		"threshold":       QuantizeThreshold,
+		"nearest":         QuantizeNearest,
+		"ordered":         QuantizeOrdered,
+		"floyd-steinberg": QuantizeFloydSteinberg,
+	}
+
+	for name, mode := range modes {
+		for _, levels := range []int{2, 5, 16} {
+			out := mode.Quantize(ramp, levels)
+
+			sum := 0
+			for y := 0; y < 16; y++ {
+				for x := 0; x < 256; x++ {
+					pix := out.Pix[out.PixOffset(x, y)]
+					sum += int(pix)
+
+					if QuantizeLevel(pix, levels) != pix {
+						t.Fatalf("%v, %v levels: (%v,%v) %#x is not a level", name, levels, x, y, pix)
+					}
+				}
+
+				// Black and white are kept
+				if out.Pix[out.PixOffset(0, y)] != 0x00 || out.Pix[out.PixOffset(255, y)] != 0xff {
+					t.Errorf("%v, %v levels: black or white was not kept", name, levels)
+				}
+			}
+
+			// All but truncation keep the mean brightness of the ramp
+			mean := float64(sum) / (256 * 16)
+			if mode == QuantizeThreshold {
+				if mean >= 127.5 {
+					t.Errorf("%v, %v levels: expected a darker mean than 127.5, got %v", name, levels, mean)
+				}
+			} else if mean < 126.5 || mean > 128.5 {
+				t.Errorf("%v, %v levels: expected a mean of 127.5, got %v", name, levels, mean)
+			}
+		}
+	}
+}
+
+func TestQuantizeDither(t *testing.T) {
+	// A flat gray, half way between black and white
+	gray := image.NewGray(image.Rect(0, 0, 16, 16))
+	for n := range gray.Pix {
+		gray.Pix[n] = 0x80
+	}
+
+	table := []struct {
+		mode QuantizeMode
+		on   int
+	}{
+		{QuantizeThreshold, 0},
+		{QuantizeNearest, 256},
+		{QuantizeOrdered, 128},
+		{QuantizeFloydSteinberg, 128},
+	}
+
+	for _, item := range table {
+		out := item.mode.Quantize(gray, 2)
+
+		on := 0
+		for _, pix := range out.Pix {
+			if pix == 0xff {
+				on++
+			}
+		}
+
+		if on < item.on-2 || on > item.on+2 {
+			t.Errorf("mode %v: expected %v pixels on, got %v", item.mode, item.on, on)
+		}
+	}
+
+	// Layers are quantized by the mode that is set
+	if QuantizeLayer(gray, 2) != gray {
+		t.Errorf("expected the threshold mode to leave layers to the format by default")
+	}
+
+	defer SetQuantizeMode(QuantizeThreshold)
+	SetQuantizeMode(QuantizeNearest)
+	if QuantizeLayer(gray, 2).Pix[0] != 0xff {
+		t.Errorf("expected the nearest mode to quantize layers")
+	}
+}
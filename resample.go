@@ -0,0 +1,87 @@
+//
+// Copyright (c) 2020 Jason S. McMullan <jason.mcmullan@gmail.com>
+//
+
+package uv3dp
+
+import (
+	"image"
+
+	"golang.org/x/image/draw"
+	"golang.org/x/image/math/f64"
+)
+
+// ResampleKernel is the kernel used to resample layer images, as they are
+// scaled or transformed
+type ResampleKernel int
+
+const (
+	ResampleNearest     = ResampleKernel(iota) // Nearest source pixel (no new gray levels)
+	ResampleBilinear                           // Bilinear interpolation
+	ResampleCatmullRom                         // Catmull-Rom cubic interpolation (sharpest)
+	ResampleAreaAverage                        // Average of the source pixels covered (best when shrinking)
+)
+
+// resampleArea is a box kernel, that averages the source pixels covered by
+// each destination pixel. The support is a little wider than half a pixel,
+// so that both of the source pixels are averaged when a destination pixel
+// is centred on the edge between them.
+var resampleArea = &draw.Kernel{
+	Support: 0.5 + 1e-6,
+	At:      func(t float64) float64 { return 1.0 },
+}
+
+// Resample scales or transforms layer images with a kernel, and may
+// threshold them again (for formats without gray levels)
+type Resample struct {
+	Kernel      ResampleKernel
+	Rethreshold bool  // Threshold the resampled image
+	Threshold   uint8 // Pixels brighter than this are on, if Rethreshold
+}
+
+func (rs *Resample) interpolator() (interp draw.Interpolator) {
+	switch rs.Kernel {
+	case ResampleBilinear:
+		interp = draw.BiLinear
+	case ResampleCatmullRom:
+		interp = draw.CatmullRom
+	case ResampleAreaAverage:
+		interp = resampleArea
+	default:
+		interp = draw.NearestNeighbor
+	}
+
+	return
+}
+
+func (rs *Resample) rethreshold(dst *image.Gray, dr image.Rectangle) {
+	if !rs.Rethreshold {
+		return
+	}
+
+	dr = dr.Intersect(dst.Bounds())
+	for y := dr.Min.Y; y < dr.Max.Y; y++ {
+		row := dst.Pix[dst.PixOffset(dr.Min.X, y):dst.PixOffset(dr.Max.X, y)]
+		for x, pix := range row {
+			if pix > rs.Threshold {
+				row[x] = 0xff
+			} else {
+				row[x] = 0x00
+			}
+		}
+	}
+}
+
+// Scale scales the source rectangle of an image to the destination
+// rectangle of a layer image
+func (rs *Resample) Scale(dst *image.Gray, dr image.Rectangle, src image.Image, sr image.Rectangle) {
+	rs.interpolator().Scale(dst, dr, src, sr, draw.Src, nil)
+	rs.rethreshold(dst, dr)
+}
+
+// Transform transforms an image into a layer image, by an affine matrix of
+// source pixels to destination pixels
+func (rs *Resample) Transform(dst *image.Gray, s2d f64.Aff3, src image.Image) {
+	rs.interpolator().Transform(dst, s2d, src, src.Bounds(), draw.Src, nil)
+	rs.rethreshold(dst, dst.Bounds())
+}
@@ -0,0 +1,71 @@
+//
+// Copyright (c) 2020 Jason S. McMullan <jason.mcmullan@gmail.com>
+//
+
+package uv3dp
+
+import (
+	"image"
+
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestResampleScale(t *testing.T) {
+	// Columns of black, white, white, black
+	src := image.NewGray(image.Rect(0, 0, 4, 2))
+	copy(src.Pix, []uint8{
+		0x00, 0xff, 0xff, 0x00,
+		0x00, 0xff, 0xff, 0x00,
+	})
+
+	table := []struct {
+		name     string
+		resample Resample
+		pix      []uint8
+	}{
+		{"nearest", Resample{Kernel: ResampleNearest}, []uint8{0xff, 0x00}},
+		{"area-average", Resample{Kernel: ResampleAreaAverage}, []uint8{0x80, 0x80}},
+		{"rethreshold", Resample{Kernel: ResampleAreaAverage, Rethreshold: true, Threshold: 0x7f}, []uint8{0xff, 0xff}},
+		{"rethreshold-high", Resample{Kernel: ResampleAreaAverage, Rethreshold: true, Threshold: 0x80}, []uint8{0x00, 0x00}},
+	}
+
+	for _, item := range table {
+		dst := image.NewGray(image.Rect(0, 0, 2, 1))
+		item.resample.Scale(dst, dst.Bounds(), src, src.Bounds())
+		if !cmp.Equal(dst.Pix, item.pix) {
+			t.Errorf("%v: expected %#v, got %#v", item.name, item.pix, dst.Pix)
+		}
+	}
+}
+
+func TestResampleTransform(t *testing.T) {
+	block := image.Rect(2, 2, 6, 6)
+
+	for _, kernel := range []ResampleKernel{ResampleNearest, ResampleBilinear, ResampleCatmullRom, ResampleAreaAverage} {
+		p := newTransformTestPrintable(block)
+
+		// Half a pixel to the right
+		transform := Transform{X: 0.25, ScaleX: 1, ScaleY: 1, Resample: Resample{Kernel: kernel}}
+		tp, err := NewTransformPrintable(p, transform, image.Rectangle{})
+		if err != nil {
+			t.Fatalf("kernel %v: expected nil, got %v", kernel, err)
+		}
+
+		ig := tp.LayerImage(0)
+		left, right := ig.GrayAt(2, 3).Y, ig.GrayAt(6, 3).Y
+
+		if kernel == ResampleNearest {
+			if (left != 0x00 && left != 0xff) || (right != 0x00 && right != 0xff) {
+				t.Errorf("kernel %v: expected no gray edges, got %#x and %#x", kernel, left, right)
+			}
+		} else if left < 0x60 || left > 0xa0 || right < 0x60 || right > 0xa0 {
+			t.Errorf("kernel %v: expected half gray edges, got %#x and %#x", kernel, left, right)
+		}
+
+		if ig.GrayAt(4, 3).Y != 0xff {
+			t.Errorf("kernel %v: expected a lit interior, got %#x", kernel, ig.GrayAt(4, 3).Y)
+		}
+	}
+}
@@ -0,0 +1,201 @@
+//
+// Copyright (c) 2020 Jason S. McMullan <jason.mcmullan@gmail.com>
+//
+
+package review
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/png"
+	"math"
+
+	"github.com/ezrec/uv3dp"
+)
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// pngChunk is a single chunk of a PNG stream
+type pngChunk struct {
+	Type string
+	Data []byte
+}
+
+// Split a PNG stream into its chunks
+func pngChunks(data []byte) (chunks []pngChunk, err error) {
+	if !bytes.HasPrefix(data, pngSignature) {
+		err = errors.New("not a PNG stream")
+		return
+	}
+
+	data = data[len(pngSignature):]
+	for len(data) > 0 {
+		if len(data) < 12 {
+			err = errors.New("truncated PNG chunk")
+			return
+		}
+
+		length := int(binary.BigEndian.Uint32(data[0:4]))
+		if len(data) < 12+length {
+			err = errors.New("truncated PNG chunk")
+			return
+		}
+
+		chunks = append(chunks, pngChunk{
+			Type: string(data[4:8]),
+			Data: data[8 : 8+length],
+		})
+
+		data = data[12+length:]
+	}
+
+	return
+}
+
+// Write a PNG chunk, with its CRC
+func writeChunk(writer uv3dp.Writer, kind string, data []byte) (err error) {
+	header := make([]byte, 8)
+	binary.BigEndian.PutUint32(header[0:4], uint32(len(data)))
+	copy(header[4:8], kind)
+
+	crc := crc32.NewIEEE()
+	crc.Write(header[4:8])
+	crc.Write(data)
+
+	trailer := make([]byte, 4)
+	binary.BigEndian.PutUint32(trailer, crc.Sum32())
+
+	for _, buff := range [][]byte{header, data, trailer} {
+		_, err = writer.Write(buff)
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+// apngFrame is an encoded frame: the header, and the concatenated image data
+type apngFrame struct {
+	Header []byte
+	Data   []byte
+	Err    error
+}
+
+func encodeFrame(gray *image.Gray) (frame apngFrame) {
+	buff := &bytes.Buffer{}
+	encoder := png.Encoder{CompressionLevel: png.BestSpeed}
+
+	frame.Err = encoder.Encode(buff, gray)
+	if frame.Err != nil {
+		return
+	}
+
+	chunks, err := pngChunks(buff.Bytes())
+	if err != nil {
+		frame.Err = err
+		return
+	}
+
+	for _, chunk := range chunks {
+		switch chunk.Type {
+		case "IHDR":
+			frame.Header = chunk.Data
+		case "IDAT":
+			frame.Data = append(frame.Data, chunk.Data...)
+		}
+	}
+
+	return
+}
+
+func (sf *Format) encodeAPNG(writer uv3dp.Writer, printable uv3dp.Printable) (err error) {
+	width, height := sf.scaledSize(printable.Size())
+	frames := printable.Size().Layers
+
+	frameChan := make([](chan apngFrame), frames)
+	for n := range frameChan {
+		frameChan[n] = make(chan apngFrame, 1)
+	}
+
+	go uv3dp.WithAllLayers(printable, func(p uv3dp.Printable, n int) {
+		frameChan[n] <- encodeFrame(scaleLayer(p.LayerImage(n), width, height))
+		close(frameChan[n])
+	})
+
+	// Delays are in 1/1000s
+	delay := uint16(math.Min(math.Round(1000.0/float64(sf.Rate)), math.MaxUint16))
+	if delay < 1 {
+		delay = 1
+	}
+
+	sequence := uint32(0)
+	for n, done := range frameChan {
+		frame := <-done
+		if err != nil {
+			continue
+		}
+
+		if frame.Err != nil {
+			err = frame.Err
+			continue
+		}
+
+		if n == 0 {
+			_, err = writer.Write(pngSignature)
+			if err != nil {
+				continue
+			}
+
+			err = writeChunk(writer, "IHDR", frame.Header)
+			if err != nil {
+				continue
+			}
+
+			// Frame count, and loop forever
+			actl := make([]byte, 8)
+			binary.BigEndian.PutUint32(actl[0:4], uint32(frames))
+			err = writeChunk(writer, "acTL", actl)
+			if err != nil {
+				continue
+			}
+		}
+
+		// Full frame, replacing the previous frame
+		fctl := make([]byte, 26)
+		binary.BigEndian.PutUint32(fctl[0:4], sequence)
+		binary.BigEndian.PutUint32(fctl[4:8], uint32(width))
+		binary.BigEndian.PutUint32(fctl[8:12], uint32(height))
+		binary.BigEndian.PutUint16(fctl[20:22], delay)
+		binary.BigEndian.PutUint16(fctl[22:24], 1000)
+		sequence++
+
+		err = writeChunk(writer, "fcTL", fctl)
+		if err != nil {
+			continue
+		}
+
+		// The first frame is also the default image
+		if n == 0 {
+			err = writeChunk(writer, "IDAT", frame.Data)
+		} else {
+			fdat := make([]byte, 4+len(frame.Data))
+			binary.BigEndian.PutUint32(fdat[0:4], sequence)
+			copy(fdat[4:], frame.Data)
+			sequence++
+
+			err = writeChunk(writer, "fdAT", fdat)
+		}
+	}
+
+	if err != nil {
+		return
+	}
+
+	err = writeChunk(writer, "IEND", nil)
+
+	return
+}
@@ -0,0 +1,25 @@
+//
+// Copyright (c) 2020 Jason S. McMullan <jason.mcmullan@gmail.com>
+//
+
+package review
+
+import (
+	"testing"
+
+	"github.com/ezrec/uv3dp"
+	"github.com/ezrec/uv3dp/uv3dptest"
+)
+
+func TestConformance(t *testing.T) {
+	newFormatter := func(suffix string) uv3dp.Formatter { return NewFormatter(suffix) }
+
+	for _, suffix := range []string{".gif", ".apng", sheetSuffix} {
+		t.Run(suffix, func(t *testing.T) {
+			uv3dptest.RunFormatterConformance(t, newFormatter, uv3dptest.Capabilities{
+				Suffix:     suffix,
+				EncodeOnly: true,
+			})
+		})
+	}
+}
@@ -0,0 +1,226 @@
+//
+// Copyright (c) 2020 Jason S. McMullan <jason.mcmullan@gmail.com>
+//
+
+package review
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+	"math"
+
+	"github.com/ezrec/uv3dp"
+	"github.com/spf13/pflag"
+	"golang.org/x/image/draw"
+)
+
+const (
+	defaultScale      = 0.25
+	defaultRate       = 10.0 // Frames per second
+	defaultAnimEvery  = 1
+	defaultSheetEvery = 10
+	defaultColumns    = 8
+)
+
+// sheetSuffix is the suffix of contact sheets, which leaves other '.png'
+// names to other formats
+const sheetSuffix = ".sheet.png"
+
+// Palette where the index is the gray level
+var grayPalette color.Palette
+
+func init() {
+	grayPalette = make(color.Palette, 256)
+	for n := range grayPalette {
+		grayPalette[n] = color.Gray{Y: uint8(n)}
+	}
+}
+
+type Format struct {
+	*pflag.FlagSet
+
+	suffix string
+
+	Scale   float32
+	Every   int
+	Rate    float32
+	Columns int
+}
+
+func NewFormatter(suffix string) (sf *Format) {
+	flagSet := pflag.NewFlagSet(suffix, pflag.ContinueOnError)
+
+	sf = &Format{
+		FlagSet: flagSet,
+		suffix:  suffix,
+	}
+
+	sf.Float32VarP(&sf.Scale, "scale", "s", defaultScale, "Scale of the layer images")
+
+	if suffix == sheetSuffix {
+		sf.IntVarP(&sf.Every, "every", "n", defaultSheetEvery, "Tile every Nth layer")
+		sf.IntVarP(&sf.Columns, "columns", "c", defaultColumns, "Number of tiles across the sheet")
+	} else {
+		sf.IntVarP(&sf.Every, "every", "n", defaultAnimEvery, "Animate every Nth layer")
+		sf.Float32VarP(&sf.Rate, "rate", "r", defaultRate, "Frames per second")
+	}
+
+	sf.SetInterspersed(false)
+
+	return
+}
+
+// stridePrintable selects every Nth layer of a printable
+type stridePrintable struct {
+	uv3dp.Printable
+
+	every int
+}
+
+func (sp *stridePrintable) Size() (size uv3dp.Size) {
+	size = sp.Printable.Size()
+	size.Layers = (size.Layers + sp.every - 1) / sp.every
+
+	return
+}
+
+func (sp *stridePrintable) LayerZ(index int) float32 {
+	return sp.Printable.LayerZ(index * sp.every)
+}
+
+func (sp *stridePrintable) LayerExposure(index int) uv3dp.Exposure {
+	return sp.Printable.LayerExposure(index * sp.every)
+}
+
+func (sp *stridePrintable) LayerImage(index int) *image.Gray {
+	return sp.Printable.LayerImage(index * sp.every)
+}
+
+// Size of a scaled layer image
+func (sf *Format) scaledSize(size uv3dp.Size) (width, height int) {
+	width = int(math.Round(float64(size.X) * float64(sf.Scale)))
+	height = int(math.Round(float64(size.Y) * float64(sf.Scale)))
+
+	if width < 1 {
+		width = 1
+	}
+
+	if height < 1 {
+		height = 1
+	}
+
+	return
+}
+
+// Scale a layer image, filtering when reducing
+func scaleLayer(gray *image.Gray, width, height int) (scaled *image.Gray) {
+	if gray.Bounds().Dx() == width && gray.Bounds().Dy() == height {
+		scaled = gray
+		return
+	}
+
+	scaled = image.NewGray(image.Rect(0, 0, width, height))
+	draw.BiLinear.Scale(scaled, scaled.Bounds(), gray, gray.Bounds(), draw.Src, nil)
+
+	return
+}
+
+// Encode renders the selected layers as an animation or a contact sheet
+func (sf *Format) Encode(writer uv3dp.Writer, printable uv3dp.Printable) (err error) {
+	if sf.Scale <= 0 {
+		err = fmt.Errorf("illegal --scale setting: %v", sf.Scale)
+		return
+	}
+
+	if sf.Every < 1 {
+		err = fmt.Errorf("illegal --every setting: %v", sf.Every)
+		return
+	}
+
+	if sf.suffix != sheetSuffix && sf.Rate <= 0 {
+		err = fmt.Errorf("illegal --rate setting: %v", sf.Rate)
+		return
+	}
+
+	if sf.suffix == sheetSuffix && sf.Columns < 1 {
+		err = fmt.Errorf("illegal --columns setting: %v", sf.Columns)
+		return
+	}
+
+	if printable.Size().Layers < 1 {
+		err = errors.New("no layers to render")
+		return
+	}
+
+	printable = &stridePrintable{Printable: printable, every: sf.Every}
+
+	switch sf.suffix {
+	case ".gif":
+		err = sf.encodeGIF(writer, printable)
+	case ".apng":
+		err = sf.encodeAPNG(writer, printable)
+	default:
+		err = sf.encodeSheet(writer, printable)
+	}
+
+	return
+}
+
+// Scaled layer images, in order
+func (sf *Format) scaledLayers(printable uv3dp.Printable) (layerChan [](chan *image.Gray)) {
+	width, height := sf.scaledSize(printable.Size())
+
+	layerChan = make([](chan *image.Gray), printable.Size().Layers)
+	for n := range layerChan {
+		layerChan[n] = make(chan *image.Gray, 1)
+	}
+
+	go uv3dp.WithAllLayers(printable, func(p uv3dp.Printable, n int) {
+		layerChan[n] <- scaleLayer(p.LayerImage(n), width, height)
+		close(layerChan[n])
+	})
+
+	return
+}
+
+func (sf *Format) encodeGIF(writer uv3dp.Writer, printable uv3dp.Printable) (err error) {
+	width, height := sf.scaledSize(printable.Size())
+
+	anim := &gif.GIF{
+		Config: image.Config{
+			ColorModel: grayPalette,
+			Width:      width,
+			Height:     height,
+		},
+	}
+
+	// GIF delays are in 1/100s
+	delay := int(math.Round(100.0 / float64(sf.Rate)))
+	if delay < 1 {
+		delay = 1
+	}
+
+	for _, done := range sf.scaledLayers(printable) {
+		gray := <-done
+		anim.Image = append(anim.Image, &image.Paletted{
+			Pix:     gray.Pix,
+			Stride:  gray.Stride,
+			Rect:    gray.Rect,
+			Palette: grayPalette,
+		})
+		anim.Delay = append(anim.Delay, delay)
+		anim.Disposal = append(anim.Disposal, gif.DisposalNone)
+	}
+
+	err = gif.EncodeAll(writer, anim)
+
+	return
+}
+
+func (sf *Format) Decode(reader uv3dp.Reader, filesize int64) (printable uv3dp.Printable, err error) {
+	err = fmt.Errorf("%v input is not supported", sf.suffix)
+	return
+}
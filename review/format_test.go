@@ -0,0 +1,258 @@
+//
+// Copyright (c) 2020 Jason S. McMullan <jason.mcmullan@gmail.com>
+//
+
+package review
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/gif"
+	"image/png"
+
+	"testing"
+
+	"github.com/ezrec/uv3dp"
+)
+
+type barPrint struct {
+	uv3dp.Print
+}
+
+// Each layer is lit from the left edge, 8 pixels wider than the last
+func (bp *barPrint) LayerImage(index int) (gray *image.Gray) {
+	gray = image.NewGray(bp.Bounds())
+	for y := 0; y < gray.Rect.Dy(); y++ {
+		for x := 0; x < (index+1)*8 && x < gray.Rect.Dx(); x++ {
+			gray.Pix[y*gray.Stride+x] = 0xff
+		}
+	}
+
+	return
+}
+
+func newBarPrint() *barPrint {
+	prop := uv3dp.Properties{
+		Size: uv3dp.Size{
+			X: 40, Y: 20,
+			Millimeter:  uv3dp.SizeMillimeter{X: 4.0, Y: 2.0},
+			Layers:      5,
+			LayerHeight: 0.05,
+		},
+		Exposure: uv3dp.Exposure{LightOnTime: 8.0, LightPWM: 255},
+		Bottom: uv3dp.Bottom{
+			Count:    1,
+			Exposure: uv3dp.Exposure{LightOnTime: 60.0, LightPWM: 255},
+		},
+	}
+
+	return &barPrint{Print: uv3dp.Print{Properties: prop}}
+}
+
+func TestEncodeGIF(t *testing.T) {
+	formatter := NewFormatter(".gif")
+	err := formatter.Parse([]string{"--scale", "0.5", "--every", "2", "--rate", "4"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buff := &bytes.Buffer{}
+	err = formatter.Encode(buff, newBarPrint())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	anim, err := gif.DecodeAll(buff)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Layers 0, 2 and 4
+	if len(anim.Image) != 3 {
+		t.Fatalf("expected 3 frames, got %v", len(anim.Image))
+	}
+
+	for n, frame := range anim.Image {
+		if frame.Bounds() != image.Rect(0, 0, 20, 10) {
+			t.Errorf("frame %v: unexpected bounds %v", n, frame.Bounds())
+		}
+
+		if anim.Delay[n] != 25 {
+			t.Errorf("frame %v: expected delay 25, got %v", n, anim.Delay[n])
+		}
+
+		// Lit to 4, 12 and 20 pixels, with filtered edges
+		edge := (n*2 + 1) * 4
+		lit, _, _, _ := frame.At(edge-2, 5).RGBA()
+		if lit != 0xffff {
+			t.Errorf("frame %v: expected (%v,5) to be lit", n, edge-2)
+		}
+		if edge < 20 {
+			unlit, _, _, _ := frame.At(edge+1, 5).RGBA()
+			if unlit != 0 {
+				t.Errorf("frame %v: expected (%v,5) to be unlit", n, edge+1)
+			}
+		}
+	}
+}
+
+func TestEncodeAPNG(t *testing.T) {
+	formatter := NewFormatter(".apng")
+	err := formatter.Parse([]string{"--scale", "0.5"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buff := &bytes.Buffer{}
+	err = formatter.Encode(buff, newBarPrint())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	chunks, err := pngChunks(buff.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	count := map[string]int{}
+	var sequence []uint32
+	for _, chunk := range chunks {
+		count[chunk.Type]++
+		switch chunk.Type {
+		case "acTL":
+			frames := binary.BigEndian.Uint32(chunk.Data[0:4])
+			if frames != 5 {
+				t.Errorf("expected 5 frames, got %v", frames)
+			}
+		case "fcTL":
+			sequence = append(sequence, binary.BigEndian.Uint32(chunk.Data[0:4]))
+			delay := binary.BigEndian.Uint16(chunk.Data[20:22])
+			if delay != 100 {
+				t.Errorf("expected a delay of 100/1000, got %v", delay)
+			}
+		case "fdAT":
+			sequence = append(sequence, binary.BigEndian.Uint32(chunk.Data[0:4]))
+		}
+	}
+
+	if count["fcTL"] != 5 || count["fdAT"] != 4 || count["IDAT"] != 1 || count["IEND"] != 1 {
+		t.Errorf("unexpected chunks %v", count)
+	}
+
+	for n, seq := range sequence {
+		if seq != uint32(n) {
+			t.Errorf("expected sequence numbers in order, got %v", sequence)
+			break
+		}
+	}
+
+	// The default image is the first frame
+	img, err := png.Decode(bytes.NewReader(buff.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if img.Bounds() != image.Rect(0, 0, 20, 10) {
+		t.Errorf("unexpected bounds %v", img.Bounds())
+	}
+
+	gray := img.(*image.Gray)
+	if gray.GrayAt(2, 5).Y != 0xff || gray.GrayAt(5, 5).Y != 0x00 {
+		t.Errorf("expected the default image to be the first layer")
+	}
+}
+
+func TestEncodeSheet(t *testing.T) {
+	formatter := NewFormatter(sheetSuffix)
+	err := formatter.Parse([]string{"--scale", "0.5", "--every", "2", "--columns", "2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buff := &bytes.Buffer{}
+	err = formatter.Encode(buff, newBarPrint())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	img, err := png.Decode(buff)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Three tiles, in two columns and two rows
+	tileWidth := 20 + sheetGap
+	tileHeight := 10 + sheetLabelSize + sheetGap
+	bounds := image.Rect(0, 0, 2*tileWidth+sheetGap, 2*tileHeight+sheetGap)
+	if img.Bounds() != bounds {
+		t.Fatalf("expected bounds %v, got %v", bounds, img.Bounds())
+	}
+
+	gray := img.(*image.Gray)
+	for n, edge := range []int{4, 12, 20} {
+		x := sheetGap + (n%2)*tileWidth
+		y := sheetGap + (n/2)*tileHeight + 5
+
+		if gray.GrayAt(x+edge-2, y).Y != 0xff {
+			t.Errorf("tile %v: expected (%v,%v) to be lit", n, x+edge-2, y)
+		}
+
+		if edge < 20 && gray.GrayAt(x+edge+1, y).Y != 0x00 {
+			t.Errorf("tile %v: expected (%v,%v) to be unlit", n, x+edge+1, y)
+		}
+
+		if gray.GrayAt(x-1, y).Y != sheetGapGray {
+			t.Errorf("tile %v: expected a gap before the tile", n)
+		}
+	}
+
+	// Labels have text on them
+	lit := 0
+	label := image.Rect(sheetGap, sheetGap+10, sheetGap+20, sheetGap+10+sheetLabelSize)
+	for y := label.Min.Y; y < label.Max.Y; y++ {
+		for x := label.Min.X; x < label.Max.X; x++ {
+			if gray.GrayAt(x, y).Y == 0xff {
+				lit++
+			}
+		}
+	}
+
+	if lit == 0 {
+		t.Errorf("expected label text on the first tile")
+	}
+
+	// Unused tile is left as the gap
+	if gray.GrayAt(sheetGap+tileWidth+5, sheetGap+tileHeight+5).Y != sheetGapGray {
+		t.Errorf("expected the unused tile to be empty")
+	}
+
+	if labels := sheetLabel(newBarPrint(), 0, 0); labels[0] != "#0 Z 0.050mm" || labels[1] != "60.00s" {
+		t.Errorf("unexpected label %v", labels)
+	}
+}
+
+func TestEncodeIllegal(t *testing.T) {
+	table := []struct {
+		Suffix string
+		Args   []string
+	}{
+		{".gif", []string{"--scale", "0"}},
+		{".apng", []string{"--rate", "0"}},
+		{sheetSuffix, []string{"--every", "0"}},
+		{sheetSuffix, []string{"--columns", "0"}},
+	}
+
+	for _, item := range table {
+		formatter := NewFormatter(item.Suffix)
+		err := formatter.Parse(item.Args)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = formatter.Encode(&bytes.Buffer{}, newBarPrint())
+		if err == nil {
+			t.Errorf("%v %v: expected an error", item.Suffix, item.Args)
+		}
+	}
+}
@@ -0,0 +1,19 @@
+//
+// Copyright (c) 2020 Jason S. McMullan <jason.mcmullan@gmail.com>
+//
+
+// Package review renders the layers of printables for quick review, as
+// animated GIF or APNG files, or as a PNG contact sheet ('.sheet.png')
+package review
+
+import (
+	"github.com/ezrec/uv3dp"
+)
+
+func init() {
+	newFormatter := func(suffix string) uv3dp.Formatter { return NewFormatter(suffix) }
+
+	uv3dp.RegisterFormatter(".gif", newFormatter)
+	uv3dp.RegisterFormatter(".apng", newFormatter)
+	uv3dp.RegisterFormatter(sheetSuffix, newFormatter)
+}
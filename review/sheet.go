@@ -0,0 +1,95 @@
+//
+// Copyright (c) 2020 Jason S. McMullan <jason.mcmullan@gmail.com>
+//
+
+package review
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+
+	"github.com/ezrec/uv3dp"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+const (
+	sheetGap        = 2           // Pixels between tiles
+	sheetLineHeight = 13          // Height of a line of label text
+	sheetLabelLines = 2           // Lines of label text under each tile
+	sheetLabelInset = 2           // Offset of the label text from the tile edge
+	sheetGapGray    = uint8(0x60) // Gray level between tiles
+	sheetLabelGray  = uint8(0x20) // Gray level behind the labels
+	sheetTextGray   = uint8(0xff) // Gray level of the label text
+
+	sheetLabelSize = sheetLineHeight*sheetLabelLines + sheetLabelInset*2
+)
+
+// Label text for a layer: index, Z and exposure
+func sheetLabel(p uv3dp.Printable, index int, layer int) (lines []string) {
+	exposure := p.LayerExposure(index)
+
+	lines = []string{
+		fmt.Sprintf("#%d Z %.3fmm", layer, p.LayerZ(index)),
+		fmt.Sprintf("%.2fs", exposure.LightOnTime),
+	}
+
+	if exposure.LightPWM != 255 {
+		lines[1] += fmt.Sprintf(" PWM %d", exposure.LightPWM)
+	}
+
+	return
+}
+
+func (sf *Format) encodeSheet(writer uv3dp.Writer, printable uv3dp.Printable) (err error) {
+	width, height := sf.scaledSize(printable.Size())
+	tiles := printable.Size().Layers
+
+	columns := sf.Columns
+	if columns > tiles {
+		columns = tiles
+	}
+	rows := (tiles + columns - 1) / columns
+
+	tileWidth := width + sheetGap
+	tileHeight := height + sheetLabelSize + sheetGap
+
+	sheet := image.NewGray(image.Rect(0, 0, columns*tileWidth+sheetGap, rows*tileHeight+sheetGap))
+	draw.Draw(sheet, sheet.Bounds(), image.NewUniform(color.Gray{Y: sheetGapGray}), image.Point{}, draw.Src)
+
+	drawer := &font.Drawer{
+		Dst:  sheet,
+		Src:  image.NewUniform(color.Gray{Y: sheetTextGray}),
+		Face: basicfont.Face7x13,
+	}
+
+	for n, done := range sf.scaledLayers(printable) {
+		gray := <-done
+
+		origin := image.Point{
+			X: sheetGap + (n%columns)*tileWidth,
+			Y: sheetGap + (n/columns)*tileHeight,
+		}
+
+		tile := image.Rectangle{Min: origin, Max: origin.Add(image.Point{width, height})}
+		draw.Draw(sheet, tile, gray, gray.Bounds().Min, draw.Src)
+
+		label := image.Rect(tile.Min.X, tile.Max.Y, tile.Max.X, tile.Max.Y+sheetLabelSize)
+		draw.Draw(sheet, label, image.NewUniform(color.Gray{Y: sheetLabelGray}), image.Point{}, draw.Src)
+
+		// Text is clipped to the label, by the width of the tile
+		drawer.Dst = sheet.SubImage(label).(*image.Gray)
+		for line, text := range sheetLabel(printable, n, n*sf.Every) {
+			drawer.Dot = fixed.P(label.Min.X+sheetLabelInset, label.Min.Y+sheetLabelInset+basicfont.Face7x13.Ascent+line*sheetLineHeight)
+			drawer.DrawString(text)
+		}
+	}
+
+	err = png.Encode(writer, sheet)
+
+	return
+}
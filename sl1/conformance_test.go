@@ -0,0 +1,32 @@
+//
+// Copyright (c) 2020 Jason S. McMullan <jason.mcmullan@gmail.com>
+//
+
+package sl1
+
+import (
+	"testing"
+
+	"github.com/ezrec/uv3dp"
+	"github.com/ezrec/uv3dp/uv3dptest"
+)
+
+func TestConformance(t *testing.T) {
+	newFormatter := func(suffix string) uv3dp.Formatter { return NewFormatter(suffix) }
+
+	uv3dptest.RunFormatterConformance(t, newFormatter, uv3dptest.Capabilities{
+		Suffix:     ".sl1",
+		GrayLevels: 256,
+		Transition: true,
+		Previews:   []uv3dp.PreviewType{uv3dp.PreviewTypeTiny, uv3dp.PreviewTypeHuge},
+		Fixup: func(kind uv3dptest.ExposureKind, exposure *uv3dp.Exposure) {
+			// The light off time is derived from the print time, and the
+			// tower motion is from the --print-speed profile
+			exposure.LightOffTime = 0
+			exposure.LiftHeight = 0
+			exposure.LiftSpeed = 0
+			exposure.RetractHeight = 0
+			exposure.RetractSpeed = 0
+		},
+	})
+}
@@ -27,21 +27,52 @@ var (
 )
 
 var (
-	defaultExposure = uv3dp.Exposure{
-		LiftHeight:    4,
-		LiftSpeed:     60,
-		RetractHeight: 4,
-		RetractSpeed:  60,
+	// Tower motion, by printer model and 'material_print_speed' (tilt profile)
+	speedProfiles = map[string](map[string]uv3dp.Exposure){
+		"SL1": {
+			"slow":           {LiftHeight: 4, LiftSpeed: 30, RetractHeight: 4, RetractSpeed: 30},
+			"fast":           {LiftHeight: 4, LiftSpeed: 60, RetractHeight: 4, RetractSpeed: 60},
+			"high_viscosity": {LiftHeight: 4, LiftSpeed: 20, RetractHeight: 4, RetractSpeed: 20},
+		},
+		"SL1S": {
+			"slow":           {LiftHeight: 4, LiftSpeed: 45, RetractHeight: 4, RetractSpeed: 45},
+			"fast":           {LiftHeight: 4, LiftSpeed: 90, RetractHeight: 4, RetractSpeed: 90},
+			"high_viscosity": {LiftHeight: 4, LiftSpeed: 30, RetractHeight: 4, RetractSpeed: 30},
+		},
 	}
 
-	defaultBottomExposure = uv3dp.Exposure{
-		LiftHeight:    4,
-		LiftSpeed:     60,
-		RetractHeight: 4,
-		RetractSpeed:  60,
+	printerProfiles = map[string]string{
+		"SL1":  "Original Prusa SL1",
+		"SL1S": "Original Prusa SL1S SPEED",
 	}
 )
 
+const (
+	defaultJobDir     = "uv3dp"
+	defaultPrintSpeed = "fast"
+)
+
+// config.ini entries that are generated by the encoder, and are not
+// preserved as metadata
+var configKeys = []string{
+	"action",
+	"jobDir",
+	"expTime",
+	"expTimeFirst",
+	"fileCreationTimestamp",
+	"layerHeight",
+	"materialName",
+	"numFade",
+	"numFast",
+	"numSlow",
+	"printProfile",
+	"printTime",
+	"printerModel",
+	"printerProfile",
+	"prusaSlicerVersion",
+	"usedMaterial",
+}
+
 type sl1Config struct {
 	jobDir       string
 	expTime      float32
@@ -137,22 +168,70 @@ type Print struct {
 type Format struct {
 	*pflag.FlagSet
 
+	PrinterModel string
 	MaterialName string
+	PrintSpeed   string
 }
 
 func NewFormatter(suffix string) (sf *Format) {
 	flagSet := pflag.NewFlagSet(suffix, pflag.ContinueOnError)
 
 	sf = &Format{
-		FlagSet: flagSet,
+		FlagSet:      flagSet,
+		PrinterModel: "SL1",
+	}
+
+	if suffix == ".sl1s" {
+		sf.PrinterModel = "SL1S"
 	}
 
 	sf.StringVarP(&sf.MaterialName, "material-name", "m", "3DM-ABS @", "config.init entry 'materialName'")
+	sf.StringVarP(&sf.PrintSpeed, "print-speed", "s", defaultPrintSpeed, "Tilt/tower speed profile: 'slow', 'fast', or 'high_viscosity'")
 	sf.SetInterspersed(false)
 
 	return
 }
 
+// Get a string map from the metadata, if present
+func metadataMap(printable uv3dp.Printable, key string) (items map[string]string) {
+	items = map[string]string{}
+
+	anon, ok := printable.Metadata(key)
+	if ok {
+		mapped, ok := anon.(map[string]string)
+		if ok {
+			for attr, value := range mapped {
+				items[attr] = value
+			}
+		}
+	}
+
+	return
+}
+
+// Write a sorted 'attr = value' ini file
+func write_ini(archive *zip.Writer, filename string, items map[string]string) (err error) {
+	writer, err := archive.Create(filename)
+	if err != nil {
+		return
+	}
+
+	attrs := []string{}
+	for attr := range items {
+		attrs = append(attrs, attr)
+	}
+	sort.Strings(attrs)
+
+	for _, attr := range attrs {
+		_, err = fmt.Fprintf(writer, "%v = %v\n", attr, items[attr])
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
+
 func sl1Timestamp() (stamp string) {
 	now := time_Now().UTC()
 
@@ -160,6 +239,14 @@ func sl1Timestamp() (stamp string) {
 	return
 }
 
+// PreviewSizes returns the sizes of the thumbnails in the archive
+func (sf *Format) PreviewSizes() map[uv3dp.PreviewType]image.Point {
+	return map[uv3dp.PreviewType]image.Point{
+		uv3dp.PreviewTypeTiny: {X: 400, Y: 400},
+		uv3dp.PreviewTypeHuge: {X: 800, Y: 480},
+	}
+}
+
 func (sf *Format) Encode(writer uv3dp.Writer, printable uv3dp.Printable) (err error) {
 	archive := zip.NewWriter(writer)
 	defer archive.Close()
@@ -176,9 +263,62 @@ func (sf *Format) Encode(writer uv3dp.Writer, printable uv3dp.Printable) (err er
 		materialName += layerHeight
 	}
 
-	config_ini := map[string]string{
+	prusaslicer_ini := metadataMap(printable, "sl1/prusaslicer.ini")
+
+	printSpeed, ok := prusaslicer_ini["material_print_speed"]
+	if sf.Changed("print-speed") || !ok {
+		printSpeed = sf.PrintSpeed
+	}
+
+	_, ok = speedProfiles[sf.PrinterModel][printSpeed]
+	if !ok {
+		err = fmt.Errorf("unknown --print-speed '%v'", printSpeed)
+		return
+	}
+
+	// Create all the layers, and compute the resin used
+	pixelArea := float64(size.Millimeter.X) / float64(size.X) * float64(size.Millimeter.Y) / float64(size.Y)
+	usedMaterial := float64(0.0)
+
+	uv3dp.WithEachLayer(printable, func(p uv3dp.Printable, n int) {
+		filename := fmt.Sprintf("%s%05d.png", defaultJobDir, n)
+
+		var writer io.Writer
+		writer, err = archive.Create(filename)
+		if err != nil {
+			return
+		}
+
+		layerImage := p.LayerImage(n)
+
+		err = png.Encode(writer, layerImage)
+		if err != nil {
+			return
+		}
+
+		thickness := p.LayerZ(n)
+		if n > 0 {
+			thickness -= p.LayerZ(n - 1)
+		}
+
+		lit := uint64(0)
+		for _, pix := range layerImage.Pix {
+			lit += uint64(pix)
+		}
+
+		// Volume in ml (1000 cubic mm)
+		usedMaterial += float64(lit) / 255.0 * pixelArea * float64(thickness) / 1000.0
+	})
+
+	if err != nil {
+		return
+	}
+
+	config_ini := metadataMap(printable, "sl1/config.ini")
+
+	generated := map[string]string{
 		"action":                "print",
-		"jobDir":                "uv3dp",
+		"jobDir":                defaultJobDir,
 		"expTime":               fmt.Sprintf("%.3g", exp.LightOnTime),
 		"expTimeFirst":          fmt.Sprintf("%.3g", bot.LightOnTime),
 		"fileCreationTimestamp": sl1Timestamp(),
@@ -189,43 +329,43 @@ func (sf *Format) Encode(writer uv3dp.Writer, printable uv3dp.Printable) (err er
 		"numSlow":               fmt.Sprintf("%v", bot_slow),
 		"printProfile":          layerHeight + " Normal",
 		"printTime":             fmt.Sprintf("%.3f", float32(uv3dp.PrintDuration(printable))/float32(time.Second)),
-		"printerModel":          "SL1",
-		"printerProfile":        "Original Prusa SL1",
+		"printerModel":          sf.PrinterModel,
+		"printerProfile":        printerProfiles[sf.PrinterModel],
 		"prusaSlicerVersion":    "uv3dp",
-		"usedMaterial":          "0.0", // TODO: Calculate this properly!
+		"usedMaterial":          fmt.Sprintf("%.3f", usedMaterial),
+	}
+
+	for attr, value := range generated {
+		config_ini[attr] = value
 	}
 
 	// Create the config file
-	fileConfig, err := archive.Create("config.ini")
+	err = write_ini(archive, "config.ini", config_ini)
 	if err != nil {
 		return
 	}
 
-	attrs := []string{}
-	for attr := range config_ini {
-		attrs = append(attrs, attr)
+	// Create the PrusaSlicer config file
+	prusaslicer := map[string]string{
+		"display_height":        fmt.Sprintf("%.3f", size.Millimeter.X),
+		"display_width":         fmt.Sprintf("%.3f", size.Millimeter.Y),
+		"display_pixels_x":      fmt.Sprintf("%v", size.Y),
+		"display_pixels_y":      fmt.Sprintf("%v", size.X),
+		"exposure_time":         fmt.Sprintf("%.3g", exp.LightOnTime),
+		"initial_exposure_time": fmt.Sprintf("%.3g", bot.LightOnTime),
+		"layer_height":          layerHeight,
+		"material_print_speed":  printSpeed,
+		"printer_model":         sf.PrinterModel,
 	}
-	sort.Strings(attrs)
 
-	for _, attr := range attrs {
-		fmt.Fprintf(fileConfig, "%v = %v\n", attr, config_ini[attr])
+	for attr, value := range prusaslicer {
+		prusaslicer_ini[attr] = value
 	}
 
-	// Create all the layers
-	uv3dp.WithEachLayer(printable, func(p uv3dp.Printable, n int) {
-		filename := fmt.Sprintf("%s%05d.png", config_ini["jobDir"], n)
-
-		var writer io.Writer
-		writer, err = archive.Create(filename)
-		if err != nil {
-			return
-		}
-
-		err = png.Encode(writer, p.LayerImage(n))
-		if err != nil {
-			return
-		}
-	})
+	err = write_ini(archive, "prusaslicer.ini", prusaslicer_ini)
+	if err != nil {
+		return
+	}
 
 	// Save the thumbnails
 	previews := []uv3dp.PreviewType{
@@ -268,8 +408,11 @@ func read_ini(file *zip.File) (map[string]string, error) {
 
 	scanner := bufio.NewScanner(prusacfg_reader)
 	for scanner.Scan() {
-		line := scanner.Text()
+		line := strings.TrimRight(scanner.Text(), "\r")
 		fields := strings.SplitN(line, " = ", 2)
+		if len(fields) != 2 {
+			continue
+		}
 		retmap[fields[0]] = fields[1]
 	}
 
@@ -311,6 +454,15 @@ func (sf *Format) Decode(reader uv3dp.Reader, filesize int64) (printable uv3dp.P
 		return
 	}
 
+	// Preserve the unrecognized config.ini entries
+	unknown_map := map[string]string{}
+	for key, value := range config_map {
+		unknown_map[key] = value
+	}
+	for _, key := range configKeys {
+		delete(unknown_map, key)
+	}
+
 	for key := range prusacfg_map {
 		_, contained := config_map[key]
 		if !contained {
@@ -385,15 +537,26 @@ func (sf *Format) Decode(reader uv3dp.Reader, filesize int64) (printable uv3dp.P
 	size.Millimeter.Y = config.MillimeterY
 	size.LayerHeight = config.layerHeight
 
+	printerModel := config_map["printerModel"]
+	profiles, ok := speedProfiles[printerModel]
+	if !ok {
+		profiles = speedProfiles["SL1"]
+	}
+
+	motion, ok := profiles[prusacfg_map["material_print_speed"]]
+	if !ok {
+		motion = profiles[defaultPrintSpeed]
+	}
+
 	bot := &prop.Bottom
-	bot.Exposure = defaultBottomExposure
+	bot.Exposure = motion
 	bot.Exposure.LightOnTime = config.expTimeFirst
 
 	bot.Transition = int(config.numFade)
 	bot.Count = int(config.numSlow)
 
 	exp := &prop.Exposure
-	*exp = defaultExposure
+	*exp = motion
 	exp.LightOnTime = config.expTime
 
 	// Calculate layer off time based off of total print time
@@ -410,6 +573,11 @@ func (sf *Format) Decode(reader uv3dp.Reader, filesize int64) (printable uv3dp.P
 
 	prop.Preview = thumbImage
 
+	prop.Metadata = map[string]interface{}{
+		"sl1/config.ini":      unknown_map,
+		"sl1/prusaslicer.ini": prusacfg_map,
+	}
+
 	sl1 := &Print{
 		Print:    uv3dp.Print{Properties: prop},
 		layerPng: layerPng,
@@ -9,6 +9,8 @@ import (
 	"archive/zip"
 	"bytes"
 	"image"
+	"image/color"
+	"image/draw"
 	"image/png"
 	"io"
 	"strings"
@@ -30,6 +32,10 @@ func (br *bufferReader) ReadAt(p []byte, off int64) (n int, err error) {
 	return
 }
 
+func (br *bufferReader) Read(p []byte) (n int, err error) {
+	return 0, io.EOF
+}
+
 func (br *bufferReader) Len() int64 {
 	return int64(len(br.data))
 }
@@ -89,7 +95,18 @@ printTime = 100.520
 printerModel = SL1
 printerProfile = Original Prusa SL1
 prusaSlicerVersion = uv3dp
-usedMaterial = 0.0
+usedMaterial = 0.000
+`
+
+	testPrusaSlicerIni = `display_height = 20.000
+display_pixels_x = 20
+display_pixels_y = 10
+display_width = 40.000
+exposure_time = 16.5
+initial_exposure_time = 16.5
+layer_height = 0.05
+material_print_speed = fast
+printer_model = SL1
 `
 )
 
@@ -102,11 +119,12 @@ func TestEncodeEmptySl1(t *testing.T) {
 	png_empty := buffPng.Bytes()
 
 	expected_zip := map[string]([]byte){
-		"config.ini":     []byte(testConfigIni),
-		"uv3dp00000.png": png_empty,
-		"uv3dp00001.png": png_empty,
-		"uv3dp00002.png": png_empty,
-		"uv3dp00003.png": png_empty,
+		"config.ini":      []byte(testConfigIni),
+		"prusaslicer.ini": []byte(testPrusaSlicerIni),
+		"uv3dp00000.png":  png_empty,
+		"uv3dp00001.png":  png_empty,
+		"uv3dp00002.png":  png_empty,
+		"uv3dp00003.png":  png_empty,
 	}
 
 	empty := uv3dp.NewEmptyPrintable(testProperties)
@@ -145,3 +163,87 @@ func TestEncodeEmptySl1(t *testing.T) {
 		}
 	}
 }
+
+type litPrint struct {
+	uv3dp.Print
+}
+
+func (lp *litPrint) LayerImage(index int) (gi *image.Gray) {
+	gi = image.NewGray(lp.Bounds())
+	draw.Draw(gi, gi.Bounds(), &image.Uniform{C: color.Gray{Y: 0xff}}, image.Point{}, draw.Src)
+	return
+}
+
+func TestSl1sRoundTrip(t *testing.T) {
+	time_Now = func() (now time.Time) { return }
+
+	prop := testProperties
+	prop.Metadata = map[string]interface{}{
+		"sl1/config.ini": map[string]string{
+			"hollow": "0",
+		},
+		"sl1/prusaslicer.ini": map[string]string{
+			"material_print_speed": "slow",
+			"printer_variant":      "default",
+		},
+	}
+
+	lit := &litPrint{Print: uv3dp.Print{Properties: prop}}
+
+	formatter := NewFormatter(".sl1s")
+
+	buffWriter := &bytes.Buffer{}
+	err := formatter.Encode(buffWriter, lit)
+	if err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+
+	buffReader := &bufferReader{buffWriter.Bytes()}
+
+	archive, _ := zip.NewReader(buffReader, buffReader.Len())
+
+	config_map := map[string]string{}
+	for _, file := range archive.File {
+		if file.Name == "config.ini" {
+			config_map, err = read_ini(file)
+			if err != nil {
+				t.Fatalf("config.ini: %v", err)
+			}
+		}
+	}
+
+	expected := map[string]string{
+		"hollow":         "0",
+		"printerModel":   "SL1S",
+		"printerProfile": "Original Prusa SL1S SPEED",
+		// 10x20 pixels, 2x2mm each, 4 layers of 0.05mm => 160 cubic mm
+		"usedMaterial": "0.160",
+	}
+
+	for attr, value := range expected {
+		if config_map[attr] != value {
+			t.Errorf("config.ini %v: expected '%v', got '%v'", attr, value, config_map[attr])
+		}
+	}
+
+	printable, err := formatter.Decode(buffReader, buffReader.Len())
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+
+	if printable.Exposure().LiftSpeed != speedProfiles["SL1S"]["slow"].LiftSpeed {
+		t.Errorf("lift speed: expected %v, got %v", speedProfiles["SL1S"]["slow"].LiftSpeed, printable.Exposure().LiftSpeed)
+	}
+
+	anon, _ := printable.Metadata("sl1/config.ini")
+	unknown, _ := anon.(map[string]string)
+	if len(unknown) != 1 || unknown["hollow"] != "0" {
+		t.Errorf("sl1/config.ini: expected only 'hollow', got %v", unknown)
+	}
+
+	anon, _ = printable.Metadata("sl1/prusaslicer.ini")
+	prusaslicer, _ := anon.(map[string]string)
+	if prusaslicer["printer_variant"] != "default" || prusaslicer["printer_model"] != "SL1S" {
+		t.Errorf("sl1/prusaslicer.ini: unexpected %v", prusaslicer)
+	}
+}
@@ -2,7 +2,7 @@
 // Copyright (c) 2020 Jason S. McMullan <jason.mcmullan@gmail.com>
 //
 
-// Package sl1 handles input and output of Prusa SL1 DLP/LCD printables
+// Package sl1 handles input and output of Prusa SL1 and SL1S DLP/LCD printables
 package sl1
 
 import (
@@ -13,12 +13,20 @@ var (
 	machines_sl1 = map[string]uv3dp.Machine{
 		"sl1": {Vendor: "Prusa", Model: "SL1", Size: uv3dp.MachineSize{X: 1440, Y: 2560, Xmm: 68.04, Ymm: 120.96}},
 	}
+	machines_sl1s = map[string]uv3dp.Machine{
+		"sl1s": {Vendor: "Prusa", Model: "SL1S", Size: uv3dp.MachineSize{X: 1620, Y: 2560, Xmm: 80.64, Ymm: 127.008}},
+	}
 )
 
 func init() {
 	newFormatter := func(suffix string) uv3dp.Formatter { return NewFormatter(suffix) }
 
 	uv3dp.RegisterFormatter(".sl1", newFormatter)
+	uv3dp.RegisterFormatter(".sl1s", newFormatter)
 
 	uv3dp.RegisterMachines(machines_sl1, ".sl1")
+	uv3dp.RegisterMachines(machines_sl1s, ".sl1s")
+
+	uv3dp.RegisterMetadata("sl1/config.ini", map[string]string{})
+	uv3dp.RegisterMetadata("sl1/prusaslicer.ini", map[string]string{})
 }
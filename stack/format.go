@@ -0,0 +1,458 @@
+//
+// Copyright (c) 2020 Jason S. McMullan <jason.mcmullan@gmail.com>
+//
+
+package stack
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ezrec/uv3dp"
+	"github.com/spf13/pflag"
+)
+
+const (
+	defaultPixelSize   = 50.0 // Pixel pitch in microns
+	defaultLayerHeight = 0.05 // Layer height in mm
+	defaultBits        = 8
+
+	sidecarName = "stack.json"
+)
+
+type StackLayer struct {
+	Z        float32
+	Exposure uv3dp.Exposure
+}
+
+// StackConfig is the sidecar JSON description of a stack
+type StackConfig struct {
+	Properties uv3dp.Properties
+	Layers     []StackLayer
+}
+
+type Print struct {
+	uv3dp.Print
+	Layers []StackLayer
+
+	layerImage func(index int) (image.Image, error)
+}
+
+type Format struct {
+	*pflag.FlagSet
+
+	suffix string
+
+	Bits        int
+	PixelSize   float32
+	LayerHeight float32
+}
+
+func NewFormatter(suffix string) (sf *Format) {
+	flagSet := pflag.NewFlagSet(suffix, pflag.ContinueOnError)
+
+	sf = &Format{
+		FlagSet: flagSet,
+		suffix:  suffix,
+	}
+
+	sf.IntVarP(&sf.Bits, "bits", "b", defaultBits, "Bits per pixel of the written images (1 or 8)")
+	sf.Float32VarP(&sf.PixelSize, "pixel-size", "x", defaultPixelSize, "Pixel pitch in microns, when there is no sidecar JSON")
+	sf.Float32VarP(&sf.LayerHeight, "layer-height", "l", defaultLayerHeight, "Layer height in mm, when there is no sidecar JSON")
+	sf.SetInterspersed(false)
+
+	return
+}
+
+func (sf *Format) isDirectory() bool {
+	return sf.suffix == ".stack"
+}
+
+// Sidecar JSON filename for a stack
+func (sf *Format) sidecar(filename string) string {
+	if sf.isDirectory() {
+		return filepath.Join(filename, sidecarName)
+	}
+
+	return strings.TrimSuffix(filename, sf.suffix) + ".json"
+}
+
+func (sf *Format) checkBits() (err error) {
+	if sf.Bits != 1 && sf.Bits != 8 {
+		err = fmt.Errorf("illegal --bits setting: %v (must be 1 or 8)", sf.Bits)
+	}
+	return
+}
+
+// Quantize a layer to the gray levels of the requested bit depth
+func (sf *Format) quantizeLayer(gray *image.Gray) (out *image.Gray) {
+	if sf.Bits == 8 {
+		return gray
+	}
+
+	return uv3dp.QuantizeLayer(gray, 1<<sf.Bits)
+}
+
+// Convert a layer to the requested bit depth
+func (sf *Format) layerImage(gray *image.Gray) (out image.Image) {
+	if sf.Bits == 8 {
+		return gray
+	}
+
+	gray = sf.quantizeLayer(gray)
+
+	bounds := gray.Bounds()
+	bilevel := image.NewPaletted(bounds, color.Palette{color.Gray{Y: 0x00}, color.Gray{Y: 0xff}})
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		src := gray.Pix[gray.PixOffset(bounds.Min.X, y):]
+		dst := bilevel.Pix[bilevel.PixOffset(bounds.Min.X, y):]
+		for x := 0; x < bounds.Dx(); x++ {
+			if src[x] >= 0x80 {
+				dst[x] = 1
+			}
+		}
+	}
+
+	return bilevel
+}
+
+func stackConfig(printable uv3dp.Printable) (config StackConfig) {
+	prop := uv3dp.Properties{
+		Size:     printable.Size(),
+		Exposure: printable.Exposure(),
+		Bottom:   printable.Bottom(),
+	}
+
+	config = StackConfig{
+		Properties: prop,
+		Layers:     make([]StackLayer, prop.Size.Layers),
+	}
+
+	for n := range config.Layers {
+		config.Layers[n] = StackLayer{
+			Z:        printable.LayerZ(n),
+			Exposure: printable.LayerExposure(n),
+		}
+	}
+
+	return
+}
+
+func (sf *Format) Encode(writer uv3dp.Writer, printable uv3dp.Printable) (err error) {
+	if sf.isDirectory() {
+		err = errors.New("directory stacks can only be written to a filename")
+		return
+	}
+
+	err = sf.checkBits()
+	if err != nil {
+		return
+	}
+
+	size := printable.Size()
+
+	tw := &tiffWriter{
+		Width:  size.X,
+		Height: size.Y,
+		Pages:  size.Layers,
+		Bits:   sf.Bits,
+		Resolution: [2]float64{
+			float64(size.X) / float64(size.Millimeter.X) * 10.0,
+			float64(size.Y) / float64(size.Millimeter.Y) * 10.0,
+		},
+	}
+
+	err = tw.WriteHeader(writer)
+	if err != nil {
+		return
+	}
+
+	layerChan := make([](chan *image.Gray), size.Layers)
+	for n := range layerChan {
+		layerChan[n] = make(chan *image.Gray, 1)
+	}
+
+	go uv3dp.WithAllLayers(printable, func(p uv3dp.Printable, n int) {
+		layerChan[n] <- sf.quantizeLayer(p.LayerImage(n))
+		close(layerChan[n])
+	})
+
+	for n, done := range layerChan {
+		gray := <-done
+		if err != nil {
+			continue
+		}
+
+		err = tw.WritePage(writer, n, gray)
+	}
+
+	return
+}
+
+func (sf *Format) EncodeFile(filename string, printable uv3dp.Printable) (err error) {
+	err = sf.checkBits()
+	if err != nil {
+		return
+	}
+
+	if sf.isDirectory() {
+		err = os.MkdirAll(filename, 0755)
+		if err != nil {
+			return
+		}
+
+		uv3dp.WithEachLayer(printable, func(p uv3dp.Printable, n int) {
+			var writer *os.File
+			writer, err = os.Create(filepath.Join(filename, fmt.Sprintf("%08d.png", n)))
+			if err != nil {
+				return
+			}
+			defer writer.Close()
+
+			err = png.Encode(writer, sf.layerImage(p.LayerImage(n)))
+		})
+	} else {
+		var writer *os.File
+		writer, err = os.Create(filename)
+		if err != nil {
+			return
+		}
+		defer writer.Close()
+
+		err = sf.Encode(writer, printable)
+	}
+
+	if err != nil {
+		return
+	}
+
+	data, err := json.MarshalIndent(stackConfig(printable), "", "  ")
+	if err != nil {
+		return
+	}
+
+	data = append(data, '\n')
+
+	err = os.WriteFile(sf.sidecar(filename), data, 0644)
+
+	return
+}
+
+// Default properties, when there is no sidecar JSON
+func (sf *Format) defaultConfig(layers int, bounds image.Rectangle) (config StackConfig) {
+	prop := &config.Properties
+
+	size := &prop.Size
+	size.X = bounds.Dx()
+	size.Y = bounds.Dy()
+	size.Millimeter.X = float32(size.X) * sf.PixelSize / 1000.0
+	size.Millimeter.Y = float32(size.Y) * sf.PixelSize / 1000.0
+	size.Layers = layers
+	size.LayerHeight = sf.LayerHeight
+
+	prop.Exposure.LightPWM = 255
+	prop.Bottom.Exposure.LightPWM = 255
+
+	return
+}
+
+// Load the sidecar JSON, if present
+func (sf *Format) loadConfig(filename string) (config StackConfig, found bool, err error) {
+	data, err := os.ReadFile(sf.sidecar(filename))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			err = nil
+		}
+		return
+	}
+
+	// Set some non-zero defaults
+	config.Properties.Exposure.LightPWM = 255
+	config.Properties.Bottom.Exposure.LightPWM = 255
+
+	err = json.Unmarshal(data, &config)
+	if err != nil {
+		err = fmt.Errorf("%s: %w", sf.sidecar(filename), err)
+		return
+	}
+
+	if len(config.Layers) > 0 && len(config.Layers) != config.Properties.Size.Layers {
+		err = fmt.Errorf("%s: expected %v layers, found %v layers", sf.sidecar(filename), config.Properties.Size.Layers, len(config.Layers))
+		return
+	}
+
+	found = true
+
+	return
+}
+
+func (sf *Format) decodeTiff(data []byte, config StackConfig, found bool) (printable uv3dp.Printable, err error) {
+	order, ifds, err := tiffPages(data)
+	if err != nil {
+		return
+	}
+
+	if len(ifds) == 0 {
+		err = errors.New("no pages found in TIFF file")
+		return
+	}
+
+	if !found {
+		var pic image.Image
+		pic, err = decodeTiffPage(data, order, ifds[0])
+		if err != nil {
+			return
+		}
+		config = sf.defaultConfig(len(ifds), pic.Bounds())
+	}
+
+	if config.Properties.Size.Layers > len(ifds) {
+		err = fmt.Errorf("expected %v pages, found %v pages", config.Properties.Size.Layers, len(ifds))
+		return
+	}
+
+	printable = &Print{
+		Print:  uv3dp.Print{Properties: config.Properties},
+		Layers: config.Layers,
+		layerImage: func(index int) (pic image.Image, err error) {
+			return decodeTiffPage(data, order, ifds[index])
+		},
+	}
+
+	return
+}
+
+func (sf *Format) Decode(reader uv3dp.Reader, filesize int64) (printable uv3dp.Printable, err error) {
+	if sf.isDirectory() {
+		err = errors.New("directory stacks can only be read from a filename")
+		return
+	}
+
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return
+	}
+
+	printable, err = sf.decodeTiff(data, StackConfig{}, false)
+
+	return
+}
+
+func (sf *Format) DecodeFile(filename string) (printable uv3dp.Printable, err error) {
+	config, found, err := sf.loadConfig(filename)
+	if err != nil {
+		return
+	}
+
+	if !sf.isDirectory() {
+		var data []byte
+		data, err = os.ReadFile(filename)
+		if err != nil {
+			return
+		}
+
+		printable, err = sf.decodeTiff(data, config, found)
+		return
+	}
+
+	entries, err := os.ReadDir(filename)
+	if err != nil {
+		return
+	}
+
+	names := []string{}
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.EqualFold(filepath.Ext(entry.Name()), ".png") {
+			names = append(names, entry.Name())
+		}
+	}
+
+	if len(names) == 0 {
+		err = fmt.Errorf("%s: no PNG files found", filename)
+		return
+	}
+
+	sort.Slice(names, func(i, j int) bool { return uv3dp.NaturalLess(names[i], names[j]) })
+
+	readPng := func(index int) (pic image.Image, err error) {
+		data, err := os.ReadFile(filepath.Join(filename, names[index]))
+		if err != nil {
+			return
+		}
+
+		pic, err = png.Decode(bytes.NewReader(data))
+		if err != nil {
+			err = fmt.Errorf("%s: %w", names[index], err)
+		}
+		return
+	}
+
+	if !found {
+		var pic image.Image
+		pic, err = readPng(0)
+		if err != nil {
+			return
+		}
+		config = sf.defaultConfig(len(names), pic.Bounds())
+	}
+
+	if config.Properties.Size.Layers > len(names) {
+		err = fmt.Errorf("%s: expected %v images, found %v images", filename, config.Properties.Size.Layers, len(names))
+		return
+	}
+
+	printable = &Print{
+		Print:      uv3dp.Print{Properties: config.Properties},
+		Layers:     config.Layers,
+		layerImage: readPng,
+	}
+
+	return
+}
+
+func (sp *Print) LayerZ(index int) (z float32) {
+	if len(sp.Layers) == 0 {
+		z = sp.Print.LayerZ(index)
+	} else {
+		z = sp.Layers[index].Z
+	}
+
+	return
+}
+
+func (sp *Print) LayerExposure(index int) (exposure uv3dp.Exposure) {
+	if len(sp.Layers) == 0 {
+		exposure = sp.Print.LayerExposure(index)
+	} else {
+		exposure = sp.Layers[index].Exposure
+	}
+
+	return
+}
+
+func (sp *Print) LayerImage(index int) (layerImage *image.Gray) {
+	pic, err := sp.layerImage(index)
+	if err != nil {
+		err = fmt.Errorf("layer %v: %w", index, err)
+		panic(err)
+	}
+
+	layerImage, ok := pic.(*image.Gray)
+	if !ok {
+		layerImage = image.NewGray(pic.Bounds())
+		draw.Draw(layerImage, layerImage.Bounds(), pic, pic.Bounds().Min, draw.Src)
+	}
+
+	return
+}
@@ -0,0 +1,228 @@
+//
+// Copyright (c) 2020 Jason S. McMullan <jason.mcmullan@gmail.com>
+//
+
+package stack
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+
+	"testing"
+
+	"github.com/ezrec/uv3dp"
+	"github.com/google/go-cmp/cmp"
+)
+
+var (
+	testProperties = uv3dp.Properties{
+		Size: uv3dp.Size{
+			X: 13,
+			Y: 20,
+			Millimeter: uv3dp.SizeMillimeter{
+				X: 26.0,
+				Y: 40.0,
+			},
+			Layers:      4,
+			LayerHeight: 0.05,
+		},
+		Exposure: uv3dp.Exposure{
+			LightOnTime:  16.5,
+			LightOffTime: 2.25,
+			LightPWM:     255,
+			LiftHeight:   5.5,
+			LiftSpeed:    120.0,
+		},
+		Bottom: uv3dp.Bottom{
+			Count: 2,
+			Exposure: uv3dp.Exposure{
+				LightOnTime:  30.0,
+				LightOffTime: 2.25,
+				LightPWM:     255,
+				LiftHeight:   6.5,
+				LiftSpeed:    60.0,
+			},
+		},
+	}
+)
+
+type gradientPrint struct {
+	uv3dp.Print
+}
+
+func (gp *gradientPrint) LayerImage(index int) (gi *image.Gray) {
+	gi = image.NewGray(gp.Bounds())
+	for n := range gi.Pix {
+		gi.Pix[n] = uint8(n*7 + index*31)
+	}
+
+	return
+}
+
+func thresholded(gi *image.Gray) (out []uint8) {
+	out = make([]uint8, len(gi.Pix))
+	for n, pix := range gi.Pix {
+		if pix >= 0x80 {
+			out[n] = 0xff
+		}
+	}
+
+	return
+}
+
+func TestStackRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+
+	table := []struct {
+		Filename string
+		Suffix   string
+		Bits     int
+	}{
+		{"gray.tif", ".tif", 8},
+		{"bilevel.tiff", ".tiff", 1},
+		{"gray.stack", ".stack", 8},
+		{"bilevel.stack", ".stack", 1},
+	}
+
+	gradient := &gradientPrint{Print: uv3dp.Print{Properties: testProperties}}
+
+	for _, item := range table {
+		filename := filepath.Join(dir, item.Filename)
+
+		formatter := NewFormatter(item.Suffix)
+		formatter.Bits = item.Bits
+
+		err := formatter.EncodeFile(filename, gradient)
+		if err != nil {
+			t.Fatalf("%v: %v", item.Filename, err)
+		}
+
+		printable, err := NewFormatter(item.Suffix).DecodeFile(filename)
+		if err != nil {
+			t.Fatalf("%v: %v", item.Filename, err)
+		}
+
+		if !cmp.Equal(printable.Size(), testProperties.Size) {
+			t.Errorf("%v: %v", item.Filename, cmp.Diff(testProperties.Size, printable.Size()))
+		}
+
+		if !cmp.Equal(printable.Bottom(), testProperties.Bottom) {
+			t.Errorf("%v: %v", item.Filename, cmp.Diff(testProperties.Bottom, printable.Bottom()))
+		}
+
+		for n := 0; n < testProperties.Size.Layers; n++ {
+			expected := gradient.LayerImage(n).Pix
+			if item.Bits == 1 {
+				expected = thresholded(gradient.LayerImage(n))
+			}
+
+			if !bytes.Equal(printable.LayerImage(n).Pix, expected) {
+				t.Errorf("%v: layer %v mismatch", item.Filename, n)
+			}
+
+			if printable.LayerZ(n) != gradient.LayerZ(n) {
+				t.Errorf("%v: layer %v: expected Z %v, got %v", item.Filename, n, gradient.LayerZ(n), printable.LayerZ(n))
+			}
+		}
+	}
+}
+
+func TestStackQuantize(t *testing.T) {
+	dir := t.TempDir()
+
+	uv3dp.SetQuantizeMode(uv3dp.QuantizeOrdered)
+	defer uv3dp.SetQuantizeMode(uv3dp.QuantizeThreshold)
+
+	gradient := &gradientPrint{Print: uv3dp.Print{Properties: testProperties}}
+
+	for _, filename := range []string{"bilevel.tiff", "bilevel.stack"} {
+		filename = filepath.Join(dir, filename)
+		suffix := filepath.Ext(filename)
+
+		formatter := NewFormatter(suffix)
+		formatter.Bits = 1
+
+		err := formatter.EncodeFile(filename, gradient)
+		if err != nil {
+			t.Fatalf("%v: %v", filename, err)
+		}
+
+		printable, err := NewFormatter(suffix).DecodeFile(filename)
+		if err != nil {
+			t.Fatalf("%v: %v", filename, err)
+		}
+
+		for n := 0; n < testProperties.Size.Layers; n++ {
+			expected := uv3dp.QuantizeOrdered.Quantize(gradient.LayerImage(n), 2).Pix
+			if !bytes.Equal(printable.LayerImage(n).Pix, expected) {
+				t.Errorf("%v: layer %v was not dithered", filename, n)
+			}
+		}
+	}
+}
+
+func TestStackNoSidecar(t *testing.T) {
+	dir := t.TempDir()
+
+	names := []string{"slice10.png", "slice9.png", "slice1.png"}
+	for n, name := range names {
+		gi := image.NewGray(image.Rect(0, 0, 4, 3))
+		gi.Pix[0] = uint8(n + 1)
+
+		writer, err := os.Create(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		png.Encode(writer, gi)
+		writer.Close()
+	}
+
+	formatter := NewFormatter(".stack")
+	err := formatter.Parse([]string{"--pixel-size", "100", "--layer-height", "0.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	printable, err := formatter.DecodeFile(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	size := printable.Size()
+	expected := uv3dp.Size{
+		X:           4,
+		Y:           3,
+		Millimeter:  uv3dp.SizeMillimeter{X: 0.4, Y: 0.3},
+		Layers:      3,
+		LayerHeight: 0.1,
+	}
+
+	if !cmp.Equal(size, expected) {
+		t.Errorf("%v", cmp.Diff(expected, size))
+	}
+
+	// slice1, slice9, slice10
+	for n, first := range []uint8{3, 2, 1} {
+		got := printable.LayerImage(n).Pix[0]
+		if got != first {
+			t.Errorf("layer %v: expected %v, got %v", n, first, got)
+		}
+	}
+}
+
+func TestTiffTooLarge(t *testing.T) {
+	// A 3840x2400 8-bit page is ~9MiB, so ~470 pages pass 4GiB
+	for pages, fits := range map[int]bool{400: true, 500: false} {
+		tw := &tiffWriter{Width: 3840, Height: 2400, Pages: pages, Bits: 8}
+
+		err := tw.WriteHeader(&bytes.Buffer{})
+		if fits && err != nil {
+			t.Errorf("%v pages: %v", pages, err)
+		} else if !fits && err == nil {
+			t.Errorf("%v pages: expected an error", pages)
+		}
+	}
+}
@@ -0,0 +1,19 @@
+//
+// Copyright (c) 2020 Jason S. McMullan <jason.mcmullan@gmail.com>
+//
+
+// Package stack handles input and output of plain layer stacks
+// (directories of numbered PNGs, or multi-page TIFFs) with a sidecar JSON
+package stack
+
+import (
+	"github.com/ezrec/uv3dp"
+)
+
+func init() {
+	newFormatter := func(suffix string) uv3dp.Formatter { return NewFormatter(suffix) }
+
+	uv3dp.RegisterFormatter(".stack", newFormatter)
+	uv3dp.RegisterFormatter(".tif", newFormatter)
+	uv3dp.RegisterFormatter(".tiff", newFormatter)
+}
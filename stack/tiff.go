@@ -0,0 +1,258 @@
+//
+// Copyright (c) 2020 Jason S. McMullan <jason.mcmullan@gmail.com>
+//
+
+package stack
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"math"
+
+	"golang.org/x/image/tiff"
+)
+
+// TIFF tags used by the writer
+const (
+	tagImageWidth      = 256
+	tagImageLength     = 257
+	tagBitsPerSample   = 258
+	tagCompression     = 259
+	tagPhotometric     = 262
+	tagStripOffsets    = 273
+	tagSamplesPerPixel = 277
+	tagRowsPerStrip    = 278
+	tagStripByteCounts = 279
+	tagXResolution     = 282
+	tagYResolution     = 283
+	tagResolutionUnit  = 296
+	tagPageNumber      = 297
+)
+
+// TIFF field types used by the writer
+const (
+	dtShort    = 3
+	dtLong     = 4
+	dtRational = 5
+)
+
+const (
+	tiffHeaderLen  = 8
+	tiffEntryCount = 13
+	tiffIFDLen     = 2 + tiffEntryCount*12 + 4
+	tiffExtraLen   = 2 * 8 // X and Y resolution rationals
+)
+
+type tiffEntry struct {
+	Tag      uint16
+	Datatype uint16
+	Count    uint32
+	Value    uint32
+}
+
+// tiffWriter writes a multi-page, uncompressed, grayscale TIFF
+type tiffWriter struct {
+	Width, Height int
+	Pages         int
+	Bits          int        // 1 or 8 bits per sample
+	Resolution    [2]float64 // Pixels per cm, X and Y
+}
+
+func (tw *tiffWriter) rowLen() int {
+	return (tw.Width*tw.Bits + 7) / 8
+}
+
+func (tw *tiffWriter) dataLen() int {
+	return tw.rowLen() * tw.Height
+}
+
+func (tw *tiffWriter) pageLen() int {
+	// Keep the IFDs word aligned
+	return (tw.dataLen()+1)&^1 + tiffIFDLen + tiffExtraLen
+}
+
+func (tw *tiffWriter) pageOffset(n int) uint32 {
+	return uint32(tiffHeaderLen + n*tw.pageLen())
+}
+
+func (tw *tiffWriter) ifdOffset(n int) uint32 {
+	return tw.pageOffset(n) + uint32((tw.dataLen()+1)&^1)
+}
+
+// WriteHeader writes the TIFF file header
+func (tw *tiffWriter) WriteHeader(w io.Writer) (err error) {
+	// All the offsets must fit in 32 bits
+	if int64(tiffHeaderLen)+int64(tw.Pages)*int64(tw.pageLen()) > math.MaxUint32 {
+		err = errors.New("stack is too large for a TIFF file")
+		return
+	}
+
+	header := []byte{'I', 'I', 42, 0, 0, 0, 0, 0}
+	binary.LittleEndian.PutUint32(header[4:], tw.ifdOffset(0))
+
+	_, err = w.Write(header)
+	return
+}
+
+// WritePage writes the n'th page; pages must be written in order
+func (tw *tiffWriter) WritePage(w io.Writer, n int, gray *image.Gray) (err error) {
+	bounds := gray.Bounds()
+	if bounds.Dx() != tw.Width || bounds.Dy() != tw.Height {
+		err = fmt.Errorf("page %v: expected %vx%v, got %vx%v", n, tw.Width, tw.Height, bounds.Dx(), bounds.Dy())
+		return
+	}
+
+	data := make([]byte, tw.pageLen())
+
+	rowLen := tw.rowLen()
+	for y := 0; y < tw.Height; y++ {
+		src := gray.Pix[gray.PixOffset(bounds.Min.X, bounds.Min.Y+y):]
+		dst := data[y*rowLen : (y+1)*rowLen]
+		if tw.Bits == 8 {
+			copy(dst, src[:tw.Width])
+		} else {
+			for x := 0; x < tw.Width; x++ {
+				if src[x] >= 0x80 {
+					dst[x/8] |= 0x80 >> (x % 8)
+				}
+			}
+		}
+	}
+
+	next := uint32(0)
+	if n+1 < tw.Pages {
+		next = tw.ifdOffset(n + 1)
+	}
+
+	ifd := tw.ifdOffset(n)
+	extra := ifd + tiffIFDLen
+
+	entries := []tiffEntry{
+		{tagImageWidth, dtLong, 1, uint32(tw.Width)},
+		{tagImageLength, dtLong, 1, uint32(tw.Height)},
+		{tagBitsPerSample, dtShort, 1, uint32(tw.Bits)},
+		{tagCompression, dtShort, 1, 1}, // None
+		{tagPhotometric, dtShort, 1, 1}, // BlackIsZero
+		{tagStripOffsets, dtLong, 1, tw.pageOffset(n)},
+		{tagSamplesPerPixel, dtShort, 1, 1},
+		{tagRowsPerStrip, dtLong, 1, uint32(tw.Height)},
+		{tagStripByteCounts, dtLong, 1, uint32(tw.dataLen())},
+		{tagXResolution, dtRational, 1, extra},
+		{tagYResolution, dtRational, 1, extra + 8},
+		{tagResolutionUnit, dtShort, 1, 3}, // Centimeters
+		{tagPageNumber, dtShort, 2, uint32(n) | uint32(tw.Pages)<<16},
+	}
+
+	out := data[ifd-tw.pageOffset(n):]
+	binary.LittleEndian.PutUint16(out[0:], uint16(len(entries)))
+	for i, entry := range entries {
+		field := out[2+i*12:]
+		binary.LittleEndian.PutUint16(field[0:], entry.Tag)
+		binary.LittleEndian.PutUint16(field[2:], entry.Datatype)
+		binary.LittleEndian.PutUint32(field[4:], entry.Count)
+		binary.LittleEndian.PutUint32(field[8:], entry.Value)
+	}
+	binary.LittleEndian.PutUint32(out[2+len(entries)*12:], next)
+
+	out = out[tiffIFDLen:]
+	for i, res := range tw.Resolution {
+		binary.LittleEndian.PutUint32(out[i*8:], uint32(math.Round(res*1000)))
+		binary.LittleEndian.PutUint32(out[i*8+4:], 1000)
+	}
+
+	_, err = w.Write(data)
+	return
+}
+
+// tiffPages returns the byte order, and the offsets of all the IFDs in a TIFF file
+func tiffPages(data []byte) (order binary.ByteOrder, ifds []uint32, err error) {
+	if len(data) < tiffHeaderLen {
+		err = errors.New("TIFF header truncated")
+		return
+	}
+
+	switch string(data[0:4]) {
+	case "II\x2a\x00":
+		order = binary.LittleEndian
+	case "MM\x00\x2a":
+		order = binary.BigEndian
+	default:
+		err = errors.New("not a TIFF file")
+		return
+	}
+
+	seen := map[uint32]bool{}
+	for ifd := order.Uint32(data[4:]); ifd != 0; {
+		if seen[ifd] {
+			err = fmt.Errorf("IFD loop at offset %#x", ifd)
+			return
+		}
+		seen[ifd] = true
+
+		if int(ifd)+2 > len(data) {
+			err = fmt.Errorf("IFD at offset %#x truncated", ifd)
+			return
+		}
+		count := int(order.Uint16(data[ifd:]))
+		end := int(ifd) + 2 + count*12
+		if end+4 > len(data) {
+			err = fmt.Errorf("IFD at offset %#x truncated", ifd)
+			return
+		}
+
+		ifds = append(ifds, ifd)
+		ifd = order.Uint32(data[end:])
+	}
+
+	return
+}
+
+// pageReader presents a single page of a multi-page TIFF as if it were
+// the first page, by patching the first IFD offset in the header
+type pageReader struct {
+	data   []byte
+	header [4]byte
+	offset int64
+}
+
+func newPageReader(data []byte, order binary.ByteOrder, ifd uint32) (pr *pageReader) {
+	pr = &pageReader{data: data}
+	order.PutUint32(pr.header[:], ifd)
+	return
+}
+
+func (pr *pageReader) ReadAt(p []byte, off int64) (n int, err error) {
+	if off >= int64(len(pr.data)) {
+		err = io.EOF
+		return
+	}
+
+	n = copy(p, pr.data[off:])
+	for i := 0; i < n; i++ {
+		pos := off + int64(i)
+		if pos >= 4 && pos < 8 {
+			p[i] = pr.header[pos-4]
+		}
+	}
+
+	if n < len(p) {
+		err = io.EOF
+	}
+
+	return
+}
+
+func (pr *pageReader) Read(p []byte) (n int, err error) {
+	n, err = pr.ReadAt(p, pr.offset)
+	pr.offset += int64(n)
+	return
+}
+
+// decodeTiffPage decodes the page at the IFD offset
+func decodeTiffPage(data []byte, order binary.ByteOrder, ifd uint32) (pic image.Image, err error) {
+	pic, err = tiff.Decode(newPageReader(data, order, ifd))
+	return
+}
@@ -0,0 +1,277 @@
+//
+// Copyright (c) 2020 Jason S. McMullan <jason.mcmullan@gmail.com>
+//
+
+package stl
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"math"
+
+	"github.com/ezrec/uv3dp"
+	"github.com/spf13/pflag"
+)
+
+const (
+	defaultMachine     = "photon"
+	defaultLayerHeight = 0.05 // Layer height in mm
+	defaultAntiAlias   = 1
+	maxAntiAlias       = 16
+	defaultIsoLevel    = 127.5
+)
+
+type Print struct {
+	uv3dp.Print
+	Mesh *Mesh
+
+	slicer *slicer
+}
+
+type Format struct {
+	*pflag.FlagSet
+
+	suffix string
+
+	Machine     string
+	LayerHeight float32
+	Position    []float32
+	AntiAlias   int
+
+	Downsample int
+	Smooth     int
+	IsoLevel   float32
+	ASCII      bool
+}
+
+func NewFormatter(suffix string) (sf *Format) {
+	flagSet := pflag.NewFlagSet(suffix, pflag.ContinueOnError)
+
+	sf = &Format{
+		FlagSet: flagSet,
+		suffix:  suffix,
+	}
+
+	sf.StringVarP(&sf.Machine, "machine", "M", defaultMachine, "Target machine, sets the slice resolution and bed size")
+	sf.Float32VarP(&sf.LayerHeight, "layer-height", "l", defaultLayerHeight, "Layer height in mm")
+	sf.Float32SliceVarP(&sf.Position, "position", "P", []float32{0, 0}, "Offset of the model center from the bed center, in mm")
+	sf.IntVarP(&sf.AntiAlias, "anti-alias", "a", defaultAntiAlias, "Anti-aliasing supersampling, in samples per pixel edge (1 for none)")
+	sf.IntVarP(&sf.Downsample, "downsample", "d", 1, "Mesh output: downsample the layers to one voxel per N x N pixel block, before meshing")
+	sf.IntVarP(&sf.Smooth, "smooth", "s", 0, "Mesh output: passes of surface smoothing")
+	sf.Float32VarP(&sf.IsoLevel, "iso-level", "i", defaultIsoLevel, "Mesh output: gray level of the surface (anti-aliased edges are placed at sub-pixel positions)")
+	sf.BoolVar(&sf.ASCII, "ascii", false, "Mesh output: write ASCII instead of binary STL")
+	sf.SetInterspersed(false)
+
+	return
+}
+
+// Downsample a layer into N x N blocks
+func downsampleLayer(gray *image.Gray, n int, width, height int) (pix []uint8) {
+	pix = make([]uint8, width*height)
+	if n == 1 {
+		for y := 0; y < height; y++ {
+			copy(pix[y*width:(y+1)*width], gray.Pix[gray.PixOffset(gray.Rect.Min.X, gray.Rect.Min.Y+y):])
+		}
+		return
+	}
+
+	bounds := gray.Bounds()
+	for by := 0; by < height; by++ {
+		for bx := 0; bx < width; bx++ {
+			sum := 0
+			count := 0
+			for y := by * n; y < (by+1)*n && y < bounds.Dy(); y++ {
+				for x := bx * n; x < (bx+1)*n && x < bounds.Dx(); x++ {
+					sum += int(gray.GrayAt(bounds.Min.X+x, bounds.Min.Y+y).Y)
+					count++
+				}
+			}
+			pix[by*width+bx] = uint8((sum + count/2) / count)
+		}
+	}
+
+	return
+}
+
+// Encode reconstructs a mesh from the layer images
+func (sf *Format) Encode(writer uv3dp.Writer, printable uv3dp.Printable) (err error) {
+	if sf.Downsample < 1 {
+		err = fmt.Errorf("illegal --downsample setting: %v", sf.Downsample)
+		return
+	}
+
+	if sf.Smooth < 0 {
+		err = fmt.Errorf("illegal --smooth setting: %v", sf.Smooth)
+		return
+	}
+
+	if sf.IsoLevel <= 0 || sf.IsoLevel > 255 {
+		err = fmt.Errorf("illegal --iso-level setting: %v (must be greater than 0, up to 255)", sf.IsoLevel)
+		return
+	}
+
+	size := printable.Size()
+	if size.Layers < 1 {
+		err = errors.New("no layers to convert to a mesh")
+		return
+	}
+
+	block := sf.Downsample
+	width := (size.X + block - 1) / block
+	height := (size.Y + block - 1) / block
+
+	mc := newMarcher(width, height, float64(sf.IsoLevel))
+	mc.pitch = [2]float64{
+		float64(size.Millimeter.X) / float64(size.X) * float64(block),
+		float64(size.Millimeter.Y) / float64(size.Y) * float64(block),
+	}
+	mc.origin = [2]float64{
+		-float64(size.Millimeter.X) / 2,
+		float64(size.Millimeter.Y) / 2,
+	}
+
+	// Sample each layer in the middle of its thickness
+	layerHeight := float64(size.LayerHeight)
+	layerZ := func(index int) float64 {
+		return float64(printable.LayerZ(index)) - layerHeight/2
+	}
+
+	layerChan := make([](chan []uint8), size.Layers)
+	for n := range layerChan {
+		layerChan[n] = make(chan []uint8, 1)
+	}
+
+	go uv3dp.WithAllLayers(printable, func(p uv3dp.Printable, index int) {
+		layerChan[index] <- downsampleLayer(p.LayerImage(index), block, width, height)
+		close(layerChan[index])
+	})
+
+	// Empty planes below and above the print close the surface
+	lower := mc.newPlane(nil, layerZ(0)-layerHeight)
+	for index, done := range layerChan {
+		upper := mc.newPlane(<-done, layerZ(index))
+		mc.Slab(index-1, lower, upper)
+		lower = upper
+	}
+	mc.Slab(size.Layers-1, lower, mc.newPlane(nil, layerZ(size.Layers-1)+layerHeight))
+
+	surface := &mc.surface
+	if sf.Smooth > 0 {
+		surface.Smooth(sf.Smooth)
+	}
+
+	switch {
+	case sf.suffix == ".obj":
+		err = surface.WriteOBJ(writer)
+	case sf.ASCII:
+		err = surface.Mesh().WriteASCII(writer)
+	default:
+		err = surface.Mesh().WriteBinary(writer)
+	}
+
+	return
+}
+
+func (sf *Format) Decode(reader uv3dp.Reader, filesize int64) (printable uv3dp.Printable, err error) {
+	if sf.suffix == ".obj" {
+		err = errors.New("OBJ input is not supported")
+		return
+	}
+
+	machine, found := uv3dp.MachineFormats[sf.Machine]
+	if !found {
+		err = fmt.Errorf("unknown machine '%v'", sf.Machine)
+		return
+	}
+
+	if sf.LayerHeight <= 0 {
+		err = fmt.Errorf("illegal --layer-height setting: %v", sf.LayerHeight)
+		return
+	}
+
+	if sf.AntiAlias < 1 || sf.AntiAlias > maxAntiAlias {
+		err = fmt.Errorf("illegal --anti-alias setting: %v (must be 1 to %v)", sf.AntiAlias, maxAntiAlias)
+		return
+	}
+
+	if len(sf.Position) != 2 {
+		err = fmt.Errorf("illegal --position setting: expected X,Y")
+		return
+	}
+
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return
+	}
+
+	mesh, err := ReadMesh(data)
+	if err != nil {
+		return
+	}
+
+	if len(mesh.Triangles) == 0 {
+		err = errors.New("STL file has no facets")
+		return
+	}
+
+	min, max := mesh.Bounds()
+
+	msize := machine.Size
+
+	var prop uv3dp.Properties
+	size := &prop.Size
+	size.X = msize.X
+	size.Y = msize.Y
+	size.Millimeter.X = msize.Xmm
+	size.Millimeter.Y = msize.Ymm
+	size.LayerHeight = sf.LayerHeight
+	size.Layers = int(math.Ceil(float64(max[2]-min[2])/float64(sf.LayerHeight) - 1e-4))
+
+	if size.Layers < 1 {
+		err = errors.New("STL model has no height")
+		return
+	}
+
+	// The bed center is the center of the model, less the offset
+	center := [2]float64{
+		float64(min[0]+max[0])/2 - float64(sf.Position[0]),
+		float64(min[1]+max[1])/2 - float64(sf.Position[1]),
+	}
+
+	bed := [2]float64{float64(msize.Xmm), float64(msize.Ymm)}
+	for n := 0; n < 2; n++ {
+		half := bed[n] / 2
+		if float64(min[n]) < center[n]-half || float64(max[n]) > center[n]+half {
+			err = fmt.Errorf("model (%.2f x %.2f mm) at offset %v does not fit on the %v bed (%.2f x %.2f mm)",
+				max[0]-min[0], max[1]-min[1], sf.Position, sf.Machine, msize.Xmm, msize.Ymm)
+			return
+		}
+	}
+
+	prop.Exposure = uv3dp.DefaultExposure
+	prop.Bottom = uv3dp.DefaultBottom
+
+	sl := newSlicer(mesh, size.Layers, float64(min[2]), float64(sf.LayerHeight))
+	sl.width = size.X
+	sl.height = size.Y
+	sl.antiAlias = sf.AntiAlias
+	sl.center = center
+	sl.scale = [2]float64{
+		float64(size.X*sf.AntiAlias) / float64(size.Millimeter.X),
+		float64(size.Y*sf.AntiAlias) / float64(size.Millimeter.Y),
+	}
+
+	printable = &Print{
+		Print:  uv3dp.Print{Properties: prop},
+		Mesh:   mesh,
+		slicer: sl,
+	}
+
+	return
+}
+
+func (sp *Print) LayerImage(index int) (layerImage *image.Gray) {
+	return sp.slicer.Slice(index)
+}
@@ -0,0 +1,435 @@
+//
+// Copyright (c) 2020 Jason S. McMullan <jason.mcmullan@gmail.com>
+//
+
+package stl
+
+import (
+	"bytes"
+	"image"
+	"math"
+	"strings"
+
+	"testing"
+
+	"github.com/ezrec/uv3dp"
+)
+
+type bufferReader struct {
+	*bytes.Reader
+}
+
+func newBufferReader(data []byte) *bufferReader {
+	return &bufferReader{Reader: bytes.NewReader(data)}
+}
+
+func init() {
+	uv3dp.RegisterMachine("test-stl", uv3dp.Machine{Vendor: "Test", Model: "STL", Size: uv3dp.MachineSize{X: 100, Y: 80, Xmm: 20.0, Ymm: 16.0}}, ".stl")
+}
+
+// Axis aligned box, with outward facing facets
+func boxMesh(min, max Vertex) (mesh *Mesh) {
+	corner := func(n int) (v Vertex) {
+		for i := 0; i < 3; i++ {
+			if n&(1<<i) == 0 {
+				v[i] = min[i]
+			} else {
+				v[i] = max[i]
+			}
+		}
+		return
+	}
+
+	// Counter-clockwise quads, when viewed from outside
+	quads := [][4]int{
+		{0, 2, 3, 1}, // -Z
+		{4, 5, 7, 6}, // +Z
+		{0, 1, 5, 4}, // -Y
+		{2, 6, 7, 3}, // +Y
+		{0, 4, 6, 2}, // -X
+		{1, 3, 7, 5}, // +X
+	}
+
+	mesh = &Mesh{}
+	for _, q := range quads {
+		mesh.Triangles = append(mesh.Triangles,
+			Triangle{Vertex: [3]Vertex{corner(q[0]), corner(q[1]), corner(q[2])}},
+			Triangle{Vertex: [3]Vertex{corner(q[0]), corner(q[2]), corner(q[3])}},
+		)
+	}
+
+	return
+}
+
+func binarySTL(mesh *Mesh) []byte {
+	buff := &bytes.Buffer{}
+	mesh.WriteBinary(buff)
+	return buff.Bytes()
+}
+
+func asciiSTL(mesh *Mesh) []byte {
+	buff := &bytes.Buffer{}
+	mesh.WriteASCII(buff)
+	return buff.Bytes()
+}
+
+func TestReadMesh(t *testing.T) {
+	box := boxMesh(Vertex{-1, -2, 0}, Vertex{3, 4, 5})
+
+	for name, data := range map[string][]byte{"binary": binarySTL(box), "ascii": asciiSTL(box)} {
+		mesh, err := ReadMesh(data)
+		if err != nil {
+			t.Fatalf("%v: %v", name, err)
+		}
+
+		if len(mesh.Triangles) != 12 {
+			t.Errorf("%v: expected 12 facets, got %v", name, len(mesh.Triangles))
+		}
+
+		min, max := mesh.Bounds()
+		if min != (Vertex{-1, -2, 0}) || max != (Vertex{3, 4, 5}) {
+			t.Errorf("%v: unexpected bounds %v - %v", name, min, max)
+		}
+	}
+
+	_, err := ReadMesh([]byte("not a mesh"))
+	if err == nil {
+		t.Errorf("expected an error for a non-STL file")
+	}
+}
+
+func TestSliceBox(t *testing.T) {
+	// 10 x 8 x 1 mm box, offset in model space
+	box := boxMesh(Vertex{100, 200, 10}, Vertex{110, 208, 11})
+
+	formatter := NewFormatter(".stl")
+	err := formatter.Parse([]string{"--machine", "test-stl"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	printable, err := formatter.Decode(newBufferReader(binarySTL(box)), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	size := printable.Size()
+	if size.X != 100 || size.Y != 80 || size.Layers != 20 {
+		t.Fatalf("unexpected size %+v", size)
+	}
+
+	// 0.2mm pixels, so the box is 50x40 pixels in the center of the bed
+	expected := checkCenteredBox(t, printable.LayerImage(0).Pix)
+	for n := 1; n < size.Layers; n++ {
+		if !bytes.Equal(printable.LayerImage(n).Pix, expected) {
+			t.Errorf("layer %v: mismatch", n)
+		}
+	}
+}
+
+func checkCenteredBox(t *testing.T, pix []uint8) []uint8 {
+	for y := 0; y < 80; y++ {
+		for x := 0; x < 100; x++ {
+			var expected uint8
+			if x >= 25 && x < 75 && y >= 20 && y < 60 {
+				expected = 0xff
+			}
+			if pix[y*100+x] != expected {
+				t.Fatalf("(%v,%v): expected %#x, got %#x", x, y, expected, pix[y*100+x])
+			}
+		}
+	}
+
+	return pix
+}
+
+func TestSliceAntiAlias(t *testing.T) {
+	// Box with a hole, half a pixel off the grid in X
+	outer := boxMesh(Vertex{-4.9, -4, 0}, Vertex{5.1, 4, 0.1})
+	inner := boxMesh(Vertex{-2, -2, 0}, Vertex{2, 2, 0.1})
+	for n := range inner.Triangles {
+		tri := &inner.Triangles[n]
+		tri.Vertex[1], tri.Vertex[2] = tri.Vertex[2], tri.Vertex[1]
+	}
+
+	mesh := &Mesh{Triangles: append(outer.Triangles, inner.Triangles...)}
+
+	formatter := NewFormatter(".stl")
+	err := formatter.Parse([]string{"-M", "test-stl", "-a", "4", "--position", "0.1,0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	printable, err := formatter.Decode(newBufferReader(asciiSTL(mesh)), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if printable.Size().Layers != 2 {
+		t.Errorf("expected 2 layers, got %v", printable.Size().Layers)
+	}
+
+	gray := printable.LayerImage(0)
+
+	table := []struct {
+		X, Y  int
+		Value uint8
+	}{
+		{24, 40, 0x00},
+		{25, 40, 0x80}, // Half covered
+		{26, 40, 0xff},
+		{30, 40, 0xff},
+		{50, 40, 0x00}, // In the hole
+		{74, 40, 0xff},
+		{75, 40, 0x80}, // Half covered
+		{76, 40, 0x00},
+	}
+
+	for _, item := range table {
+		got := gray.GrayAt(item.X, item.Y).Y
+		if got != item.Value {
+			t.Errorf("(%v,%v): expected %#x, got %#x", item.X, item.Y, item.Value, got)
+		}
+	}
+}
+
+func TestSliceTooLarge(t *testing.T) {
+	box := boxMesh(Vertex{0, 0, 0}, Vertex{30, 1, 1})
+
+	formatter := NewFormatter(".stl")
+	formatter.Parse([]string{"--machine", "test-stl"})
+
+	_, err := formatter.Decode(newBufferReader(binarySTL(box)), 0)
+	if err == nil {
+		t.Errorf("expected an error for a model larger than the bed")
+	}
+}
+
+// Check that every edge is shared by exactly two facets, in opposite directions
+func checkWatertight(t *testing.T, mesh *Mesh) {
+	type edge struct{ a, b Vertex }
+
+	edges := map[edge]int{}
+	for _, tri := range mesh.Triangles {
+		for n := 0; n < 3; n++ {
+			edges[edge{tri.Vertex[n], tri.Vertex[(n+1)%3]}]++
+		}
+	}
+
+	for e, count := range edges {
+		if count != 1 || edges[edge{e.b, e.a}] != 1 {
+			t.Fatalf("edge %v - %v: not manifold", e.a, e.b)
+		}
+	}
+}
+
+// Signed volume of a closed mesh
+func meshVolume(mesh *Mesh) (volume float64) {
+	for _, tri := range mesh.Triangles {
+		a, b, c := tri.Vertex[0], tri.Vertex[1], tri.Vertex[2]
+		volume += float64(a[0]*(b[1]*c[2]-b[2]*c[1])-a[1]*(b[0]*c[2]-b[2]*c[0])+a[2]*(b[0]*c[1]-b[1]*c[0])) / 6
+	}
+
+	return
+}
+
+func TestMeshRoundTrip(t *testing.T) {
+	box := boxMesh(Vertex{0, 0, 0}, Vertex{10, 8, 1})
+
+	formatter := NewFormatter(".stl")
+	formatter.Parse([]string{"--machine", "test-stl"})
+
+	sliced, err := formatter.Decode(newBufferReader(binarySTL(box)), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, args := range [][]string{{}, {"--ascii"}, {"--smooth", "3"}, {"--downsample", "2"}} {
+		buff := &bytes.Buffer{}
+		formatter = NewFormatter(".stl")
+		formatter.Parse(args)
+		err = formatter.Encode(buff, sliced)
+		if err != nil {
+			t.Fatalf("%v: %v", args, err)
+		}
+
+		mesh, err := ReadMesh(buff.Bytes())
+		if err != nil {
+			t.Fatalf("%v: %v", args, err)
+		}
+
+		checkWatertight(t, mesh)
+
+		// Outward facing normals give a positive volume
+		volume := meshVolume(mesh)
+		if math.Abs(volume-80.0) > 2.0 {
+			t.Errorf("%v: expected a volume of ~80mm^3, got %v", args, volume)
+		}
+
+		// Smoothed and downsampled meshes are only approximate
+		if len(args) > 0 && args[0] != "--ascii" {
+			continue
+		}
+
+		min, max := mesh.Bounds()
+		if min != (Vertex{-5, -4, 0}) || max != (Vertex{5, 4, 1}) {
+			t.Errorf("%v: unexpected bounds %v - %v", args, min, max)
+		}
+
+		// ...and slices back to the same layers
+		formatter = NewFormatter(".stl")
+		formatter.Parse([]string{"--machine", "test-stl"})
+		resliced, err := formatter.Decode(newBufferReader(buff.Bytes()), 0)
+		if err != nil {
+			t.Fatalf("%v: %v", args, err)
+		}
+
+		if resliced.Size().Layers != sliced.Size().Layers {
+			t.Fatalf("%v: expected %v layers, got %v", args, sliced.Size().Layers, resliced.Size().Layers)
+		}
+
+		for n := 0; n < sliced.Size().Layers; n++ {
+			if !bytes.Equal(resliced.LayerImage(n).Pix, sliced.LayerImage(n).Pix) {
+				t.Errorf("%v: layer %v mismatch", args, n)
+			}
+		}
+	}
+}
+
+type edgePrint struct {
+	uv3dp.Print
+	Edge uint8
+}
+
+// Single layer, with a solid block and a single anti-aliased column
+func (ep *edgePrint) LayerImage(index int) (gray *image.Gray) {
+	gray = image.NewGray(ep.Bounds())
+	for y := 10; y < 20; y++ {
+		for x := 10; x < 20; x++ {
+			gray.Pix[y*gray.Stride+x] = 0xff
+		}
+		gray.Pix[y*gray.Stride+20] = ep.Edge
+	}
+
+	return
+}
+
+func TestMeshIsoLevel(t *testing.T) {
+	prop := uv3dp.Properties{
+		Size: uv3dp.Size{
+			X: 40, Y: 40,
+			Millimeter:  uv3dp.SizeMillimeter{X: 4, Y: 4},
+			Layers:      1,
+			LayerHeight: 0.1,
+		},
+	}
+
+	// Right side of the block, in mm, for each edge column gray level.
+	// The edge column is centered at X = 0.05mm
+	table := map[uint8]float32{
+		0x00: 0.0,
+		0x80: 0.05 + 0.1*(0.5/128), // (0x80 - 127.5) / (0x80 - 0)
+		0xff: 0.1,
+	}
+
+	for edge, expected := range table {
+		printable := &edgePrint{Print: uv3dp.Print{Properties: prop}, Edge: edge}
+
+		buff := &bytes.Buffer{}
+		formatter := NewFormatter(".stl")
+		err := formatter.Encode(buff, printable)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		mesh, _ := ReadMesh(buff.Bytes())
+		checkWatertight(t, mesh)
+
+		_, max := mesh.Bounds()
+		if math.Abs(float64(max[0]-expected)) > 1e-5 {
+			t.Errorf("edge %#x: expected right side at %v, got %v", edge, expected, max[0])
+		}
+	}
+}
+
+// Samples at exactly the iso-level give faces with coincident vertices,
+// which must be kept for the surface to be closed
+func TestMarchIsoSamples(t *testing.T) {
+	const width, height = 4, 4
+
+	pix := make([]uint8, width*height)
+	for y := 1; y < 3; y++ {
+		for x := 1; x < 3; x++ {
+			pix[y*width+x] = 0x80
+		}
+	}
+	pix[1*width+1] = 0xff
+
+	mc := newMarcher(width, height, 0x80)
+	mc.pitch = [2]float64{1, 1}
+
+	lower := mc.newPlane(nil, 0)
+	middle := mc.newPlane(pix, 1)
+	mc.Slab(0, lower, middle)
+	mc.Slab(1, middle, mc.newPlane(nil, 2))
+
+	if len(mc.surface.Faces) == 0 {
+		t.Fatalf("no faces")
+	}
+
+	// Every edge is shared by exactly two faces, in opposite directions
+	type edge struct{ a, b int32 }
+
+	edges := map[edge]int{}
+	for _, face := range mc.surface.Faces {
+		for n := 0; n < 3; n++ {
+			edges[edge{face[n], face[(n+1)%3]}]++
+		}
+	}
+
+	for e, count := range edges {
+		if count != 1 || edges[edge{e.b, e.a}] != 1 {
+			t.Fatalf("edge %v - %v: not manifold", e.a, e.b)
+		}
+	}
+}
+
+func TestMeshOBJ(t *testing.T) {
+	prop := uv3dp.Properties{
+		Size: uv3dp.Size{
+			X: 40, Y: 40,
+			Millimeter:  uv3dp.SizeMillimeter{X: 4, Y: 4},
+			Layers:      1,
+			LayerHeight: 0.1,
+		},
+	}
+
+	printable := &edgePrint{Print: uv3dp.Print{Properties: prop}, Edge: 0xff}
+
+	buff := &bytes.Buffer{}
+	err := NewFormatter(".obj").Encode(buff, printable)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vertices := 0
+	faces := 0
+	for _, line := range strings.Split(buff.String(), "\n") {
+		switch {
+		case strings.HasPrefix(line, "v "):
+			vertices++
+		case strings.HasPrefix(line, "f "):
+			faces++
+		}
+	}
+
+	// Closed genus 0 surface: V - E + F = 2, with E = 3F/2
+	if vertices-faces/2 != 2 {
+		t.Errorf("expected a closed surface, got %v vertices and %v faces", vertices, faces)
+	}
+
+	_, err = NewFormatter(".obj").Decode(newBufferReader(buff.Bytes()), int64(buff.Len()))
+	if err == nil {
+		t.Errorf("expected an error decoding an OBJ file")
+	}
+}
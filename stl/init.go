@@ -0,0 +1,18 @@
+//
+// Copyright (c) 2020 Jason S. McMullan <jason.mcmullan@gmail.com>
+//
+
+// Package stl slices binary and ASCII STL models into printables, and
+// reconstructs STL or OBJ meshes from the layers of a printable
+package stl
+
+import (
+	"github.com/ezrec/uv3dp"
+)
+
+func init() {
+	newFormatter := func(suffix string) uv3dp.Formatter { return NewFormatter(suffix) }
+
+	uv3dp.RegisterFormatter(".stl", newFormatter)
+	uv3dp.RegisterFormatter(".obj", newFormatter)
+}
@@ -0,0 +1,332 @@
+//
+// Copyright (c) 2020 Jason S. McMullan <jason.mcmullan@gmail.com>
+//
+
+package stl
+
+import (
+	"math"
+)
+
+// Surface is an indexed triangle mesh
+type Surface struct {
+	Vertices []Vertex
+	Faces    [][3]int32
+}
+
+// Each cube is split into six tetrahedra along its main diagonal (the
+// Kuhn triangulation). Unlike the classic marching cubes case tables,
+// this has no ambiguous cases, so the resulting surface is watertight.
+// Corners are numbered by bits: 1 for X, 2 for Y, and 4 for Z.
+var kuhnTetrahedra = [6][4]int{
+	{0, 1, 3, 7},
+	{0, 1, 5, 7},
+	{0, 2, 3, 7},
+	{0, 2, 6, 7},
+	{0, 4, 5, 7},
+	{0, 4, 6, 7},
+}
+
+// edgeKey identifies a cube edge by its lower grid point, and the
+// corner bits of its direction
+type edgeKey struct {
+	x, y, z int32
+	dir     uint8
+}
+
+// marcher builds a surface, one slab (pair of planes) at a time
+type marcher struct {
+	width, height int     // Plane size, in samples
+	iso           float64 // Iso-level of the surface
+	pitch         [2]float64
+	origin        [2]float64 // Model X and Y of the plane's top left corner
+
+	surface Surface
+	edges   map[edgeKey]int32
+}
+
+// plane is a single layer of samples
+type plane struct {
+	pix   []uint8 // nil for an empty plane
+	empty []bool  // Rows which are entirely empty
+	z     float64
+}
+
+func newMarcher(width, height int, iso float64) (mc *marcher) {
+	mc = &marcher{
+		width:  width,
+		height: height,
+		iso:    iso,
+		edges:  map[edgeKey]int32{},
+	}
+
+	return
+}
+
+func (mc *marcher) newPlane(pix []uint8, z float64) (pl *plane) {
+	pl = &plane{
+		pix:   pix,
+		empty: make([]bool, mc.height),
+		z:     z,
+	}
+
+	for y := range pl.empty {
+		pl.empty[y] = true
+		if pix == nil {
+			continue
+		}
+		for _, value := range pix[y*mc.width : (y+1)*mc.width] {
+			if float64(value) >= mc.iso {
+				pl.empty[y] = false
+				break
+			}
+		}
+	}
+
+	return
+}
+
+func (mc *marcher) value(pl *plane, x, y int) float64 {
+	if pl.pix == nil || x < 0 || y < 0 || x >= mc.width || y >= mc.height {
+		return 0
+	}
+
+	return float64(pl.pix[y*mc.width+x])
+}
+
+func (mc *marcher) rowEmpty(pl *plane, y int) bool {
+	if y < 0 || y >= mc.height {
+		return true
+	}
+
+	return pl.empty[y]
+}
+
+// Model position of a grid point
+func (mc *marcher) position(x, y int, z float64) [3]float64 {
+	return [3]float64{
+		mc.origin[0] + (float64(x)+0.5)*mc.pitch[0],
+		mc.origin[1] - (float64(y)+0.5)*mc.pitch[1],
+		z,
+	}
+}
+
+// Vertex on the edge between two cube corners, shared between all
+// the tetrahedra that use that edge
+func (mc *marcher) edgeVertex(key edgeKey, pa, pb [3]float64, va, vb float64) (index int32) {
+	index, found := mc.edges[key]
+	if found {
+		return
+	}
+
+	t := (mc.iso - va) / (vb - va)
+
+	var v Vertex
+	for n := range v {
+		v[n] = float32(pa[n] + t*(pb[n]-pa[n]))
+	}
+
+	index = int32(len(mc.surface.Vertices))
+	mc.surface.Vertices = append(mc.surface.Vertices, v)
+	mc.edges[key] = index
+
+	return
+}
+
+// Slab adds the surface between two adjacent planes, where the lower
+// plane has grid index z
+func (mc *marcher) Slab(z int, lower, upper *plane) {
+	planes := [2]*plane{lower, upper}
+
+	for y := -1; y < mc.height; y++ {
+		if mc.rowEmpty(lower, y) && mc.rowEmpty(lower, y+1) &&
+			mc.rowEmpty(upper, y) && mc.rowEmpty(upper, y+1) {
+			continue
+		}
+
+		for x := -1; x < mc.width; x++ {
+			var value [8]float64
+			var pos [8][3]float64
+			var inside int
+			for c := 0; c < 8; c++ {
+				pl := planes[c>>2]
+				value[c] = mc.value(pl, x+c&1, y+(c>>1)&1)
+				if value[c] >= mc.iso {
+					inside |= 1 << c
+				}
+			}
+
+			if inside == 0 || inside == 0xff {
+				continue
+			}
+
+			for c := 0; c < 8; c++ {
+				pos[c] = mc.position(x+c&1, y+(c>>1)&1, planes[c>>2].z)
+			}
+
+			for _, tet := range kuhnTetrahedra {
+				mc.tetrahedron(x, y, z, tet, &value, &pos)
+			}
+		}
+	}
+}
+
+func (mc *marcher) tetrahedron(x, y, z int, tet [4]int, value *[8]float64, pos *[8][3]float64) {
+	var in, out []int
+	for _, c := range tet {
+		if value[c] >= mc.iso {
+			in = append(in, c)
+		} else {
+			out = append(out, c)
+		}
+	}
+
+	if len(in) == 0 || len(out) == 0 {
+		return
+	}
+
+	vertex := func(a, b int) int32 {
+		// Tetrahedron corners are ordered, so one is always a subset of the other
+		if a > b {
+			a, b = b, a
+		}
+		key := edgeKey{
+			x:   int32(x + a&1),
+			y:   int32(y + (a>>1)&1),
+			z:   int32(z + (a>>2)&1),
+			dir: uint8(a ^ b),
+		}
+		return mc.edgeVertex(key, pos[a], pos[b], value[a], value[b])
+	}
+
+	// Direction towards the inside of the solid
+	var inward [3]float64
+	for n := 0; n < 3; n++ {
+		for _, c := range in {
+			inward[n] += pos[c][n] / float64(len(in))
+		}
+		for _, c := range out {
+			inward[n] -= pos[c][n] / float64(len(out))
+		}
+	}
+
+	// Add a face, with its normal pointing away from the inside.
+	//
+	// Faces are oriented by the midpoints of their tetrahedron edges, and
+	// not by their vertices, which coincide where samples are at the
+	// iso-level. Such faces are kept, as their edges close the surface.
+	face := func(edges [3][2]int) {
+		var index [3]int32
+		var mid [3]Vertex
+		for i, edge := range edges {
+			index[i] = vertex(edge[0], edge[1])
+			for n := range mid[i] {
+				mid[i][n] = float32((pos[edge[0]][n] + pos[edge[1]][n]) / 2)
+			}
+		}
+
+		normal := faceNormal(mid[0], mid[1], mid[2])
+		dot := float64(normal[0])*inward[0] + float64(normal[1])*inward[1] + float64(normal[2])*inward[2]
+		if dot > 0 {
+			index[1], index[2] = index[2], index[1]
+		}
+
+		mc.surface.Faces = append(mc.surface.Faces, index)
+	}
+
+	switch {
+	case len(in) == 1:
+		face([3][2]int{{in[0], out[0]}, {in[0], out[1]}, {in[0], out[2]}})
+	case len(out) == 1:
+		face([3][2]int{{out[0], in[0]}, {out[0], in[1]}, {out[0], in[2]}})
+	default:
+		a := [2]int{in[0], out[0]}
+		b := [2]int{in[0], out[1]}
+		c := [2]int{in[1], out[1]}
+		d := [2]int{in[1], out[0]}
+		face([3][2]int{a, b, c})
+		face([3][2]int{a, c, d})
+	}
+}
+
+// Unnormalized normal of a counter-clockwise triangle
+func faceNormal(a, b, c Vertex) (normal Vertex) {
+	ux, uy, uz := b[0]-a[0], b[1]-a[1], b[2]-a[2]
+	wx, wy, wz := c[0]-a[0], c[1]-a[1], c[2]-a[2]
+
+	normal = Vertex{
+		uy*wz - uz*wy,
+		uz*wx - ux*wz,
+		ux*wy - uy*wx,
+	}
+
+	return
+}
+
+// Smooth applies Taubin smoothing, which (unlike plain Laplacian
+// smoothing) does not shrink the surface
+func (surface *Surface) Smooth(passes int) {
+	const (
+		lambda = 0.5
+		mu     = -0.53
+	)
+
+	neighbors := make([][]int32, len(surface.Vertices))
+	for _, face := range surface.Faces {
+		for n := 0; n < 3; n++ {
+			a := face[n]
+			b := face[(n+1)%3]
+			neighbors[a] = append(neighbors[a], b)
+			neighbors[b] = append(neighbors[b], a)
+		}
+	}
+
+	next := make([]Vertex, len(surface.Vertices))
+	for pass := 0; pass < passes; pass++ {
+		for _, factor := range []float32{lambda, mu} {
+			for n, v := range surface.Vertices {
+				next[n] = v
+				if len(neighbors[n]) == 0 {
+					continue
+				}
+
+				var avg Vertex
+				for _, m := range neighbors[n] {
+					for i := range avg {
+						avg[i] += surface.Vertices[m][i]
+					}
+				}
+
+				for i := range avg {
+					avg[i] /= float32(len(neighbors[n]))
+					next[n][i] += factor * (avg[i] - v[i])
+				}
+			}
+			surface.Vertices, next = next, surface.Vertices
+		}
+	}
+}
+
+// Mesh converts the surface to a triangle soup, with unit normals
+func (surface *Surface) Mesh() (mesh *Mesh) {
+	mesh = &Mesh{
+		Triangles: make([]Triangle, len(surface.Faces)),
+	}
+
+	for n, face := range surface.Faces {
+		tri := &mesh.Triangles[n]
+		for i, index := range face {
+			tri.Vertex[i] = surface.Vertices[index]
+		}
+
+		normal := faceNormal(tri.Vertex[0], tri.Vertex[1], tri.Vertex[2])
+		length := float32(math.Sqrt(float64(normal[0]*normal[0] + normal[1]*normal[1] + normal[2]*normal[2])))
+		if length > 0 {
+			for i := range normal {
+				tri.Normal[i] = normal[i] / length
+			}
+		}
+	}
+
+	return
+}
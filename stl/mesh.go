@@ -0,0 +1,235 @@
+//
+// Copyright (c) 2020 Jason S. McMullan <jason.mcmullan@gmail.com>
+//
+
+package stl
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Vertex is a point in model space, in millimeters
+type Vertex [3]float32
+
+// Triangle is a single STL facet
+type Triangle struct {
+	Normal Vertex
+	Vertex [3]Vertex
+}
+
+// Mesh is a triangle soup, as read from an STL file
+type Mesh struct {
+	Triangles []Triangle
+}
+
+const (
+	stlHeaderLen   = 80
+	stlTriangleLen = 50
+)
+
+// Bounds returns the minimum and maximum corners of the mesh
+func (mesh *Mesh) Bounds() (min, max Vertex) {
+	for n := range min {
+		min[n] = float32(math.Inf(1))
+		max[n] = float32(math.Inf(-1))
+	}
+
+	for _, tri := range mesh.Triangles {
+		for _, v := range tri.Vertex {
+			for n := range v {
+				if v[n] < min[n] {
+					min[n] = v[n]
+				}
+				if v[n] > max[n] {
+					max[n] = v[n]
+				}
+			}
+		}
+	}
+
+	return
+}
+
+// ReadMesh parses a binary or ASCII STL file
+func ReadMesh(data []byte) (mesh *Mesh, err error) {
+	// Binary files may also start with 'solid', so check the size first
+	if len(data) >= stlHeaderLen+4 {
+		count := binary.LittleEndian.Uint32(data[stlHeaderLen:])
+		if int64(len(data)) == stlHeaderLen+4+int64(count)*stlTriangleLen {
+			mesh, err = readBinary(data, int(count))
+			return
+		}
+	}
+
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("solid")) {
+		mesh, err = readASCII(data)
+		return
+	}
+
+	err = errors.New("not an STL file")
+	return
+}
+
+func readBinary(data []byte, count int) (mesh *Mesh, err error) {
+	mesh = &Mesh{
+		Triangles: make([]Triangle, count),
+	}
+
+	data = data[stlHeaderLen+4:]
+	for n := range mesh.Triangles {
+		tri := &mesh.Triangles[n]
+		floats := data[n*stlTriangleLen:]
+		for i := 0; i < 12; i++ {
+			value := math.Float32frombits(binary.LittleEndian.Uint32(floats[i*4:]))
+			if i < 3 {
+				tri.Normal[i] = value
+			} else {
+				tri.Vertex[(i-3)/3][i%3] = value
+			}
+		}
+	}
+
+	return
+}
+
+func readASCII(data []byte) (mesh *Mesh, err error) {
+	mesh = &Mesh{}
+
+	var tri Triangle
+	vertices := 0
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		var xyz []string
+		switch strings.ToLower(fields[0]) {
+		case "facet":
+			if len(fields) != 5 || strings.ToLower(fields[1]) != "normal" {
+				err = fmt.Errorf("line %v: malformed facet", line)
+				return
+			}
+			xyz = fields[2:]
+			vertices = 0
+		case "vertex":
+			if len(fields) != 4 || vertices >= 3 {
+				err = fmt.Errorf("line %v: malformed vertex", line)
+				return
+			}
+			xyz = fields[1:]
+		case "endfacet":
+			if vertices != 3 {
+				err = fmt.Errorf("line %v: facet has %v vertices", line, vertices)
+				return
+			}
+			mesh.Triangles = append(mesh.Triangles, tri)
+			continue
+		default:
+			continue
+		}
+
+		var v Vertex
+		for n, field := range xyz {
+			var value float64
+			value, err = strconv.ParseFloat(field, 32)
+			if err != nil {
+				err = fmt.Errorf("line %v: %w", line, err)
+				return
+			}
+			v[n] = float32(value)
+		}
+
+		if strings.ToLower(fields[0]) == "facet" {
+			tri.Normal = v
+		} else {
+			tri.Vertex[vertices] = v
+			vertices++
+		}
+	}
+
+	err = scanner.Err()
+
+	return
+}
+
+// WriteBinary writes the mesh as a binary STL file
+func (mesh *Mesh) WriteBinary(writer io.Writer) (err error) {
+	header := make([]byte, stlHeaderLen+4)
+	copy(header, "uv3dp")
+	binary.LittleEndian.PutUint32(header[stlHeaderLen:], uint32(len(mesh.Triangles)))
+
+	out := bufio.NewWriter(writer)
+	_, err = out.Write(header)
+	if err != nil {
+		return
+	}
+
+	facet := make([]byte, stlTriangleLen)
+	for _, tri := range mesh.Triangles {
+		floats := append([]float32{}, tri.Normal[:]...)
+		for _, v := range tri.Vertex {
+			floats = append(floats, v[:]...)
+		}
+		for n, value := range floats {
+			binary.LittleEndian.PutUint32(facet[n*4:], math.Float32bits(value))
+		}
+
+		_, err = out.Write(facet)
+		if err != nil {
+			return
+		}
+	}
+
+	err = out.Flush()
+
+	return
+}
+
+// WriteASCII writes the mesh as an ASCII STL file
+func (mesh *Mesh) WriteASCII(writer io.Writer) (err error) {
+	out := bufio.NewWriter(writer)
+
+	fmt.Fprintln(out, "solid uv3dp")
+	for _, tri := range mesh.Triangles {
+		fmt.Fprintf(out, "  facet normal %g %g %g\n", tri.Normal[0], tri.Normal[1], tri.Normal[2])
+		fmt.Fprintln(out, "    outer loop")
+		for _, v := range tri.Vertex {
+			fmt.Fprintf(out, "      vertex %g %g %g\n", v[0], v[1], v[2])
+		}
+		fmt.Fprintln(out, "    endloop")
+		fmt.Fprintln(out, "  endfacet")
+	}
+	fmt.Fprintln(out, "endsolid uv3dp")
+
+	err = out.Flush()
+
+	return
+}
+
+// WriteOBJ writes the surface as a Wavefront OBJ file
+func (surface *Surface) WriteOBJ(writer io.Writer) (err error) {
+	out := bufio.NewWriter(writer)
+
+	fmt.Fprintln(out, "# uv3dp")
+	for _, v := range surface.Vertices {
+		fmt.Fprintf(out, "v %g %g %g\n", v[0], v[1], v[2])
+	}
+	for _, face := range surface.Faces {
+		fmt.Fprintf(out, "f %d %d %d\n", face[0]+1, face[1]+1, face[2]+1)
+	}
+
+	err = out.Flush()
+
+	return
+}
@@ -0,0 +1,204 @@
+//
+// Copyright (c) 2020 Jason S. McMullan <jason.mcmullan@gmail.com>
+//
+
+package stl
+
+import (
+	"image"
+	"math"
+	"sort"
+)
+
+// slicer rasterizes a mesh, one layer at a time
+type slicer struct {
+	mesh *Mesh
+
+	width, height int        // Image size, in pixels
+	antiAlias     int        // Supersampling, in samples per pixel edge
+	center        [2]float64 // Model point at the center of the image, in mm
+	scale         [2]float64 // Samples per mm
+
+	zBase       float64 // Model Z of the build plate
+	layerHeight float64
+	layers      [][]int32 // Triangles which may cross each layer
+}
+
+// segment is a contour edge, in sample coordinates
+type segment struct {
+	x0, y0, x1, y1 float64
+}
+
+// crossing is where a contour crosses a sample row
+type crossing struct {
+	x       float64
+	winding int
+}
+
+func newSlicer(mesh *Mesh, layers int, zBase, layerHeight float64) (sl *slicer) {
+	sl = &slicer{
+		mesh:        mesh,
+		zBase:       zBase,
+		layerHeight: layerHeight,
+		layers:      make([][]int32, layers),
+	}
+
+	for n, tri := range mesh.Triangles {
+		zmin := math.Min(float64(tri.Vertex[0][2]), math.Min(float64(tri.Vertex[1][2]), float64(tri.Vertex[2][2])))
+		zmax := math.Max(float64(tri.Vertex[0][2]), math.Max(float64(tri.Vertex[1][2]), float64(tri.Vertex[2][2])))
+
+		// Be generous; the exact test is done when slicing
+		first := int(math.Floor((zmin-zBase)/layerHeight-0.5)) - 1
+		last := int(math.Ceil((zmax-zBase)/layerHeight-0.5)) + 1
+		if first < 0 {
+			first = 0
+		}
+		if last >= layers {
+			last = layers - 1
+		}
+
+		for layer := first; layer <= last; layer++ {
+			sl.layers[layer] = append(sl.layers[layer], int32(n))
+		}
+	}
+
+	return
+}
+
+// Sample coordinates of a model point
+func (sl *slicer) sample(x, y float64) (sx, sy float64) {
+	sx = float64(sl.width*sl.antiAlias)/2 + (x-sl.center[0])*sl.scale[0]
+	sy = float64(sl.height*sl.antiAlias)/2 - (y-sl.center[1])*sl.scale[1]
+	return
+}
+
+// Intersect a triangle with the plane at z, oriented so that the
+// solid is on the left (in model space)
+func (sl *slicer) intersect(tri *Triangle, z float64) (seg segment, ok bool) {
+	var points [2][2]float64
+	found := 0
+
+	for n := 0; n < 3; n++ {
+		a := tri.Vertex[n]
+		b := tri.Vertex[(n+1)%3]
+		za := float64(a[2])
+		zb := float64(b[2])
+		if (za >= z) == (zb >= z) {
+			continue
+		}
+		if found == 2 {
+			return
+		}
+
+		t := (z - za) / (zb - za)
+		points[found][0] = float64(a[0]) + t*float64(b[0]-a[0])
+		points[found][1] = float64(a[1]) + t*float64(b[1]-a[1])
+		found++
+	}
+
+	if found != 2 {
+		return
+	}
+
+	// Orient by the winding of the facet, not the (often bogus) stored normal
+	v0, v1, v2 := tri.Vertex[0], tri.Vertex[1], tri.Vertex[2]
+	ux, uy, uz := float64(v1[0]-v0[0]), float64(v1[1]-v0[1]), float64(v1[2]-v0[2])
+	wx, wy, wz := float64(v2[0]-v0[0]), float64(v2[1]-v0[1]), float64(v2[2]-v0[2])
+	nx := uy*wz - uz*wy
+	ny := uz*wx - ux*wz
+
+	dx := points[1][0] - points[0][0]
+	dy := points[1][1] - points[0][1]
+	if dy*nx-dx*ny < 0 {
+		points[0], points[1] = points[1], points[0]
+	}
+
+	seg.x0, seg.y0 = sl.sample(points[0][0], points[0][1])
+	seg.x1, seg.y1 = sl.sample(points[1][0], points[1][1])
+	ok = true
+
+	return
+}
+
+// Slice rasterizes a single layer, using the non-zero winding rule
+func (sl *slicer) Slice(index int) (gray *image.Gray) {
+	gray = image.NewGray(image.Rect(0, 0, sl.width, sl.height))
+
+	aa := sl.antiAlias
+	rows := make([][]crossing, sl.height*aa)
+	z := sl.zBase + (float64(index)+0.5)*sl.layerHeight
+
+	for _, n := range sl.layers[index] {
+		seg, ok := sl.intersect(&sl.mesh.Triangles[n], z)
+		if !ok || seg.y0 == seg.y1 {
+			continue
+		}
+
+		winding := 1
+		if seg.y1 < seg.y0 {
+			winding = -1
+		}
+
+		ymin := math.Min(seg.y0, seg.y1)
+		ymax := math.Max(seg.y0, seg.y1)
+		first := int(math.Ceil(ymin - 0.5))
+		last := int(math.Ceil(ymax - 0.5))
+		if first < 0 {
+			first = 0
+		}
+		if last > len(rows) {
+			last = len(rows)
+		}
+
+		for row := first; row < last; row++ {
+			y := float64(row) + 0.5
+			x := seg.x0 + (y-seg.y0)*(seg.x1-seg.x0)/(seg.y1-seg.y0)
+			rows[row] = append(rows[row], crossing{x: x, winding: winding})
+		}
+	}
+
+	samples := sl.width * aa
+	coverage := make([]int, sl.width)
+	full := aa * aa
+
+	for y := 0; y < sl.height; y++ {
+		for n := range coverage {
+			coverage[n] = 0
+		}
+
+		for _, row := range rows[y*aa : (y+1)*aa] {
+			sort.Slice(row, func(i, j int) bool { return row[i].x < row[j].x })
+
+			winding := 0
+			var start float64
+			for _, cross := range row {
+				if winding == 0 {
+					start = cross.x
+				}
+				winding += cross.winding
+				if winding != 0 {
+					continue
+				}
+
+				first := int(math.Ceil(start - 0.5))
+				last := int(math.Ceil(cross.x - 0.5))
+				if first < 0 {
+					first = 0
+				}
+				if last > samples {
+					last = samples
+				}
+				for x := first; x < last; x++ {
+					coverage[x/aa]++
+				}
+			}
+		}
+
+		pix := gray.Pix[gray.PixOffset(0, y):]
+		for x, count := range coverage {
+			pix[x] = uint8((count*255 + full/2) / full)
+		}
+	}
+
+	return
+}
@@ -0,0 +1,816 @@
+//
+// Copyright (c) 2020 Jason S. McMullan <jason.mcmullan@gmail.com>
+//
+
+package svg
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ezrec/uv3dp"
+	"github.com/spf13/pflag"
+	"golang.org/x/image/vector"
+)
+
+const (
+	defaultMachine     = "photon"
+	defaultLayerHeight = 0.05 // Layer height in mm
+	defaultIsoLevel    = 127.5
+)
+
+var (
+	// Millimeters per unit
+	unitScale = map[string]float64{
+		"":   1.0, // Slic3r writes unitless millimeters
+		"mm": 1.0,
+		"cm": 10.0,
+		"in": 25.4,
+		"pt": 25.4 / 72,
+		"pc": 25.4 / 6,
+		"px": 25.4 / 96,
+	}
+
+	namedColors = map[string]uint8{
+		"black": 0x00,
+		"white": 0xff,
+		"gray":  0x80,
+		"grey":  0x80,
+		"red":   0x4c,
+		"green": 0x4b,
+		"lime":  0x96,
+		"blue":  0x1d,
+	}
+
+	// Elements whose contents are never drawn directly
+	skipElements = map[string]bool{
+		"defs":     true,
+		"clipPath": true,
+		"mask":     true,
+		"marker":   true,
+		"pattern":  true,
+		"symbol":   true,
+		"text":     true,
+		"title":    true,
+		"desc":     true,
+		"metadata": true,
+		"style":    true,
+	}
+)
+
+// Shape is a filled set of closed subpaths, in millimeters from the
+// top left of the canvas
+type Shape struct {
+	Fill     uint8
+	Subpaths [][][2]float64
+}
+
+// Layer is a single slice
+type Layer struct {
+	Z      float32 // Layer Z, in mm, if HasZ is set
+	HasZ   bool
+	Shapes []Shape
+}
+
+// Document is a parsed SVG slice stack
+type Document struct {
+	Width, Height float64 // Canvas size, in mm
+	Layers        []*Layer
+}
+
+type Print struct {
+	uv3dp.Print
+	Layers []*Layer
+
+	antiAlias bool
+	origin    [2]float64 // Canvas position of the top left of the bed, in mm
+	scale     [2]float64 // Pixels per mm
+}
+
+type Format struct {
+	*pflag.FlagSet
+
+	Machine     string
+	LayerHeight float32
+	Position    []float32
+	AntiAlias   bool
+
+	IsoLevel float32
+}
+
+func NewFormatter(suffix string) (sf *Format) {
+	flagSet := pflag.NewFlagSet(suffix, pflag.ContinueOnError)
+
+	sf = &Format{
+		FlagSet: flagSet,
+	}
+
+	sf.StringVarP(&sf.Machine, "machine", "M", defaultMachine, "Target machine, sets the slice resolution and bed size")
+	sf.Float32VarP(&sf.LayerHeight, "layer-height", "l", defaultLayerHeight, "Layer height in mm, when the layers have no 'z' attributes")
+	sf.Float32SliceVarP(&sf.Position, "position", "P", []float32{0, 0}, "Offset of the canvas center from the bed center, in mm")
+	sf.BoolVarP(&sf.AntiAlias, "anti-alias", "a", true, "Anti-alias the edges of the slices")
+	sf.Float32VarP(&sf.IsoLevel, "iso-level", "i", defaultIsoLevel, "Outline output: gray level of the layer edges")
+	sf.SetInterspersed(false)
+
+	return
+}
+
+// Parse a length, in millimeters
+func parseLength(text string) (length float64, err error) {
+	text = strings.TrimSpace(text)
+	end := strings.IndexFunc(text, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.' && r != '-' && r != '+' && r != 'e' && r != 'E'
+	})
+	if end < 0 {
+		end = len(text)
+	}
+
+	scale, ok := unitScale[text[end:]]
+	if !ok {
+		err = fmt.Errorf("unsupported unit in length '%v'", text)
+		return
+	}
+
+	length, err = strconv.ParseFloat(text[:end], 64)
+	length *= scale
+
+	return
+}
+
+// Parse a coordinate attribute, in user units
+func parseCoordinate(text string) (value float64) {
+	numbers, _ := parseNumbers(strings.TrimRight(text, "abcdefghijklmnopqrstuvwxyz%"))
+	if len(numbers) > 0 {
+		value = numbers[0]
+	}
+	return
+}
+
+// Parse a fill color, as a gray level
+func parseColor(text string) (gray uint8, none bool, err error) {
+	text = strings.ToLower(strings.TrimSpace(text))
+
+	var r, g, b float64
+	switch {
+	case text == "none" || text == "transparent":
+		none = true
+		return
+	case strings.HasPrefix(text, "#") && len(text) == 4:
+		var value uint64
+		value, err = strconv.ParseUint(text[1:], 16, 16)
+		r, g, b = float64(value>>8&0xf)*17, float64(value>>4&0xf)*17, float64(value&0xf)*17
+	case strings.HasPrefix(text, "#") && len(text) == 7:
+		var value uint64
+		value, err = strconv.ParseUint(text[1:], 16, 32)
+		r, g, b = float64(value>>16&0xff), float64(value>>8&0xff), float64(value&0xff)
+	case strings.HasPrefix(text, "rgb(") && strings.HasSuffix(text, ")"):
+		var rgb []float64
+		rgb, err = parseNumbers(strings.ReplaceAll(text[4:len(text)-1], "%", ""))
+		if err == nil && len(rgb) != 3 {
+			err = fmt.Errorf("malformed color '%v'", text)
+		}
+		if err == nil {
+			if strings.Contains(text, "%") {
+				for n := range rgb {
+					rgb[n] *= 255.0 / 100.0
+				}
+			}
+			r, g, b = rgb[0], rgb[1], rgb[2]
+		}
+	default:
+		var ok bool
+		gray, ok = namedColors[text]
+		if !ok {
+			err = fmt.Errorf("unsupported color '%v'", text)
+		}
+		return
+	}
+
+	if err != nil {
+		return
+	}
+
+	gray = uint8(math.Round(math.Min(255, math.Max(0, r*0.299+g*0.587+b*0.114))))
+
+	return
+}
+
+// Inherited drawing state
+type state struct {
+	transform matrix
+	fill      uint8
+	none      bool
+}
+
+// Apply the attributes of an element to the inherited state
+func (st state) apply(attrs map[string]string) (next state, err error) {
+	next = st
+
+	transform, ok := attrs["transform"]
+	if ok {
+		var m matrix
+		m, err = parseTransform(transform)
+		if err != nil {
+			return
+		}
+		next.transform = m.Multiply(st.transform)
+	}
+
+	fill, ok := attrs["fill"]
+	for _, style := range strings.Split(attrs["style"], ";") {
+		kv := strings.SplitN(style, ":", 2)
+		if len(kv) == 2 && strings.TrimSpace(kv[0]) == "fill" {
+			fill = kv[1]
+			ok = true
+		}
+	}
+
+	if ok {
+		next.fill, next.none, err = parseColor(fill)
+	}
+
+	return
+}
+
+// Element geometry, in user units
+func elementPath(name string, attrs map[string]string) (subpaths [][]point, err error) {
+	pb := &pathBuilder{}
+	value := func(key string) float64 { return parseCoordinate(attrs[key]) }
+
+	switch name {
+	case "path":
+		subpaths, err = parsePath(attrs["d"])
+		return
+	case "polygon", "polyline":
+		var numbers []float64
+		numbers, err = parseNumbers(attrs["points"])
+		if err != nil {
+			return
+		}
+		for n := 0; n+1 < len(numbers); n += 2 {
+			p := point{numbers[n], numbers[n+1]}
+			if n == 0 {
+				pb.MoveTo(p)
+			} else {
+				pb.LineTo(p)
+			}
+		}
+	case "rect":
+		x, y, w, h := value("x"), value("y"), value("width"), value("height")
+		pb.MoveTo(point{x, y})
+		pb.LineTo(point{x + w, y})
+		pb.LineTo(point{x + w, y + h})
+		pb.LineTo(point{x, y + h})
+	case "circle":
+		pb.Ellipse(value("cx"), value("cy"), value("r"), value("r"))
+	case "ellipse":
+		pb.Ellipse(value("cx"), value("cy"), value("rx"), value("ry"))
+	}
+
+	subpaths = pb.subpaths
+
+	return
+}
+
+// Find the layer Z in a 'z' attribute (ie slic3r:z), or 'data-z'
+func layerZ(attrs map[string]string) (z float32, ok bool) {
+	text, found := attrs["z"]
+	if !found {
+		text, found = attrs["data-z"]
+	}
+	if !found {
+		return
+	}
+
+	value, err := strconv.ParseFloat(strings.TrimSpace(text), 32)
+	if err != nil {
+		return
+	}
+
+	z = float32(value)
+	ok = true
+
+	return
+}
+
+func attrMap(attrs []xml.Attr) (m map[string]string) {
+	m = map[string]string{}
+	for _, attr := range attrs {
+		m[attr.Name.Local] = attr.Value
+	}
+	return
+}
+
+// ParseDocument parses an SVG slice stack. Each top-level group is a
+// layer, unless there are none (or flatten is set), in which case the
+// whole document is a single layer.
+func ParseDocument(data []byte, flatten bool) (doc *Document, err error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.Strict = false
+
+	doc = &Document{}
+
+	var root *Layer // Shapes outside of any top-level group
+	var layer *Layer
+	var stack []state
+	var depth int // Depth of the element stack, relative to the root
+
+	for {
+		var token xml.Token
+		token, err = decoder.Token()
+		if err == io.EOF {
+			err = nil
+			break
+		}
+		if err != nil {
+			return
+		}
+
+		switch elem := token.(type) {
+		case xml.StartElement:
+			attrs := attrMap(elem.Attr)
+			name := elem.Name.Local
+
+			if depth == 0 {
+				if name != "svg" {
+					err = fmt.Errorf("expected <svg>, found <%v>", name)
+					return
+				}
+
+				var userToMM matrix
+				userToMM, err = doc.canvas(attrs)
+				if err != nil {
+					return
+				}
+
+				root = &Layer{}
+				root.Z, root.HasZ = layerZ(attrs)
+
+				var st state
+				st, err = state{transform: userToMM, fill: 0xff}.apply(attrs)
+				if err != nil {
+					return
+				}
+				stack = append(stack, st)
+				depth++
+				continue
+			}
+
+			if skipElements[name] {
+				err = decoder.Skip()
+				if err != nil {
+					return
+				}
+				continue
+			}
+
+			var st state
+			st, err = stack[len(stack)-1].apply(attrs)
+			if err != nil {
+				err = fmt.Errorf("<%v>: %w", name, err)
+				return
+			}
+			stack = append(stack, st)
+			depth++
+
+			if name == "g" && depth == 2 && !flatten {
+				layer = &Layer{}
+				layer.Z, layer.HasZ = layerZ(attrs)
+				doc.Layers = append(doc.Layers, layer)
+				continue
+			}
+
+			if name == "g" && flatten && !root.HasZ {
+				root.Z, root.HasZ = layerZ(attrs)
+			}
+
+			if st.none {
+				continue
+			}
+
+			var subpaths [][]point
+			subpaths, err = elementPath(name, attrs)
+			if err != nil {
+				err = fmt.Errorf("<%v>: %w", name, err)
+				return
+			}
+
+			if len(subpaths) == 0 {
+				continue
+			}
+
+			shape := Shape{Fill: st.fill}
+			for _, sub := range subpaths {
+				if len(sub) < 2 {
+					continue
+				}
+				mm := make([][2]float64, len(sub))
+				for n, p := range sub {
+					mm[n] = st.transform.Apply(p)
+				}
+				shape.Subpaths = append(shape.Subpaths, mm)
+			}
+
+			target := root
+			if layer != nil {
+				target = layer
+			}
+			target.Shapes = append(target.Shapes, shape)
+
+		case xml.EndElement:
+			depth--
+			stack = stack[:len(stack)-1]
+			if depth == 1 {
+				layer = nil
+			}
+		}
+	}
+
+	if root == nil {
+		err = errors.New("no <svg> element found")
+		return
+	}
+
+	if len(doc.Layers) == 0 {
+		doc.Layers = []*Layer{root}
+	}
+
+	return
+}
+
+// Set the canvas size, and return the user unit to millimeter transform
+func (doc *Document) canvas(attrs map[string]string) (userToMM matrix, err error) {
+	viewBox, err := parseNumbers(attrs["viewBox"])
+	if err != nil || (len(viewBox) != 0 && len(viewBox) != 4) {
+		err = fmt.Errorf("malformed viewBox '%v'", attrs["viewBox"])
+		return
+	}
+
+	for n, key := range []string{"width", "height"} {
+		text, ok := attrs[key]
+		size := &doc.Width
+		if n == 1 {
+			size = &doc.Height
+		}
+
+		switch {
+		case ok && !strings.HasSuffix(text, "%"):
+			*size, err = parseLength(text)
+			if err != nil {
+				return
+			}
+		case len(viewBox) == 4:
+			*size = viewBox[2+n]
+		}
+	}
+
+	userToMM = identity
+	if len(viewBox) == 4 && viewBox[2] > 0 && viewBox[3] > 0 {
+		userToMM = matrix{1, 0, 0, 1, -viewBox[0], -viewBox[1]}.Multiply(matrix{doc.Width / viewBox[2], 0, 0, doc.Height / viewBox[3], 0, 0})
+	} else if width, ok := attrs["width"]; ok {
+		// Without a viewBox, user units are in the units of the width
+		scale, ok := unitScale[strings.TrimLeft(width, "0123456789.+- ")]
+		if ok {
+			userToMM = matrix{scale, 0, 0, scale, 0, 0}
+		}
+	}
+
+	return
+}
+
+// Bounds of all the shapes in the document, in mm
+func (doc *Document) bounds() (min, max [2]float64, ok bool) {
+	min = [2]float64{math.Inf(1), math.Inf(1)}
+	max = [2]float64{math.Inf(-1), math.Inf(-1)}
+
+	for _, layer := range doc.Layers {
+		for _, shape := range layer.Shapes {
+			for _, sub := range shape.Subpaths {
+				for _, p := range sub {
+					for n := range p {
+						min[n] = math.Min(min[n], p[n])
+						max[n] = math.Max(max[n], p[n])
+					}
+					ok = true
+				}
+			}
+		}
+	}
+
+	return
+}
+
+// Path data for a contour
+func contourPath(out *strings.Builder, contour uv3dp.Contour) {
+	for n, p := range contour {
+		if n == 0 {
+			out.WriteString("M")
+		} else {
+			out.WriteString(" L")
+		}
+		fmt.Fprintf(out, "%.3f,%.3f", p.X, p.Y)
+	}
+	out.WriteString(" Z")
+}
+
+// Encode writes the outlines of each layer, as a group per layer,
+// in the same style as Slic3r's SVG export
+func (sf *Format) Encode(writer uv3dp.Writer, printable uv3dp.Printable) (err error) {
+	size := printable.Size()
+
+	layerChan := make([](chan string), size.Layers)
+	for n := range layerChan {
+		layerChan[n] = make(chan string, 1)
+	}
+
+	go uv3dp.WithAllLayers(printable, func(p uv3dp.Printable, n int) {
+		out := &strings.Builder{}
+		fmt.Fprintf(out, "  <g id=\"layer%d\" slic3r:z=\"%.4f\">\n", n, p.LayerZ(n))
+		for _, polygon := range uv3dp.LayerContours(p, n, sf.IsoLevel) {
+			out.WriteString("    <path d=\"")
+			contourPath(out, polygon.Outline)
+			for _, hole := range polygon.Holes {
+				out.WriteString(" ")
+				contourPath(out, hole)
+			}
+			out.WriteString("\" style=\"fill: white\" />\n")
+		}
+		out.WriteString("  </g>\n")
+
+		layerChan[n] <- out.String()
+		close(layerChan[n])
+	})
+
+	header := fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<svg width="%.3fmm" height="%.3fmm" viewBox="0 0 %.3f %.3f" xmlns="http://www.w3.org/2000/svg" xmlns:slic3r="http://slic3r.org/namespaces/slic3r">
+`, size.Millimeter.X, size.Millimeter.Y, size.Millimeter.X, size.Millimeter.Y)
+
+	_, err = writer.Write([]byte(header))
+	for _, done := range layerChan {
+		layer := <-done
+		if err != nil {
+			continue
+		}
+		_, err = writer.Write([]byte(layer))
+	}
+
+	if err != nil {
+		return
+	}
+
+	_, err = writer.Write([]byte("</svg>\n"))
+
+	return
+}
+
+func (sf *Format) EncodeFile(filename string, printable uv3dp.Printable) (err error) {
+	writer, err := os.Create(filename)
+	if err != nil {
+		return
+	}
+	defer writer.Close()
+
+	err = sf.Encode(writer, printable)
+
+	return
+}
+
+func (sf *Format) Decode(reader uv3dp.Reader, filesize int64) (printable uv3dp.Printable, err error) {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return
+	}
+
+	doc, err := ParseDocument(data, false)
+	if err != nil {
+		return
+	}
+
+	printable, err = sf.printable(doc)
+
+	return
+}
+
+// DecodeFile decodes a single SVG file, or a directory with one SVG file per layer
+func (sf *Format) DecodeFile(filename string) (printable uv3dp.Printable, err error) {
+	info, err := os.Stat(filename)
+	if err != nil {
+		return
+	}
+
+	if !info.IsDir() {
+		var reader *os.File
+		reader, err = os.Open(filename)
+		if err != nil {
+			return
+		}
+		defer reader.Close()
+
+		printable, err = sf.Decode(reader, info.Size())
+		return
+	}
+
+	entries, err := os.ReadDir(filename)
+	if err != nil {
+		return
+	}
+
+	names := []string{}
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.EqualFold(filepath.Ext(entry.Name()), ".svg") {
+			names = append(names, entry.Name())
+		}
+	}
+
+	if len(names) == 0 {
+		err = fmt.Errorf("%s: no SVG files found", filename)
+		return
+	}
+
+	sort.Slice(names, func(i, j int) bool { return uv3dp.NaturalLess(names[i], names[j]) })
+
+	var doc *Document
+	for _, name := range names {
+		var data []byte
+		data, err = os.ReadFile(filepath.Join(filename, name))
+		if err != nil {
+			return
+		}
+
+		var page *Document
+		page, err = ParseDocument(data, true)
+		if err != nil {
+			err = fmt.Errorf("%s: %w", name, err)
+			return
+		}
+
+		if doc == nil {
+			doc = page
+		} else {
+			doc.Layers = append(doc.Layers, page.Layers...)
+		}
+	}
+
+	printable, err = sf.printable(doc)
+
+	return
+}
+
+// Place a document on the bed of the target machine
+func (sf *Format) printable(doc *Document) (printable uv3dp.Printable, err error) {
+	machine, found := uv3dp.MachineFormats[sf.Machine]
+	if !found {
+		err = fmt.Errorf("unknown machine '%v'", sf.Machine)
+		return
+	}
+
+	if len(sf.Position) != 2 {
+		err = fmt.Errorf("illegal --position setting: expected X,Y")
+		return
+	}
+
+	if sf.LayerHeight <= 0 {
+		err = fmt.Errorf("illegal --layer-height setting: %v", sf.LayerHeight)
+		return
+	}
+
+	min, max, ok := doc.bounds()
+	if !ok {
+		err = errors.New("no shapes found in SVG")
+		return
+	}
+
+	// Documents without a size are sized to their contents
+	if doc.Width <= 0 || doc.Height <= 0 {
+		doc.Width = max[0]
+		doc.Height = max[1]
+	}
+
+	msize := machine.Size
+
+	var prop uv3dp.Properties
+	size := &prop.Size
+	size.X = msize.X
+	size.Y = msize.Y
+	size.Millimeter.X = msize.Xmm
+	size.Millimeter.Y = msize.Ymm
+	size.Layers = len(doc.Layers)
+	size.LayerHeight = sf.LayerHeight
+
+	prop.Exposure = uv3dp.DefaultExposure
+	prop.Bottom = uv3dp.DefaultBottom
+
+	sp := &Print{
+		Layers:    doc.Layers,
+		antiAlias: sf.AntiAlias,
+		origin: [2]float64{
+			doc.Width/2 - float64(sf.Position[0]) - float64(msize.Xmm)/2,
+			doc.Height/2 + float64(sf.Position[1]) - float64(msize.Ymm)/2,
+		},
+		scale: [2]float64{
+			float64(msize.X) / float64(msize.Xmm),
+			float64(msize.Y) / float64(msize.Ymm),
+		},
+	}
+
+	for n := 0; n < 2; n++ {
+		bed := [2]float64{float64(msize.Xmm), float64(msize.Ymm)}[n]
+		if min[n] < sp.origin[n] || max[n] > sp.origin[n]+bed {
+			err = fmt.Errorf("SVG shapes (%.2f x %.2f mm) do not fit on the %v bed (%.2f x %.2f mm)",
+				max[0]-min[0], max[1]-min[1], sf.Machine, msize.Xmm, msize.Ymm)
+			return
+		}
+	}
+
+	// Use the layer Z attributes, if all the layers have them
+	hasZ := true
+	for _, layer := range doc.Layers {
+		hasZ = hasZ && layer.HasZ
+	}
+
+	if hasZ {
+		size.LayerHeight = doc.Layers[0].Z
+		if len(doc.Layers) > 1 {
+			size.LayerHeight = doc.Layers[1].Z - doc.Layers[0].Z
+		}
+	} else {
+		for n, layer := range doc.Layers {
+			layer.Z = float32(n+1) * sf.LayerHeight
+		}
+	}
+
+	sp.Print = uv3dp.Print{Properties: prop}
+	printable = sp
+
+	return
+}
+
+func (sp *Print) LayerZ(index int) (z float32) {
+	return sp.Layers[index].Z
+}
+
+func (sp *Print) LayerImage(index int) (gray *image.Gray) {
+	size := sp.Size()
+	gray = image.NewGray(image.Rect(0, 0, size.X, size.Y))
+
+	raster := &vector.Rasterizer{}
+	for _, shape := range sp.Layers[index].Shapes {
+		// Only rasterize the area covered by the shape
+		min := [2]float64{math.Inf(1), math.Inf(1)}
+		max := [2]float64{math.Inf(-1), math.Inf(-1)}
+		pixels := make([][][2]float32, len(shape.Subpaths))
+		for n, sub := range shape.Subpaths {
+			pixels[n] = make([][2]float32, len(sub))
+			for i, p := range sub {
+				for axis := range p {
+					value := (p[axis] - sp.origin[axis]) * sp.scale[axis]
+					min[axis] = math.Min(min[axis], value)
+					max[axis] = math.Max(max[axis], value)
+					pixels[n][i][axis] = float32(value)
+				}
+			}
+		}
+
+		rect := image.Rect(int(math.Floor(min[0])), int(math.Floor(min[1])), int(math.Ceil(max[0])), int(math.Ceil(max[1])))
+		rect = rect.Intersect(gray.Bounds())
+		if rect.Empty() {
+			continue
+		}
+
+		raster.Reset(rect.Dx(), rect.Dy())
+		dx := float32(rect.Min.X)
+		dy := float32(rect.Min.Y)
+		for _, sub := range pixels {
+			raster.MoveTo(sub[0][0]-dx, sub[0][1]-dy)
+			for _, p := range sub[1:] {
+				raster.LineTo(p[0]-dx, p[1]-dy)
+			}
+			raster.ClosePath()
+		}
+
+		raster.Draw(gray, rect, image.NewUniform(color.Gray{Y: shape.Fill}), image.Point{})
+	}
+
+	if !sp.antiAlias {
+		for n, pix := range gray.Pix {
+			if pix >= 0x80 {
+				gray.Pix[n] = 0xff
+			} else {
+				gray.Pix[n] = 0x00
+			}
+		}
+	}
+
+	return
+}
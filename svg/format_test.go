@@ -0,0 +1,331 @@
+//
+// Copyright (c) 2020 Jason S. McMullan <jason.mcmullan@gmail.com>
+//
+
+package svg
+
+import (
+	"bytes"
+	"image"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"testing"
+
+	"github.com/ezrec/uv3dp"
+)
+
+type bufferReader struct {
+	*bytes.Reader
+}
+
+func newBufferReader(data string) *bufferReader {
+	return &bufferReader{Reader: bytes.NewReader([]byte(data))}
+}
+
+func init() {
+	uv3dp.RegisterMachine("test-svg", uv3dp.Machine{Vendor: "Test", Model: "SVG", Size: uv3dp.MachineSize{X: 100, Y: 80, Xmm: 20.0, Ymm: 16.0}}, ".svg")
+}
+
+const (
+	// Slic3r style export: 10 x 8 mm canvas, one group per layer
+	testSlic3rSvg = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<svg width="10" height="8" xmlns="http://www.w3.org/2000/svg" xmlns:svg="http://www.w3.org/2000/svg" xmlns:slic3r="http://slic3r.org/namespaces/slic3r">
+  <!-- Generated using Slic3r -->
+  <g id="layer0" slic3r:z="0.1">
+    <polygon slic3r:type="contour" points="0,0 10,0 10,8 0,8" style="fill: white" />
+    <polygon slic3r:type="hole" points="4,3 6,3 6,5 4,5" style="fill: black" />
+  </g>
+  <g id="layer1" slic3r:z="0.2">
+    <polygon slic3r:type="contour" points="0,0 5,0 5,8 0,8" style="fill: white" />
+  </g>
+</svg>
+`
+)
+
+func TestDecodeSlic3r(t *testing.T) {
+	formatter := NewFormatter(".svg")
+	err := formatter.Parse([]string{"--machine", "test-svg"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	printable, err := formatter.Decode(newBufferReader(testSlic3rSvg), int64(len(testSlic3rSvg)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	size := printable.Size()
+	if size.Layers != 2 || size.X != 100 || size.Y != 80 {
+		t.Fatalf("unexpected size %+v", size)
+	}
+
+	if math.Abs(float64(size.LayerHeight)-0.1) > 1e-6 {
+		t.Errorf("expected layer height 0.1, got %v", size.LayerHeight)
+	}
+
+	for n, z := range []float32{0.1, 0.2} {
+		if printable.LayerZ(n) != z {
+			t.Errorf("layer %v: expected Z %v, got %v", n, z, printable.LayerZ(n))
+		}
+	}
+
+	// Canvas is centered on the bed: pixels 25..75 x 20..60
+	table := []struct {
+		Layer, X, Y int
+		Value       uint8
+	}{
+		{0, 24, 40, 0x00},
+		{0, 25, 40, 0xff},
+		{0, 30, 40, 0xff},
+		{0, 50, 40, 0x00}, // In the hole
+		{0, 74, 59, 0xff},
+		{0, 75, 40, 0x00},
+		{1, 49, 40, 0xff},
+		{1, 50, 40, 0x00},
+	}
+
+	for _, item := range table {
+		got := printable.LayerImage(item.Layer).GrayAt(item.X, item.Y).Y
+		if got != item.Value {
+			t.Errorf("layer %v (%v,%v): expected %#x, got %#x", item.Layer, item.X, item.Y, item.Value, got)
+		}
+	}
+}
+
+func TestDecodeAntiAlias(t *testing.T) {
+	// A 2mm square, a half pixel off the grid, in 0.1mm units
+	svg := `<svg xmlns="http://www.w3.org/2000/svg" width="2cm" height="16mm" viewBox="0 0 200 160">
+  <g transform="translate(50 25)"><path d="M 49 44 h 20 v 20 h -20 z" /></g>
+</svg>`
+
+	for _, antiAlias := range []bool{true, false} {
+		formatter := NewFormatter(".svg")
+		formatter.Machine = "test-svg"
+		formatter.AntiAlias = antiAlias
+
+		printable, err := formatter.Decode(newBufferReader(svg), int64(len(svg)))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if printable.Size().Layers != 1 {
+			t.Fatalf("expected 1 layer, got %v", printable.Size().Layers)
+		}
+
+		if printable.LayerZ(0) != defaultLayerHeight {
+			t.Errorf("expected layer Z %v, got %v", defaultLayerHeight, printable.LayerZ(0))
+		}
+
+		// From 49.5 to 59.5 pixels in X, and 34.5 to 44.5 pixels in Y
+		half := uint8(0x80)
+		if !antiAlias {
+			half = 0xff
+		}
+
+		gray := printable.LayerImage(0)
+		table := []struct {
+			X, Y  int
+			Value uint8
+		}{
+			{45, 40, 0x00},
+			{49, 40, half},
+			{50, 40, 0xff},
+			{59, 40, half},
+			{60, 40, 0x00},
+			{55, 34, half},
+			{55, 35, 0xff},
+		}
+
+		for _, item := range table {
+			got := gray.GrayAt(item.X, item.Y).Y
+			if math.Abs(float64(got)-float64(item.Value)) > 1 {
+				t.Errorf("anti-alias %v (%v,%v): expected %#x, got %#x", antiAlias, item.X, item.Y, item.Value, got)
+			}
+		}
+	}
+}
+
+func TestDecodeDirectory(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "slices.svg")
+	os.Mkdir(dir, 0755)
+
+	// Rectangle widths, in mm; layers are in natural order
+	files := map[string]string{
+		"slice10.svg": "6",
+		"slice2.svg":  "4",
+		"slice1.svg":  "2",
+	}
+
+	for name, width := range files {
+		svg := `<svg xmlns="http://www.w3.org/2000/svg" width="10mm" height="8mm">
+  <rect x="0" y="0" width="` + width + `" height="8" fill="#fff"/>
+</svg>`
+		os.WriteFile(filepath.Join(dir, name), []byte(svg), 0644)
+	}
+
+	formatter := NewFormatter(".svg")
+	formatter.Machine = "test-svg"
+
+	printable, err := formatter.DecodeFile(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if printable.Size().Layers != 3 {
+		t.Fatalf("expected 3 layers, got %v", printable.Size().Layers)
+	}
+
+	for n, width := range []int{10, 20, 30} {
+		gray := printable.LayerImage(n)
+		if gray.GrayAt(25+width-1, 40).Y != 0xff || gray.GrayAt(25+width, 40).Y != 0x00 {
+			t.Errorf("layer %v: expected a %v pixel wide rectangle", n, width)
+		}
+	}
+}
+
+func TestDecodeTooLarge(t *testing.T) {
+	svg := `<svg width="30" height="8"><rect width="30" height="8"/></svg>`
+
+	formatter := NewFormatter(".svg")
+	formatter.Machine = "test-svg"
+
+	_, err := formatter.Decode(newBufferReader(svg), int64(len(svg)))
+	if err == nil {
+		t.Errorf("expected an error for shapes larger than the bed")
+	}
+}
+
+func TestParsePath(t *testing.T) {
+	table := []struct {
+		Path     string
+		Min, Max point
+	}{
+		{"M1,2L3,4", point{1, 2}, point{3, 4}},
+		{"m1-2 3.5.5", point{1, -2}, point{4.5, -1.5}},
+		{"M 0 0 H 10 V -5 z", point{0, -5}, point{10, 0}},
+		{"M 0 0 C 0 10 10 10 10 0", point{0, 0}, point{10, 7.5}},
+		{"M 0 5 A 5 5 0 0 0 10 5", point{0, 5}, point{10, 10}},
+		{"M 0 5 a 5 5 0 1 1 10 0", point{0, 0}, point{10, 5}},
+		{"M 0 0 Q 5 10 10 0 T 20 0", point{0, -5}, point{20, 5}},
+	}
+
+	for _, item := range table {
+		subpaths, err := parsePath(item.Path)
+		if err != nil {
+			t.Errorf("%v: %v", item.Path, err)
+			continue
+		}
+
+		min := point{math.Inf(1), math.Inf(1)}
+		max := point{math.Inf(-1), math.Inf(-1)}
+		for _, sub := range subpaths {
+			for _, p := range sub {
+				for n := range p {
+					min[n] = math.Min(min[n], p[n])
+					max[n] = math.Max(max[n], p[n])
+				}
+			}
+		}
+
+		for n := 0; n < 2; n++ {
+			if math.Abs(min[n]-item.Min[n]) > 1e-6 || math.Abs(max[n]-item.Max[n]) > 1e-6 {
+				t.Errorf("%v: expected bounds %v - %v, got %v - %v", item.Path, item.Min, item.Max, min, max)
+				break
+			}
+		}
+	}
+}
+
+func TestParseTransform(t *testing.T) {
+	table := []struct {
+		Transform string
+		In, Out   point
+	}{
+		{"translate(1,2)", point{1, 1}, point{2, 3}},
+		{"scale(2) translate(1 2)", point{1, 1}, point{4, 6}},
+		{"translate(1 2) scale(2)", point{1, 1}, point{3, 4}},
+		{"rotate(90)", point{1, 0}, point{0, 1}},
+		{"rotate(90, 1, 1)", point{2, 1}, point{1, 2}},
+		{"matrix(1 0 0 1 5 6)", point{0, 0}, point{5, 6}},
+	}
+
+	for _, item := range table {
+		m, err := parseTransform(item.Transform)
+		if err != nil {
+			t.Errorf("%v: %v", item.Transform, err)
+			continue
+		}
+
+		out := m.Apply(item.In)
+		if math.Abs(out[0]-item.Out[0]) > 1e-9 || math.Abs(out[1]-item.Out[1]) > 1e-9 {
+			t.Errorf("%v: expected %v, got %v", item.Transform, item.Out, out)
+		}
+	}
+}
+
+type ringPrint struct {
+	uv3dp.Print
+}
+
+// Square ring, with an island in the hole, that moves right each layer
+func (rp *ringPrint) LayerImage(index int) (gray *image.Gray) {
+	gray = image.NewGray(rp.Bounds())
+	for y := 20; y < 60; y++ {
+		for x := 20; x < 60; x++ {
+			inHole := x >= 30 && x < 50 && y >= 30 && y < 50
+			onIsland := x >= 35 && x < 45 && y >= 35 && y < 45
+			if !inHole || onIsland {
+				gray.Pix[y*gray.Stride+x+index] = 0xff
+			}
+		}
+	}
+
+	return
+}
+
+func TestEncodeRoundTrip(t *testing.T) {
+	prop := uv3dp.Properties{
+		Size: uv3dp.Size{
+			X: 100, Y: 80,
+			Millimeter:  uv3dp.SizeMillimeter{X: 20.0, Y: 16.0},
+			Layers:      3,
+			LayerHeight: 0.05,
+		},
+	}
+
+	ring := &ringPrint{Print: uv3dp.Print{Properties: prop}}
+
+	buff := &bytes.Buffer{}
+	err := NewFormatter(".svg").Encode(buff, ring)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Count(buff.String(), "<g ") != 3 || strings.Count(buff.String(), "<path ") != 6 {
+		t.Errorf("expected 3 layers of 2 paths each, got:\n%v", buff.String())
+	}
+
+	formatter := NewFormatter(".svg")
+	formatter.Parse([]string{"--machine", "test-svg", "--anti-alias=false"})
+	printable, err := formatter.Decode(&bufferReader{bytes.NewReader(buff.Bytes())}, int64(buff.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if printable.Size().Layers != 3 {
+		t.Fatalf("expected 3 layers, got %v", printable.Size().Layers)
+	}
+
+	for n := 0; n < 3; n++ {
+		if printable.LayerZ(n) != ring.LayerZ(n) {
+			t.Errorf("layer %v: expected Z %v, got %v", n, ring.LayerZ(n), printable.LayerZ(n))
+		}
+
+		if !bytes.Equal(printable.LayerImage(n).Pix, ring.LayerImage(n).Pix) {
+			t.Errorf("layer %v: image mismatch", n)
+		}
+	}
+}
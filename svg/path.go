@@ -0,0 +1,395 @@
+//
+// Copyright (c) 2020 Jason S. McMullan <jason.mcmullan@gmail.com>
+//
+
+package svg
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Number of line segments used to flatten each curve
+const curveSegments = 16
+
+type point [2]float64
+
+// matrix is an SVG affine transform: [a c e; b d f; 0 0 1]
+type matrix [6]float64
+
+var identity = matrix{1, 0, 0, 1, 0, 0}
+
+// Multiply returns the transform m, followed by the transform o
+func (m matrix) Multiply(o matrix) matrix {
+	return matrix{
+		o[0]*m[0] + o[2]*m[1],
+		o[1]*m[0] + o[3]*m[1],
+		o[0]*m[2] + o[2]*m[3],
+		o[1]*m[2] + o[3]*m[3],
+		o[0]*m[4] + o[2]*m[5] + o[4],
+		o[1]*m[4] + o[3]*m[5] + o[5],
+	}
+}
+
+func (m matrix) Apply(p point) point {
+	return point{
+		m[0]*p[0] + m[2]*p[1] + m[4],
+		m[1]*p[0] + m[3]*p[1] + m[5],
+	}
+}
+
+// Split a list of numbers, separated by commas and/or whitespace
+func parseNumbers(text string) (numbers []float64, err error) {
+	fields := strings.FieldsFunc(text, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t' || r == '\n' || r == '\r'
+	})
+
+	for _, field := range fields {
+		var value float64
+		value, err = strconv.ParseFloat(field, 64)
+		if err != nil {
+			return
+		}
+		numbers = append(numbers, value)
+	}
+
+	return
+}
+
+// parseTransform parses an SVG 'transform' attribute
+func parseTransform(text string) (m matrix, err error) {
+	m = identity
+
+	for {
+		text = strings.TrimLeft(text, " \t\r\n,")
+		if len(text) == 0 {
+			break
+		}
+
+		open := strings.IndexByte(text, '(')
+		close := strings.IndexByte(text, ')')
+		if open < 0 || close < open {
+			err = fmt.Errorf("malformed transform '%v'", text)
+			return
+		}
+
+		name := strings.TrimSpace(text[:open])
+		var args []float64
+		args, err = parseNumbers(text[open+1 : close])
+		if err != nil {
+			return
+		}
+		text = text[close+1:]
+
+		var t matrix
+		switch {
+		case name == "matrix" && len(args) == 6:
+			copy(t[:], args)
+		case name == "translate" && len(args) == 1:
+			t = matrix{1, 0, 0, 1, args[0], 0}
+		case name == "translate" && len(args) == 2:
+			t = matrix{1, 0, 0, 1, args[0], args[1]}
+		case name == "scale" && len(args) == 1:
+			t = matrix{args[0], 0, 0, args[0], 0, 0}
+		case name == "scale" && len(args) == 2:
+			t = matrix{args[0], 0, 0, args[1], 0, 0}
+		case name == "rotate" && (len(args) == 1 || len(args) == 3):
+			sin, cos := math.Sincos(args[0] * math.Pi / 180)
+			t = matrix{cos, sin, -sin, cos, 0, 0}
+			if len(args) == 3 {
+				t = matrix{1, 0, 0, 1, -args[1], -args[2]}.Multiply(t).Multiply(matrix{1, 0, 0, 1, args[1], args[2]})
+			}
+		case name == "skewX" && len(args) == 1:
+			t = matrix{1, 0, math.Tan(args[0] * math.Pi / 180), 1, 0, 0}
+		case name == "skewY" && len(args) == 1:
+			t = matrix{1, math.Tan(args[0] * math.Pi / 180), 0, 1, 0, 0}
+		default:
+			err = fmt.Errorf("unsupported transform '%v' with %v arguments", name, len(args))
+			return
+		}
+
+		// Transforms apply right to left
+		m = t.Multiply(m)
+	}
+
+	return
+}
+
+// pathBuilder accumulates flattened subpaths
+type pathBuilder struct {
+	subpaths [][]point
+	current  point
+	start    point
+}
+
+func (pb *pathBuilder) MoveTo(p point) {
+	pb.subpaths = append(pb.subpaths, []point{p})
+	pb.current = p
+	pb.start = p
+}
+
+func (pb *pathBuilder) LineTo(p point) {
+	last := len(pb.subpaths) - 1
+	if last < 0 || len(pb.subpaths[last]) == 0 {
+		pb.MoveTo(pb.current)
+		last = len(pb.subpaths) - 1
+	}
+	pb.subpaths[last] = append(pb.subpaths[last], p)
+	pb.current = p
+}
+
+func (pb *pathBuilder) CubicTo(c1, c2, p point) {
+	p0 := pb.current
+	for n := 1; n <= curveSegments; n++ {
+		t := float64(n) / curveSegments
+		u := 1 - t
+		var q point
+		for i := range q {
+			q[i] = u*u*u*p0[i] + 3*u*u*t*c1[i] + 3*u*t*t*c2[i] + t*t*t*p[i]
+		}
+		pb.LineTo(q)
+	}
+}
+
+func (pb *pathBuilder) QuadTo(c, p point) {
+	p0 := pb.current
+	for n := 1; n <= curveSegments; n++ {
+		t := float64(n) / curveSegments
+		u := 1 - t
+		var q point
+		for i := range q {
+			q[i] = u*u*p0[i] + 2*u*t*c[i] + t*t*p[i]
+		}
+		pb.LineTo(q)
+	}
+}
+
+// ArcTo draws an elliptical arc, converting from the SVG endpoint
+// parameterization to the center parameterization
+func (pb *pathBuilder) ArcTo(rx, ry, rotation float64, large, sweep bool, p point) {
+	p0 := pb.current
+	rx = math.Abs(rx)
+	ry = math.Abs(ry)
+	if rx == 0 || ry == 0 || p0 == p {
+		pb.LineTo(p)
+		return
+	}
+
+	sin, cos := math.Sincos(rotation * math.Pi / 180)
+	dx := (p0[0] - p[0]) / 2
+	dy := (p0[1] - p[1]) / 2
+	x1 := cos*dx + sin*dy
+	y1 := -sin*dx + cos*dy
+
+	// Scale up radii that are too small
+	lambda := (x1*x1)/(rx*rx) + (y1*y1)/(ry*ry)
+	if lambda > 1 {
+		rx *= math.Sqrt(lambda)
+		ry *= math.Sqrt(lambda)
+	}
+
+	num := rx*rx*ry*ry - rx*rx*y1*y1 - ry*ry*x1*x1
+	den := rx*rx*y1*y1 + ry*ry*x1*x1
+	coef := math.Sqrt(math.Max(0, num/den))
+	if large == sweep {
+		coef = -coef
+	}
+	cx1 := coef * rx * y1 / ry
+	cy1 := -coef * ry * x1 / rx
+
+	cx := cos*cx1 - sin*cy1 + (p0[0]+p[0])/2
+	cy := sin*cx1 + cos*cy1 + (p0[1]+p[1])/2
+
+	theta := math.Atan2((y1-cy1)/ry, (x1-cx1)/rx)
+	delta := math.Atan2((-y1-cy1)/ry, (-x1-cx1)/rx) - theta
+	if sweep && delta < 0 {
+		delta += 2 * math.Pi
+	} else if !sweep && delta > 0 {
+		delta -= 2 * math.Pi
+	}
+
+	for n := 1; n < curveSegments; n++ {
+		angle := theta + delta*float64(n)/curveSegments
+		s, c := math.Sincos(angle)
+		pb.LineTo(point{
+			cx + cos*rx*c - sin*ry*s,
+			cy + sin*rx*c + cos*ry*s,
+		})
+	}
+	pb.LineTo(p)
+}
+
+// Close the current subpath; drawing continues in a new subpath
+// from the start point
+func (pb *pathBuilder) Close() {
+	pb.current = pb.start
+	pb.subpaths = append(pb.subpaths, nil)
+}
+
+// Ellipse adds a closed ellipse
+func (pb *pathBuilder) Ellipse(cx, cy, rx, ry float64) {
+	for n := 0; n < curveSegments*4; n++ {
+		s, c := math.Sincos(2 * math.Pi * float64(n) / (curveSegments * 4))
+		p := point{cx + rx*c, cy + ry*s}
+		if n == 0 {
+			pb.MoveTo(p)
+		} else {
+			pb.LineTo(p)
+		}
+	}
+	pb.Close()
+}
+
+// Tokenize path data into commands and numbers
+func pathTokens(data string) (tokens []string) {
+	var token strings.Builder
+	flush := func() {
+		if token.Len() > 0 {
+			tokens = append(tokens, token.String())
+			token.Reset()
+		}
+	}
+
+	for n := 0; n < len(data); n++ {
+		c := data[n]
+		switch {
+		case c == ' ' || c == ',' || c == '\t' || c == '\r' || c == '\n':
+			flush()
+		case strings.IndexByte("MmLlHhVvCcSsQqTtAaZz", c) >= 0:
+			flush()
+			tokens = append(tokens, string(c))
+		case c == '-' || c == '+':
+			// A sign starts a new number, unless it follows an exponent
+			prev := token.String()
+			if len(prev) == 0 || (prev[len(prev)-1] != 'e' && prev[len(prev)-1] != 'E') {
+				flush()
+			}
+			token.WriteByte(c)
+		case c == '.':
+			// A second decimal point starts a new number
+			if strings.ContainsAny(token.String(), ".eE") {
+				flush()
+			}
+			token.WriteByte(c)
+		default:
+			token.WriteByte(c)
+		}
+	}
+	flush()
+
+	return
+}
+
+// parsePath parses SVG path data into flattened subpaths
+func parsePath(data string) (subpaths [][]point, err error) {
+	pb := &pathBuilder{}
+
+	tokens := pathTokens(data)
+
+	var command byte
+	var lastControl point
+	var lastCommand byte
+
+	next := func(count int) (args []float64, ok bool) {
+		if len(tokens) < count {
+			return
+		}
+		for _, token := range tokens[:count] {
+			value, perr := strconv.ParseFloat(token, 64)
+			if perr != nil {
+				return
+			}
+			args = append(args, value)
+		}
+		tokens = tokens[count:]
+		ok = true
+		return
+	}
+
+	for len(tokens) > 0 {
+		token := tokens[0]
+		if len(token) == 1 && strings.IndexByte("MmLlHhVvCcSsQqTtAaZz", token[0]) >= 0 {
+			command = token[0]
+			tokens = tokens[1:]
+		} else if command == 0 {
+			err = fmt.Errorf("path data must start with a command")
+			return
+		}
+
+		relative := command >= 'a'
+		offset := point{}
+		if relative {
+			offset = pb.current
+		}
+		abs := func(x, y float64) point {
+			return point{x + offset[0], y + offset[1]}
+		}
+
+		counts := map[byte]int{'m': 2, 'l': 2, 'h': 1, 'v': 1, 'c': 6, 's': 4, 'q': 4, 't': 2, 'a': 7, 'z': 0}
+		lower := command | 0x20
+		args, ok := next(counts[lower])
+		if !ok {
+			err = fmt.Errorf("malformed arguments to path command '%c'", command)
+			return
+		}
+
+		// Reflected control point, for the smooth curve commands
+		reflect := pb.current
+		switch lower {
+		case 'c', 's':
+			if lastCommand == 'c' || lastCommand == 's' {
+				reflect = point{2*pb.current[0] - lastControl[0], 2*pb.current[1] - lastControl[1]}
+			}
+		case 'q', 't':
+			if lastCommand == 'q' || lastCommand == 't' {
+				reflect = point{2*pb.current[0] - lastControl[0], 2*pb.current[1] - lastControl[1]}
+			}
+		}
+
+		switch lower {
+		case 'm':
+			pb.MoveTo(abs(args[0], args[1]))
+			// Subsequent pairs are implicit line-to commands
+			if relative {
+				command = 'l'
+			} else {
+				command = 'L'
+			}
+		case 'l':
+			pb.LineTo(abs(args[0], args[1]))
+		case 'h':
+			p := pb.current
+			p[0] = args[0] + offset[0]
+			pb.LineTo(p)
+		case 'v':
+			p := pb.current
+			p[1] = args[0] + offset[1]
+			pb.LineTo(p)
+		case 'c':
+			lastControl = abs(args[2], args[3])
+			pb.CubicTo(abs(args[0], args[1]), lastControl, abs(args[4], args[5]))
+		case 's':
+			lastControl = abs(args[0], args[1])
+			pb.CubicTo(reflect, lastControl, abs(args[2], args[3]))
+		case 'q':
+			lastControl = abs(args[0], args[1])
+			pb.QuadTo(lastControl, abs(args[2], args[3]))
+		case 't':
+			lastControl = reflect
+			pb.QuadTo(lastControl, abs(args[0], args[1]))
+		case 'a':
+			pb.ArcTo(args[0], args[1], args[2], args[3] != 0, args[4] != 0, abs(args[5], args[6]))
+		case 'z':
+			pb.Close()
+			command = 0
+		}
+
+		lastCommand = lower
+	}
+
+	subpaths = pb.subpaths
+
+	return
+}
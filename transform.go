@@ -0,0 +1,225 @@
+//
+// Copyright (c) 2020 Jason S. McMullan <jason.mcmullan@gmail.com>
+//
+
+package uv3dp
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"sync"
+
+	"golang.org/x/image/math/f64"
+)
+
+// TransformMode is how a transform places the layers on the bed
+type TransformMode int
+
+const (
+	TransformNone = TransformMode(iota) // Keep the bed, and the layers where the transform moves them
+	TransformFit                        // Scale, and centre, the lit pixels of the layers to fill the bed
+	TransformCrop                       // Crop the bed to the lit pixels of the layers
+)
+
+// Transform moves, rotates and scales the layers of a printable on its
+// bed, about the centre of the bed. The layers are scaled, then rotated,
+// then placed by the mode, then moved.
+type Transform struct {
+	X, Y           float32 // Translation, in mm
+	Rotate         float32 // Rotation, in degrees counter-clockwise as seen in the layer images
+	ScaleX, ScaleY float32 // Scale (1.0 for none)
+	Mode           TransformMode
+	Resample       Resample // Resampling of the layer images
+}
+
+// affine returns the product of affine matrices, applying the last first
+func affine(matrices ...f64.Aff3) (m f64.Aff3) {
+	m = f64.Aff3{1, 0, 0, 0, 1, 0}
+
+	for _, a := range matrices {
+		m = f64.Aff3{
+			m[0]*a[0] + m[1]*a[3], m[0]*a[1] + m[1]*a[4], m[0]*a[2] + m[1]*a[5] + m[2],
+			m[3]*a[0] + m[4]*a[3], m[3]*a[1] + m[4]*a[4], m[3]*a[2] + m[4]*a[5] + m[5],
+		}
+	}
+
+	return
+}
+
+// affineBounds returns the bounds of a rectangle, transformed by an affine
+// matrix
+func affineBounds(m f64.Aff3, minX, minY, maxX, maxY float64) (x0, y0, x1, y1 float64) {
+	x0, y0 = math.Inf(1), math.Inf(1)
+	x1, y1 = math.Inf(-1), math.Inf(-1)
+
+	for _, corner := range [][2]float64{{minX, minY}, {maxX, minY}, {minX, maxY}, {maxX, maxY}} {
+		x := m[0]*corner[0] + m[1]*corner[1] + m[2]
+		y := m[3]*corner[0] + m[4]*corner[1] + m[5]
+		x0, y0 = math.Min(x0, x), math.Min(y0, y)
+		x1, y1 = math.Max(x1, x), math.Max(y1, y)
+	}
+
+	return
+}
+
+// TransformBounds returns the pixel bounds of a rectangle of pixels,
+// transformed by an affine matrix
+func TransformBounds(m f64.Aff3, r image.Rectangle) (bounds image.Rectangle) {
+	if r.Empty() {
+		return
+	}
+
+	x0, y0, x1, y1 := affineBounds(m, float64(r.Min.X), float64(r.Min.Y), float64(r.Max.X), float64(r.Max.Y))
+
+	// Allow for rounding, of edges that land on pixel boundaries
+	const slack = 1e-6
+	bounds = image.Rect(int(math.Floor(x0+slack)), int(math.Floor(y0+slack)), int(math.Ceil(x1-slack)), int(math.Ceil(y1-slack)))
+
+	return
+}
+
+// ContentBounds returns the bounds of the lit pixels of all of the layers
+// of a printable
+func ContentBounds(p Printable) (bounds image.Rectangle) {
+	var mutex sync.Mutex
+
+	WithAllLayers(p, func(p Printable, n int) {
+		ig := p.LayerImage(n)
+		rect := ig.Bounds()
+
+		var content image.Rectangle
+		for y := rect.Min.Y; y < rect.Max.Y; y++ {
+			row := ig.Pix[ig.PixOffset(rect.Min.X, y):ig.PixOffset(rect.Max.X, y)]
+
+			first, last := 0, len(row)
+			for first < last && row[first] == 0 {
+				first++
+			}
+			for last > first && row[last-1] == 0 {
+				last--
+			}
+
+			if first < last {
+				content = content.Union(image.Rect(rect.Min.X+first, y, rect.Min.X+last, y+1))
+			}
+		}
+
+		mutex.Lock()
+		bounds = bounds.Union(content)
+		mutex.Unlock()
+	})
+
+	return
+}
+
+// TransformPrintable moves, rotates and scales the layers of a printable
+type TransformPrintable struct {
+	Printable
+
+	size     Size
+	matrix   f64.Aff3 // Source pixels to destination pixels
+	resample Resample
+}
+
+// NewTransformPrintable moves, rotates and scales the layers of a
+// printable. The content is the bounds of the lit pixels of the layers
+// (see ContentBounds), for the fit and crop modes.
+func NewTransformPrintable(printable Printable, transform Transform, content image.Rectangle) (tp *TransformPrintable, err error) {
+	size := printable.Size()
+
+	if size.X == 0 || size.Y == 0 || size.Millimeter.X <= 0 || size.Millimeter.Y <= 0 {
+		err = fmt.Errorf("transform: printable has no size in millimeters")
+		return
+	}
+
+	if transform.Mode != TransformNone && content.Empty() {
+		err = fmt.Errorf("transform: no lit pixels to fit or crop")
+		return
+	}
+
+	pitchX := float64(size.Millimeter.X) / float64(size.X)
+	pitchY := float64(size.Millimeter.Y) / float64(size.Y)
+
+	// Source pixels to mm, from the centre of the bed
+	toMM := f64.Aff3{
+		pitchX, 0, -float64(size.X) / 2 * pitchX,
+		0, pitchY, -float64(size.Y) / 2 * pitchY,
+	}
+
+	scale := f64.Aff3{
+		float64(transform.ScaleX), 0, 0,
+		0, float64(transform.ScaleY), 0,
+	}
+
+	// Counter-clockwise, with Y down
+	sin, cos := math.Sincos(float64(transform.Rotate) * math.Pi / 180)
+	rotate := f64.Aff3{
+		cos, sin, 0,
+		-sin, cos, 0,
+	}
+
+	m := affine(rotate, scale, toMM)
+
+	dstSize := size
+
+	if transform.Mode != TransformNone {
+		x0, y0, x1, y1 := affineBounds(m, float64(content.Min.X), float64(content.Min.Y), float64(content.Max.X), float64(content.Max.Y))
+		center := f64.Aff3{
+			1, 0, -(x0 + x1) / 2,
+			0, 1, -(y0 + y1) / 2,
+		}
+
+		switch transform.Mode {
+		case TransformFit:
+			fit := math.Min(float64(size.Millimeter.X)/(x1-x0), float64(size.Millimeter.Y)/(y1-y0))
+			m = affine(f64.Aff3{fit, 0, 0, 0, fit, 0}, center, m)
+		case TransformCrop:
+			dstSize.X = int(math.Ceil((x1-x0)/pitchX - 1e-6))
+			dstSize.Y = int(math.Ceil((y1-y0)/pitchY - 1e-6))
+			dstSize.Millimeter.X = float32(float64(dstSize.X) * pitchX)
+			dstSize.Millimeter.Y = float32(float64(dstSize.Y) * pitchY)
+			m = affine(center, m)
+		}
+	}
+
+	translate := f64.Aff3{
+		1, 0, float64(transform.X),
+		0, 1, float64(transform.Y),
+	}
+
+	// mm, from the centre of the bed, to destination pixels
+	fromMM := f64.Aff3{
+		1 / pitchX, 0, float64(dstSize.X) / 2,
+		0, 1 / pitchY, float64(dstSize.Y) / 2,
+	}
+
+	tp = &TransformPrintable{
+		Printable: printable,
+		size:      dstSize,
+		matrix:    affine(fromMM, translate, m),
+		resample:  transform.Resample,
+	}
+
+	return
+}
+
+// Matrix returns the affine matrix of source pixels to destination pixels
+func (tp *TransformPrintable) Matrix() f64.Aff3 {
+	return tp.matrix
+}
+
+func (tp *TransformPrintable) Size() (size Size) {
+	size = tp.size
+
+	return
+}
+
+func (tp *TransformPrintable) LayerImage(index int) (ig *image.Gray) {
+	src := tp.Printable.LayerImage(index)
+
+	ig = image.NewGray(image.Rect(0, 0, tp.size.X, tp.size.Y))
+	tp.resample.Transform(ig, tp.matrix, src)
+
+	return
+}
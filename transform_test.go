@@ -0,0 +1,110 @@
+//
+// Copyright (c) 2020 Jason S. McMullan <jason.mcmullan@gmail.com>
+//
+
+package uv3dp
+
+import (
+	"image"
+
+	"testing"
+)
+
+// transformTestPrintable has one layer, with a block of lit pixels
+type transformTestPrintable struct {
+	Printable
+	block image.Rectangle
+}
+
+func (ttp *transformTestPrintable) LayerImage(index int) (ig *image.Gray) {
+	size := ttp.Size()
+	ig = image.NewGray(image.Rect(0, 0, size.X, size.Y))
+	for y := ttp.block.Min.Y; y < ttp.block.Max.Y; y++ {
+		for x := ttp.block.Min.X; x < ttp.block.Max.X; x++ {
+			ig.Pix[ig.PixOffset(x, y)] = 0xff
+		}
+	}
+
+	return
+}
+
+func newTransformTestPrintable(block image.Rectangle) (p *transformTestPrintable) {
+	size := Size{X: 8, Y: 8, Layers: 1, LayerHeight: 0.05}
+	size.Millimeter.X = 4.0
+	size.Millimeter.Y = 4.0
+
+	p = &transformTestPrintable{
+		Printable: NewEmptyPrintable(Properties{Size: size}),
+		block:     block,
+	}
+
+	return
+}
+
+func TestContentBounds(t *testing.T) {
+	block := image.Rect(1, 2, 4, 7)
+	p := newTransformTestPrintable(block)
+
+	bounds := ContentBounds(p)
+	if bounds != block {
+		t.Errorf("expected %v, got %v", block, bounds)
+	}
+}
+
+func TestTransform(t *testing.T) {
+	block := image.Rect(0, 0, 2, 2)
+
+	table := []struct {
+		name      string
+		transform Transform
+		size      image.Point
+		block     image.Rectangle
+	}{
+		{"identity", Transform{ScaleX: 1, ScaleY: 1}, image.Pt(8, 8), block},
+		{"translate", Transform{X: 1.0, Y: 0.5, ScaleX: 1, ScaleY: 1}, image.Pt(8, 8), image.Rect(2, 1, 4, 3)},
+		{"rotate", Transform{Rotate: 90, ScaleX: 1, ScaleY: 1}, image.Pt(8, 8), image.Rect(0, 6, 2, 8)},
+		{"scale", Transform{ScaleX: 0.5, ScaleY: 1.5}, image.Pt(8, 8), image.Rect(2, -2, 3, 1)},
+		{"fit", Transform{ScaleX: 1, ScaleY: 1, Mode: TransformFit}, image.Pt(8, 8), image.Rect(0, 0, 8, 8)},
+		{"crop", Transform{ScaleX: 1, ScaleY: 1, Mode: TransformCrop}, image.Pt(2, 2), image.Rect(0, 0, 2, 2)},
+		{"crop-rotate", Transform{Rotate: 45, ScaleX: 1, ScaleY: 1, Mode: TransformCrop}, image.Pt(3, 3), image.Rect(0, 0, 3, 3)},
+	}
+
+	for _, item := range table {
+		p := newTransformTestPrintable(block)
+
+		tp, err := NewTransformPrintable(p, item.transform, ContentBounds(p))
+		if err != nil {
+			t.Fatalf("%v: expected nil, got %v", item.name, err)
+		}
+
+		size := tp.Size()
+		if size.X != item.size.X || size.Y != item.size.Y {
+			t.Errorf("%v: expected a %v bed, got %vx%v", item.name, item.size, size.X, size.Y)
+			continue
+		}
+
+		expected := TransformBounds(tp.Matrix(), block)
+		if expected != item.block {
+			t.Errorf("%v: expected the block to be transformed to %v, got %v", item.name, item.block, expected)
+		}
+
+		if item.name == "crop-rotate" {
+			// Only the bounds of the rotated block are exact
+			continue
+		}
+
+		// Pixels off the bed are lost
+		onBed := item.block.Intersect(image.Rect(0, 0, size.X, size.Y))
+		bounds := ContentBounds(tp)
+		if bounds != onBed {
+			t.Errorf("%v: expected the block at %v, got %v", item.name, onBed, bounds)
+		}
+	}
+
+	// Nothing to fit, or crop
+	p := newTransformTestPrintable(image.Rectangle{})
+	_, err := NewTransformPrintable(p, Transform{ScaleX: 1, ScaleY: 1, Mode: TransformFit}, ContentBounds(p))
+	if err == nil {
+		t.Errorf("expected an error, fitting an empty printable")
+	}
+}
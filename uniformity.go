@@ -0,0 +1,240 @@
+//
+// Copyright (c) 2020 Jason S. McMullan <jason.mcmullan@gmail.com>
+//
+
+package uv3dp
+
+import (
+	"encoding/csv"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Uniformity is a grid of light intensity measurements across the bed of
+// a machine, row by row from the top left of the layer images.
+//
+// Each measurement is of the center of its cell of the grid, and the
+// intensities between them are interpolated.
+type Uniformity struct {
+	Width, Height int       // Size of the grid
+	Intensity     []float32 // Measured intensities, in any unit
+}
+
+// NewUniformityImage converts a grayscale image of the bed to a grid, with
+// a cell for each pixel
+func NewUniformityImage(ig image.Image) (u *Uniformity, err error) {
+	bounds := ig.Bounds()
+
+	u = &Uniformity{
+		Width:     bounds.Dx(),
+		Height:    bounds.Dy(),
+		Intensity: make([]float32, bounds.Dx()*bounds.Dy()),
+	}
+
+	for y := 0; y < u.Height; y++ {
+		for x := 0; x < u.Width; x++ {
+			gray := color.Gray16Model.Convert(ig.At(bounds.Min.X+x, bounds.Min.Y+y)).(color.Gray16).Y
+			u.Intensity[y*u.Width+x] = float32(gray) / 0xffff
+		}
+	}
+
+	err = u.validate()
+	if err != nil {
+		u = nil
+		return
+	}
+
+	return
+}
+
+// ReadUniformityCSV reads a grid of comma separated measurements, a row of
+// the grid per line
+func ReadUniformityCSV(reader io.Reader) (u *Uniformity, err error) {
+	csvReader := csv.NewReader(reader)
+	csvReader.Comment = '#'
+	csvReader.TrimLeadingSpace = true
+
+	records, err := csvReader.ReadAll()
+	if err != nil {
+		return
+	}
+
+	u = &Uniformity{}
+
+	for row, record := range records {
+		if row == 0 {
+			u.Width = len(record)
+		}
+
+		for col, field := range record {
+			var value float64
+			value, err = strconv.ParseFloat(strings.TrimSpace(field), 32)
+			if err != nil {
+				err = fmt.Errorf("uniformity row %v, column %v: %w", row+1, col+1, err)
+				u = nil
+				return
+			}
+			u.Intensity = append(u.Intensity, float32(value))
+		}
+	}
+
+	u.Height = len(records)
+
+	err = u.validate()
+	if err != nil {
+		u = nil
+		return
+	}
+
+	return
+}
+
+// WriteCSV writes the grid as comma separated measurements, a row of the
+// grid per line
+func (u *Uniformity) WriteCSV(writer io.Writer) (err error) {
+	csvWriter := csv.NewWriter(writer)
+
+	record := make([]string, u.Width)
+	for y := 0; y < u.Height; y++ {
+		for x := range record {
+			record[x] = strconv.FormatFloat(float64(u.Intensity[y*u.Width+x]), 'g', -1, 32)
+		}
+
+		err = csvWriter.Write(record)
+		if err != nil {
+			return
+		}
+	}
+
+	csvWriter.Flush()
+	err = csvWriter.Error()
+
+	return
+}
+
+func (u *Uniformity) validate() (err error) {
+	if u.Width < 1 || u.Height < 1 {
+		err = fmt.Errorf("uniformity grid is empty")
+		return
+	}
+
+	if len(u.Intensity) != u.Width*u.Height {
+		err = fmt.Errorf("uniformity grid is not %vx%v measurements", u.Width, u.Height)
+		return
+	}
+
+	for n, intensity := range u.Intensity {
+		if !(intensity > 0) || math.IsInf(float64(intensity), 0) {
+			err = fmt.Errorf("uniformity row %v, column %v: intensity %v is not positive", n/u.Width+1, n%u.Width+1, intensity)
+			return
+		}
+	}
+
+	return
+}
+
+// interpolate returns the sample position of a pixel, along a grid axis of
+// a number of cells, as the cells on either side and the fraction between
+// them
+func interpolate(pixel, pixels, cells int) (lo, hi int, frac float32) {
+	pos := (float32(pixel)+0.5)*float32(cells)/float32(pixels) - 0.5
+
+	switch {
+	case pos <= 0:
+		lo, hi = 0, 0
+	case pos >= float32(cells-1):
+		lo, hi = cells-1, cells-1
+	default:
+		lo = int(pos)
+		hi = lo + 1
+		frac = pos - float32(lo)
+	}
+
+	return
+}
+
+// Factors returns the scale of the gray level of every pixel of a bed,
+// row by row, that gives each the dose of the dimmest measurement
+func (u *Uniformity) Factors(width, height int) (factors []float32) {
+	dimmest := u.Intensity[0]
+	for _, intensity := range u.Intensity {
+		if intensity < dimmest {
+			dimmest = intensity
+		}
+	}
+
+	factors = make([]float32, width*height)
+
+	for y := 0; y < height; y++ {
+		y0, y1, fy := interpolate(y, height, u.Height)
+		for x := 0; x < width; x++ {
+			x0, x1, fx := interpolate(x, width, u.Width)
+
+			top := u.Intensity[y0*u.Width+x0]*(1-fx) + u.Intensity[y0*u.Width+x1]*fx
+			bottom := u.Intensity[y1*u.Width+x0]*(1-fx) + u.Intensity[y1*u.Width+x1]*fx
+			intensity := top*(1-fy) + bottom*fy
+
+			factors[y*width+x] = dimmest / intensity
+		}
+	}
+
+	return
+}
+
+// UniformityPrintable scales the gray level of the lit pixels of a range
+// of layers, to give them all the same dose
+type UniformityPrintable struct {
+	Printable
+	Uniformity *Uniformity
+	Layers     LayerRange // Layers to scale
+
+	factors []float32
+}
+
+// NewUniformityPrintable scales the gray level of the lit pixels of a
+// range of layers, to give them all the same dose
+func NewUniformityPrintable(printable Printable, uniformity *Uniformity, layers LayerRange) (up *UniformityPrintable) {
+	size := printable.Size()
+
+	up = &UniformityPrintable{
+		Printable:  printable,
+		Uniformity: uniformity,
+		Layers:     layers,
+		factors:    uniformity.Factors(size.X, size.Y),
+	}
+
+	return
+}
+
+func (up *UniformityPrintable) LayerImage(index int) (ig *image.Gray) {
+	ig = up.Printable.LayerImage(index)
+
+	if !up.Layers.Contains(up.Printable, index) {
+		return
+	}
+
+	bounds := ig.Bounds()
+	width := bounds.Dx()
+	size := up.Printable.Size()
+	if width != size.X || bounds.Dy() != size.Y {
+		// Not the size of the bed
+		return
+	}
+
+	out := image.NewGray(bounds)
+	for y := 0; y < bounds.Dy(); y++ {
+		for x := 0; x < width; x++ {
+			pix := ig.Pix[ig.PixOffset(bounds.Min.X+x, bounds.Min.Y+y)]
+			out.Pix[y*out.Stride+x] = uint8(float32(pix)*up.factors[y*width+x] + 0.5)
+		}
+	}
+
+	ig = out
+
+	return
+}
@@ -0,0 +1,107 @@
+//
+// Copyright (c) 2020 Jason S. McMullan <jason.mcmullan@gmail.com>
+//
+
+package uv3dp
+
+import (
+	"bytes"
+	"image"
+	"math"
+	"strings"
+
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestUniformityCSV(t *testing.T) {
+	text := "# Corners are dimmer\n0.85, 1.0, 0.85\n0.9, 1, 0.9\n"
+
+	u, err := ReadUniformityCSV(strings.NewReader(text))
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+
+	expected := &Uniformity{Width: 3, Height: 2, Intensity: []float32{0.85, 1.0, 0.85, 0.9, 1.0, 0.9}}
+	if !cmp.Equal(u, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, u)
+	}
+
+	buff := &bytes.Buffer{}
+	err = u.WriteCSV(buff)
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+
+	u, err = ReadUniformityCSV(buff)
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if !cmp.Equal(u, expected) {
+		t.Errorf("round trip: expected %+v, got %+v", expected, u)
+	}
+
+	for _, text := range []string{"", "1,2\n3\n", "1,0\n", "1,-2\n", "1,x\n"} {
+		_, err = ReadUniformityCSV(strings.NewReader(text))
+		if err == nil {
+			t.Errorf("%q: expected an error", text)
+		}
+	}
+}
+
+func TestUniformityFactors(t *testing.T) {
+	u := &Uniformity{Width: 2, Height: 1, Intensity: []float32{1.0, 2.0}}
+
+	// Samples are at the centres of the cells, and flat beyond them
+	factors := u.Factors(4, 2)
+	expected := []float32{1.0, 1.0 / 1.25, 1.0 / 1.75, 0.5}
+
+	for y := 0; y < 2; y++ {
+		for x, factor := range expected {
+			got := factors[y*4+x]
+			if math.Abs(float64(got-factor)) > 1e-6 {
+				t.Errorf("(%v,%v): expected %v, got %v", x, y, factor, got)
+			}
+		}
+	}
+}
+
+// uniformityTestPrintable has the same image for every layer
+type uniformityTestPrintable struct {
+	Printable
+	layer *image.Gray
+}
+
+func (utp *uniformityTestPrintable) LayerImage(index int) (ig *image.Gray) {
+	ig = image.NewGray(utp.layer.Bounds())
+	copy(ig.Pix, utp.layer.Pix)
+
+	return
+}
+
+func TestUniformityPrintable(t *testing.T) {
+	size := Size{X: 4, Y: 1, Layers: 2}
+	size.Millimeter.X = 4
+	size.Millimeter.Y = 1
+
+	layer := image.NewGray(image.Rect(0, 0, 4, 1))
+	copy(layer.Pix, []uint8{0xff, 0xff, 0x80, 0x00})
+
+	build := NewEmptyPrintable(Properties{Size: size})
+	p := &uniformityTestPrintable{Printable: build, layer: layer}
+
+	u := &Uniformity{Width: 2, Height: 1, Intensity: []float32{1.0, 2.0}}
+	layers, _ := ParseLayerRange("1")
+
+	up := NewUniformityPrintable(p, u, layers)
+
+	if !cmp.Equal(up.LayerImage(0).Pix, layer.Pix) {
+		t.Errorf("layer 0: expected no change, got %#v", up.LayerImage(0).Pix)
+	}
+
+	expected := []uint8{0xff, 0xcc, 0x49, 0x00}
+	if !cmp.Equal(up.LayerImage(1).Pix, expected) {
+		t.Errorf("layer 1: expected %#v, got %#v", expected, up.LayerImage(1).Pix)
+	}
+}
@@ -0,0 +1,304 @@
+//
+// Copyright (c) 2020 Jason S. McMullan <jason.mcmullan@gmail.com>
+//
+
+package uv3dptest
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"math"
+	"os"
+	"path/filepath"
+
+	"testing"
+
+	"github.com/ezrec/uv3dp"
+)
+
+// Capabilities are what a formatter keeps of a printable, through an
+// encode and decode
+type Capabilities struct {
+	Suffix string            // Suffix of the format
+	Args   []string          // Formatter arguments
+	Bed    uv3dp.MachineSize // Bed of the reference printables (default DefaultBed)
+
+	EncodeOnly bool // Output only format; only encoding is checked
+
+	GrayLevels    int                 // Gray levels of layer images (2 for monochrome, 0 for all 256)
+	LayerExposure bool                // Per-layer exposures are kept
+	LayerZ        bool                // Per-layer Z heights are kept
+	Transition    bool                // Bottom transition layers are kept
+	Previews      []uv3dp.PreviewType // Previews that are kept
+	Metadata      bool                // Metadata is kept
+
+	// Fixup sets the exposure settings that the format can not keep, in
+	// both the expected and the decoded exposures, before they are compared
+	Fixup func(kind ExposureKind, exposure *uv3dp.Exposure)
+}
+
+// ExposureKind is the kind of an exposure being compared
+type ExposureKind int
+
+const (
+	ExposureNormal = ExposureKind(iota) // Printable's Exposure()
+	ExposureBottom                      // Printable's Bottom().Exposure
+	ExposureLayer                       // Printable's LayerExposure(n)
+)
+
+// Tolerances of the checks
+const (
+	ToleranceMillimeter = 0.01
+	ToleranceZ          = 0.0001
+	ToleranceExposure   = 0.001
+	TolerancePreview    = 8
+)
+
+// checker collects the differences between printables
+type checker struct {
+	t    *testing.T
+	caps Capabilities
+}
+
+func (c *checker) near(what string, expected, got float32, tolerance float64) {
+	if math.Abs(float64(expected)-float64(got)) > tolerance {
+		c.t.Errorf("%v: expected %v, got %v", what, expected, got)
+	}
+}
+
+func (c *checker) exposure(what string, kind ExposureKind, expected, got uv3dp.Exposure) {
+	if c.caps.Fixup != nil {
+		c.caps.Fixup(kind, &expected)
+		c.caps.Fixup(kind, &got)
+	}
+
+	c.near(what+" LightOnTime", expected.LightOnTime, got.LightOnTime, ToleranceExposure)
+	c.near(what+" LightOffTime", expected.LightOffTime, got.LightOffTime, ToleranceExposure)
+	c.near(what+" LiftHeight", expected.LiftHeight, got.LiftHeight, ToleranceExposure)
+	c.near(what+" LiftSpeed", expected.LiftSpeed, got.LiftSpeed, ToleranceExposure)
+	c.near(what+" RetractHeight", expected.RetractHeight, got.RetractHeight, ToleranceExposure)
+	c.near(what+" RetractSpeed", expected.RetractSpeed, got.RetractSpeed, ToleranceExposure)
+
+	if expected.LightPWM != got.LightPWM {
+		c.t.Errorf("%v LightPWM: expected %v, got %v", what, expected.LightPWM, got.LightPWM)
+	}
+}
+
+// layer compares layer images, allowing for the gray levels of the format
+func (c *checker) layer(n int, expected, got *image.Gray) {
+	if expected.Bounds() != got.Bounds() {
+		c.t.Errorf("layer %v: expected bounds %v, got %v", n, expected.Bounds(), got.Bounds())
+		return
+	}
+
+	step := 0
+	if c.caps.GrayLevels > 1 && c.caps.GrayLevels < 256 {
+		step = (0xff + c.caps.GrayLevels - 2) / (c.caps.GrayLevels - 1)
+	}
+
+	bounds := expected.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			e := int(expected.GrayAt(x, y).Y)
+			g := int(got.GrayAt(x, y).Y)
+
+			diff := e - g
+			if diff < 0 {
+				diff = -diff
+			}
+
+			// Black and white are always exact
+			if (e == 0x00 || e == 0xff || step == 0) && diff != 0 || diff > step {
+				c.t.Errorf("layer %v: (%v,%v) expected 0x%02x, got 0x%02x", n, x, y, e, g)
+				return
+			}
+		}
+	}
+}
+
+// printable compares a decoded printable to its reference
+func (c *checker) printable(expected, got uv3dp.Printable) {
+	eSize, gSize := expected.Size(), got.Size()
+	if eSize.X != gSize.X || eSize.Y != gSize.Y || eSize.Layers != gSize.Layers {
+		c.t.Fatalf("size: expected %+v, got %+v", eSize, gSize)
+	}
+
+	c.near("size Millimeter.X", eSize.Millimeter.X, gSize.Millimeter.X, ToleranceMillimeter)
+	c.near("size Millimeter.Y", eSize.Millimeter.Y, gSize.Millimeter.Y, ToleranceMillimeter)
+	c.near("size LayerHeight", eSize.LayerHeight, gSize.LayerHeight, ToleranceZ)
+
+	c.exposure("exposure", ExposureNormal, expected.Exposure(), got.Exposure())
+
+	eBottom, gBottom := expected.Bottom(), got.Bottom()
+	if eBottom.Count != gBottom.Count || eBottom.Transition != gBottom.Transition {
+		c.t.Errorf("bottom: expected %v layers (%v transition), got %v layers (%v transition)",
+			eBottom.Count, eBottom.Transition, gBottom.Count, gBottom.Transition)
+	}
+	c.exposure("bottom exposure", ExposureBottom, eBottom.Exposure, gBottom.Exposure)
+
+	for n := 0; n < eSize.Layers; n++ {
+		c.near(fmt.Sprintf("layer %v Z", n), expected.LayerZ(n), got.LayerZ(n), ToleranceZ)
+		c.exposure(fmt.Sprintf("layer %v exposure", n), ExposureLayer, expected.LayerExposure(n), got.LayerExposure(n))
+		c.layer(n, expected.LayerImage(n), got.LayerImage(n))
+	}
+}
+
+// previews checks that the decoded previews are those of the reference
+func (c *checker) previews(got uv3dp.Printable) {
+	for _, ptype := range c.caps.Previews {
+		ig, ok := got.Preview(ptype)
+		if !ok {
+			c.t.Errorf("preview %v: not found", ptype)
+			continue
+		}
+
+		expected, ok := ReferencePreviewColor[ptype]
+		if !ok {
+			continue
+		}
+
+		// The middle is not letterboxed, when fit to a size
+		bounds := ig.Bounds()
+		mid := bounds.Min.Add(bounds.Size().Div(2))
+		r, g, b, _ := ig.At(mid.X, mid.Y).RGBA()
+
+		for _, item := range []struct {
+			Name     string
+			Expected uint8
+			Got      uint32
+		}{
+			{"red", expected.R, r >> 8},
+			{"green", expected.G, g >> 8},
+			{"blue", expected.B, b >> 8},
+		} {
+			if math.Abs(float64(item.Expected)-float64(item.Got)) > TolerancePreview {
+				c.t.Errorf("preview %v: %v expected 0x%02x, got 0x%02x", ptype, item.Name, item.Expected, item.Got)
+			}
+		}
+	}
+}
+
+// metadata checks that the decoded metadata is that of the reference
+func (c *checker) metadata(got uv3dp.Printable) {
+	for key, expected := range ReferenceMetadata {
+		data, ok := got.Metadata(key)
+		if !ok {
+			c.t.Errorf("metadata %v: not found", key)
+			continue
+		}
+
+		if fmt.Sprint(data) != fmt.Sprint(expected) {
+			c.t.Errorf("metadata %v: expected %v, got %v", key, expected, data)
+		}
+	}
+}
+
+// newFormatter returns a formatter, with the arguments of the capabilities
+func (c *checker) newFormatter(newFormatter uv3dp.NewFormatter) (formatter uv3dp.Formatter) {
+	formatter = newFormatter(c.caps.Suffix)
+
+	err := formatter.Parse(c.caps.Args)
+	if err != nil {
+		c.t.Fatalf("%v: %v", c.caps.Args, err)
+	}
+
+	return
+}
+
+// roundTrip encodes and decodes a printable, as uv3dp.Format would
+func (c *checker) roundTrip(newFormatter uv3dp.NewFormatter, printable uv3dp.Printable, dir string) (got uv3dp.Printable) {
+	formatter := c.newFormatter(newFormatter)
+	printable = uv3dp.NewPreviewPrintable(printable, formatter)
+
+	filename := filepath.Join(dir, "reference"+c.caps.Suffix)
+	buff := &bytes.Buffer{}
+
+	var err error
+	fileFormatter, isFile := formatter.(uv3dp.FileFormatter)
+	if isFile {
+		err = fileFormatter.EncodeFile(filename, printable)
+	} else {
+		err = formatter.Encode(buff, printable)
+	}
+	if err != nil {
+		c.t.Fatalf("encode: %v", err)
+	}
+
+	if isFile {
+		_, err = os.Stat(filename)
+		if err != nil {
+			c.t.Fatalf("encode: %v", err)
+		}
+	} else if buff.Len() == 0 {
+		c.t.Fatalf("encode: no output")
+	}
+
+	if c.caps.EncodeOnly {
+		return
+	}
+
+	formatter = c.newFormatter(newFormatter)
+	fileFormatter, isFile = formatter.(uv3dp.FileFormatter)
+	if isFile {
+		got, err = fileFormatter.DecodeFile(filename)
+	} else {
+		got, err = formatter.Decode(bytes.NewReader(buff.Bytes()), int64(buff.Len()))
+	}
+	if err != nil {
+		c.t.Fatalf("decode: %v", err)
+	}
+
+	return
+}
+
+// RunFormatterConformance checks that the reference printables survive
+// an encode and decode by a formatter, as far as its capabilities allow.
+// A decoded printable is also checked to survive a second encode and
+// decode unchanged.
+func RunFormatterConformance(t *testing.T, newFormatter uv3dp.NewFormatter, caps Capabilities) {
+	bed := caps.Bed
+	if bed == (uv3dp.MachineSize{}) {
+		bed = DefaultBed
+	}
+
+	if caps.GrayLevels == 0 {
+		caps.GrayLevels = 256
+	}
+
+	for _, ref := range References(bed) {
+		switch {
+		case ref.Name == "exposures" && !caps.LayerExposure:
+			continue
+		case ref.Name == "transition" && !caps.Transition:
+			continue
+		case ref.Name == "variable-z" && !caps.LayerZ:
+			continue
+		}
+
+		ref := ref
+		t.Run(ref.Name, func(t *testing.T) {
+			c := &checker{t: t, caps: caps}
+
+			got := c.roundTrip(newFormatter, ref.Printable, t.TempDir())
+			if caps.EncodeOnly {
+				return
+			}
+
+			c.printable(ref.Printable, got)
+
+			if ref.Name == "decorated" {
+				c.previews(got)
+				if caps.Metadata {
+					c.metadata(got)
+				}
+			}
+
+			// A decoded printable is kept as it is, apart from the
+			// exposure settings that the format can not keep
+			c.caps.GrayLevels = 256
+			again := c.roundTrip(newFormatter, got, t.TempDir())
+			c.printable(got, again)
+		})
+	}
+}
@@ -0,0 +1,233 @@
+//
+// Copyright (c) 2020 Jason S. McMullan <jason.mcmullan@gmail.com>
+//
+
+package uv3dptest
+
+import (
+	"image"
+	"image/color"
+
+	"github.com/ezrec/uv3dp"
+)
+
+// DefaultBed is the bed of the reference printables, unless a formatter
+// needs another
+var DefaultBed = uv3dp.MachineSize{X: 64, Y: 40, Xmm: 3.2, Ymm: 2.0}
+
+// Reference printable settings
+const (
+	ReferenceLayers      = 8
+	ReferenceLayerHeight = 0.05
+	ReferenceBottom      = 2
+)
+
+var (
+	// ReferenceExposure is the normal exposure of the reference printables
+	ReferenceExposure = uv3dp.Exposure{
+		LightOnTime:   6.5,
+		LightOffTime:  1.5,
+		LightPWM:      255,
+		LiftHeight:    5.0,
+		LiftSpeed:     60.0,
+		RetractHeight: 1.0,
+		RetractSpeed:  150.0,
+	}
+
+	// ReferenceBottomExposure is the bottom exposure of the reference
+	// printables. As with most slicers, only the light on time and the
+	// lift are different from the normal exposure.
+	ReferenceBottomExposure = uv3dp.Exposure{
+		LightOnTime:   40.0,
+		LightOffTime:  1.5,
+		LightPWM:      255,
+		LiftHeight:    7.0,
+		LiftSpeed:     45.0,
+		RetractHeight: 1.0,
+		RetractSpeed:  150.0,
+	}
+
+	referenceBottom = uv3dp.Bottom{Exposure: ReferenceBottomExposure, Count: ReferenceBottom}
+
+	// ReferencePreviewColor is the color of the reference previews, by type
+	ReferencePreviewColor = map[uv3dp.PreviewType]color.RGBA{
+		uv3dp.PreviewTypeTiny: {R: 0x40, G: 0x80, B: 0xc0, A: 0xff},
+		uv3dp.PreviewTypeHuge: {R: 0xc0, G: 0x80, B: 0x40, A: 0xff},
+	}
+
+	// ReferenceMetadata is the metadata of the decorated reference printable
+	ReferenceMetadata = map[string]interface{}{
+		"uv3dptest": "reference",
+	}
+)
+
+// Reference is a named reference printable
+type Reference struct {
+	Name      string
+	Printable uv3dp.Printable
+}
+
+// layerExposure is the exposure of a reference layer
+func layerExposure(n int) uv3dp.Exposure {
+	if n < ReferenceBottom {
+		return ReferenceBottomExposure
+	}
+
+	return ReferenceExposure
+}
+
+// newBuilder adds the layers of a reference printable to a builder
+func newBuilder(bed uv3dp.MachineSize, bottom uv3dp.Bottom, z func(n int) float32, exposure func(n int) uv3dp.Exposure, layer func(bounds image.Rectangle, n int) *image.Gray) (b *uv3dp.Builder) {
+	b = uv3dp.NewBuilder(bed)
+	b.SetExposure(ReferenceExposure)
+	b.SetBottom(bottom)
+	b.SetLayerHeight(ReferenceLayerHeight)
+
+	for n := 0; n < ReferenceLayers; n++ {
+		err := b.AddLayer(z(n), exposure(n), layer(b.Bounds(), n))
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	return
+}
+
+// build makes a reference printable
+func build(b *uv3dp.Builder) (printable uv3dp.Printable) {
+	printable, err := b.Build()
+	if err != nil {
+		panic(err)
+	}
+
+	return
+}
+
+// layerZ is the Z height of a reference layer
+func layerZ(n int) float32 {
+	prop := uv3dp.Properties{Size: uv3dp.Size{LayerHeight: ReferenceLayerHeight}}
+	return prop.LayerZ(n)
+}
+
+// gradientLayer is a horizontal gradient from black to white, with a
+// black border that moves by layer
+func gradientLayer(bounds image.Rectangle, n int) (gray *image.Gray) {
+	gray = image.NewGray(bounds)
+
+	width := bounds.Dx() - 1
+	for y := bounds.Min.Y + 1 + n%4; y < bounds.Max.Y-1; y++ {
+		for x := 0; x <= width; x++ {
+			gray.Pix[gray.PixOffset(bounds.Min.X+x, y)] = uint8(x * 0xff / width)
+		}
+	}
+
+	return
+}
+
+// islandsLayer has solid islands, one with a hole, that change by layer
+func islandsLayer(bounds image.Rectangle, n int) (gray *image.Gray) {
+	gray = image.NewGray(bounds)
+
+	fill := func(rect image.Rectangle, level uint8) {
+		rect = rect.Add(bounds.Min).Intersect(bounds)
+		for y := rect.Min.Y; y < rect.Max.Y; y++ {
+			for x := rect.Min.X; x < rect.Max.X; x++ {
+				gray.Pix[gray.PixOffset(x, y)] = level
+			}
+		}
+	}
+
+	dx, dy := bounds.Dx(), bounds.Dy()
+
+	// A ring, that narrows by layer
+	fill(image.Rect(dx/8, dy/8, dx/2, dy*7/8), 0xff)
+	fill(image.Rect(dx/8+2+n%3, dy/8+2+n%3, dx/2-2-n%3, dy*7/8-2-n%3), 0x00)
+
+	// A block, that moves by layer
+	fill(image.Rect(dx*5/8, dy/8, dx*7/8, dy/2).Add(image.Pt(0, n%4)), 0xff)
+
+	// Islands that come and go
+	for i := 0; i <= n%3; i++ {
+		fill(image.Rect(dx*5/8+i*4, dy*3/4, dx*5/8+i*4+2, dy*3/4+2), 0xff)
+	}
+
+	return
+}
+
+// NewGradient returns a printable with gray gradients in every layer
+func NewGradient(bed uv3dp.MachineSize) uv3dp.Printable {
+	return build(newBuilder(bed, referenceBottom, layerZ, layerExposure, gradientLayer))
+}
+
+// NewIslands returns a printable with solid islands, that change by layer
+func NewIslands(bed uv3dp.MachineSize) uv3dp.Printable {
+	return build(newBuilder(bed, referenceBottom, layerZ, layerExposure, islandsLayer))
+}
+
+// NewExposures returns a printable with a different exposure for every
+// layer
+func NewExposures(bed uv3dp.MachineSize) uv3dp.Printable {
+	return build(newBuilder(bed, referenceBottom, layerZ, func(n int) (exposure uv3dp.Exposure) {
+		exposure = layerExposure(n)
+		exposure.LightOnTime += float32(n) * 0.5
+		exposure.LightOffTime += float32(n) * 0.25
+		exposure.LiftHeight += float32(n)
+		exposure.LiftSpeed += float32(n) * 10
+		exposure.RetractSpeed += float32(n) * 5
+		return
+	}, islandsLayer))
+}
+
+// NewTransition returns a printable with transition layers between the
+// bottom and normal exposures
+func NewTransition(bed uv3dp.MachineSize) uv3dp.Printable {
+	prop := uv3dp.Properties{
+		Exposure: ReferenceExposure,
+		Bottom:   referenceBottom,
+	}
+	prop.Bottom.Transition = 3
+
+	return build(newBuilder(bed, prop.Bottom, layerZ, prop.LayerExposure, islandsLayer))
+}
+
+// NewVariableZ returns a printable whose layers are not all the same height
+func NewVariableZ(bed uv3dp.MachineSize) uv3dp.Printable {
+	return build(newBuilder(bed, referenceBottom, func(n int) float32 {
+		return layerZ(n + n/2)
+	}, layerExposure, islandsLayer))
+}
+
+// NewDecorated returns a printable with previews and metadata
+func NewDecorated(bed uv3dp.MachineSize) uv3dp.Printable {
+	b := newBuilder(bed, referenceBottom, layerZ, layerExposure, islandsLayer)
+
+	for ptype, c := range ReferencePreviewColor {
+		size := uv3dp.DefaultPreviewSize[ptype]
+		preview := image.NewRGBA(image.Rectangle{Max: size})
+		for n := 0; n < len(preview.Pix); n += 4 {
+			preview.Pix[n+0] = c.R
+			preview.Pix[n+1] = c.G
+			preview.Pix[n+2] = c.B
+			preview.Pix[n+3] = c.A
+		}
+		b.SetPreview(ptype, preview)
+	}
+
+	for key, data := range ReferenceMetadata {
+		b.SetMetadata(key, data)
+	}
+
+	return build(b)
+}
+
+// References returns all of the reference printables, for a bed
+func References(bed uv3dp.MachineSize) []Reference {
+	return []Reference{
+		{"gradient", NewGradient(bed)},
+		{"islands", NewIslands(bed)},
+		{"exposures", NewExposures(bed)},
+		{"transition", NewTransition(bed)},
+		{"variable-z", NewVariableZ(bed)},
+		{"decorated", NewDecorated(bed)},
+	}
+}
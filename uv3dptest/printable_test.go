@@ -0,0 +1,47 @@
+//
+// Copyright (c) 2020 Jason S. McMullan <jason.mcmullan@gmail.com>
+//
+
+package uv3dptest
+
+import (
+	"testing"
+)
+
+func TestReferences(t *testing.T) {
+	for _, ref := range References(DefaultBed) {
+		size := ref.Printable.Size()
+		if size.X != DefaultBed.X || size.Y != DefaultBed.Y || size.Layers != ReferenceLayers {
+			t.Errorf("%v: unexpected size %+v", ref.Name, size)
+		}
+
+		bottom := ref.Printable.Bottom()
+		if bottom.Count != ReferenceBottom {
+			t.Errorf("%v: expected %v bottom layers, got %v", ref.Name, ReferenceBottom, bottom.Count)
+		}
+
+		lit := 0
+		for n := 0; n < size.Layers; n++ {
+			if n > 0 && ref.Printable.LayerZ(n) <= ref.Printable.LayerZ(n-1) {
+				t.Errorf("%v: layer %v is not above layer %v", ref.Name, n, n-1)
+			}
+
+			for _, pix := range ref.Printable.LayerImage(n).Pix {
+				if pix != 0 {
+					lit++
+				}
+			}
+		}
+
+		if lit == 0 {
+			t.Errorf("%v: no lit pixels", ref.Name)
+		}
+	}
+
+	// Transition layers step from the bottom to the normal exposure
+	transition := NewTransition(DefaultBed)
+	first := transition.LayerExposure(ReferenceBottom).LightOnTime
+	if first >= ReferenceBottomExposure.LightOnTime || first <= ReferenceExposure.LightOnTime {
+		t.Errorf("transition: unexpected first transition light on time %v", first)
+	}
+}
@@ -0,0 +1,22 @@
+//
+// Copyright (c) 2020 Jason S. McMullan <jason.mcmullan@gmail.com>
+//
+
+package uvj
+
+import (
+	"testing"
+
+	"github.com/ezrec/uv3dp"
+	"github.com/ezrec/uv3dp/uv3dptest"
+)
+
+func TestConformance(t *testing.T) {
+	uv3dptest.RunFormatterConformance(t, func(suffix string) uv3dp.Formatter { return NewUVJFormatter(suffix) }, uv3dptest.Capabilities{
+		Suffix:        ".uvj",
+		LayerExposure: true,
+		LayerZ:        true,
+		Transition:    true,
+		Previews:      []uv3dp.PreviewType{uv3dp.PreviewTypeTiny, uv3dp.PreviewTypeHuge},
+	})
+}
@@ -13,6 +13,8 @@ import (
 	"image"
 	"image/png"
 	"io"
+	"path"
+	"strings"
 
 	"github.com/ezrec/uv3dp"
 	"github.com/spf13/pflag"
@@ -127,27 +129,14 @@ func (sf *UVJFormat) Encode(writer uv3dp.Writer, printable uv3dp.Printable) (err
 	fileConfig.Write(data)
 	fileConfig.Write([]byte("\n"))
 
-	// Save the thumbnails
-	preview := []uv3dp.PreviewType{
-		uv3dp.PreviewTypeTiny,
-		uv3dp.PreviewTypeHuge,
-	}
-
-	for _, code := range preview {
+	// Save the thumbnails, by name
+	for _, code := range printable.PreviewKeys() {
 		image, ok := printable.Preview(code)
 		if !ok {
 			continue
 		}
 
-		var name string
-		switch code {
-		case uv3dp.PreviewTypeTiny:
-			name = "tiny"
-		case uv3dp.PreviewTypeHuge:
-			name = "huge"
-		}
-
-		filename := "preview/" + name + ".png"
+		filename := "preview/" + string(code) + ".png"
 
 		var writer io.Writer
 		writer, err = archive.Create(filename)
@@ -233,18 +222,14 @@ func (sf *UVJFormat) Decode(reader uv3dp.Reader, filesize int64) (printable uv3d
 		}
 	}
 
-	// Collect the thumbnails
-	thumbs := map[uv3dp.PreviewType]string{
-		uv3dp.PreviewTypeTiny: "preview/tiny.png",
-		uv3dp.PreviewTypeHuge: "preview/huge.png",
-	}
-
+	// Collect the thumbnails, by name
 	thumbImage := make(map[uv3dp.PreviewType]image.Image)
-	for pt, pn := range thumbs {
-		file, ok := fileMap[pn]
-		if !ok {
+	for pn, file := range fileMap {
+		name, ok := strings.CutPrefix(pn, "preview/")
+		if !ok || path.Ext(name) != ".png" || strings.Contains(name, "/") {
 			continue
 		}
+		pt := uv3dp.PreviewType(strings.TrimSuffix(name, ".png"))
 
 		var reader io.ReadCloser
 		reader, err = file.Open()
@@ -293,6 +278,11 @@ func (uvj *UVJ) LayerExposure(index int) (exposure uv3dp.Exposure) {
 		exposure = uvj.Print.LayerExposure(index)
 	} else {
 		exposure = uvj.Layers[index].Exposure
+
+		// LightPWM of 255 is not encoded
+		if exposure.LightPWM == 0 {
+			exposure.LightPWM = 255
+		}
 	}
 
 	return
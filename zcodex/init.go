@@ -21,4 +21,7 @@ func init() {
 	uv3dp.RegisterFormatter(".zcodex", newFormatter)
 
 	uv3dp.RegisterMachines(machines_zcodex, ".zcodex")
+
+	uv3dp.RegisterMetadata("zcodex/ResinMetadata", &ResinMetadata{})
+	uv3dp.RegisterMetadata("zcodex/UserSettingsData", &UserSettingsData{})
 }